@@ -15,7 +15,7 @@ import (
 type zoneAddr struct {
 	Zone      string
 	Port      string
-	Transport string     // dns, tls or grpc
+	Transport string     // dns, tls, grpc, https or quic
 	IPNet     *net.IPNet // if reverse zone this hold the IPNet
 	Address   string     // used for bound zoneAddr - validation of overlapping
 }
@@ -52,6 +52,8 @@ func normalizeZone(str string) (zoneAddr, error) {
 			port = transport.GRPCPort
 		case transport.HTTPS:
 			port = transport.HTTPSPort
+		case transport.QUIC:
+			port = transport.QUICPort
 		}
 	}
 
@@ -28,6 +28,8 @@ func TestNormalizeZone(t *testing.T) {
 		{"https://.:8443", "https://.:8443", false},
 		{"https://..", "://:", true},
 		{"https://.:", "://:", true},
+		{"quic://.", "quic://.:853", false},
+		{"quic://.:8853", "quic://.:8853", false},
 	} {
 		addr, err := normalizeZone(test.input)
 		actual := addr.String()
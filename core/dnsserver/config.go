@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 
 	"github.com/mholt/caddy"
 )
@@ -40,6 +41,10 @@ type Config struct {
 	// TLSConfig when listening for encrypted connections (gRPC, DNS-over-TLS).
 	TLSConfig *tls.Config
 
+	// TsigKeys are the TSIG keys, keyed by their name. Messages signed with one of these keys are
+	// verified by the server, plugins can check the result with tsig.Verified.
+	TsigKeys map[string]tsig.Key
+
 	// Plugin stack.
 	Plugin []plugin.Plugin
 
@@ -71,3 +76,9 @@ func GetConfig(c *caddy.Controller) *Config {
 	ctx.saveConfig(key, &Config{ListenHosts: []string{""}})
 	return GetConfig(c)
 }
+
+// GetConfigs gets the configs of all server blocks (and their keys) that are set up together with
+// the one the controller c is for.
+func GetConfigs(c *caddy.Controller) []*Config {
+	return c.Context().(*dnsContext).configs
+}
@@ -4,6 +4,8 @@ import (
 	"net"
 
 	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+
+	"github.com/miekg/dns"
 )
 
 // DoHWriter is a nonwriter.Writer that adds more specific LocalAddr and RemoteAddr methods.
@@ -21,3 +23,7 @@ func (d *DoHWriter) RemoteAddr() net.Addr { return d.raddr }
 
 // LocalAddr returns the local address.
 func (d *DoHWriter) LocalAddr() net.Addr { return d.laddr }
+
+// TsigStatus implements the dns.ResponseWriter interface. TSIG is not verified for DNS-over-HTTPS, so
+// signed messages never validate.
+func (d *DoHWriter) TsigStatus() error { return dns.ErrSecret }
@@ -141,6 +141,13 @@ func (h *dnsContext) MakeServers() ([]caddy.Server, error) {
 				return nil, err
 			}
 			servers = append(servers, s)
+
+		case transport.QUIC:
+			s, err := NewServerQUIC(addr, group)
+			if err != nil {
+				return nil, err
+			}
+			servers = append(servers, s)
 		}
 
 	}
@@ -178,6 +185,16 @@ func (c *Config) Handler(name string) plugin.Handler {
 	return nil
 }
 
+// acceptsUpdates returns true when a plugin that handles dynamic updates is loaded in this config.
+func (c *Config) acceptsUpdates() bool {
+	for name := range enableUpdate {
+		if c.Handler(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // Handlers returns a slice of plugins that have been registered. This can be used to
 // inspect and interact with registered plugins but cannot be used to remove or add plugins.
 // Note that this is order dependent and the order is defined in directives.go, i.e. if your plugin
@@ -39,6 +39,8 @@ type Server struct {
 	trace       trace.Trace        // the trace plugin for the server
 	debug       bool               // disable recover()
 	classChaos  bool               // allow non-INET class queries
+	update      bool               // allow dynamic updates
+	tsigSecret  map[string]string  // TSIG secrets of all zones, keyed by key name
 }
 
 // NewServer returns a new CoreDNS server and compiles all plugins in to it. By default CH class
@@ -66,6 +68,12 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 		}
 		// set the config per zone
 		s.zones[site.Zone] = site
+		for name, k := range site.TsigKeys {
+			if s.tsigSecret == nil {
+				s.tsigSecret = make(map[string]string)
+			}
+			s.tsigSecret[name] = k.Secret
+		}
 		// compile custom plugin for everything
 		if site.registry != nil {
 			// this config is already computed with the chain of plugin
@@ -76,6 +84,14 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 					break
 				}
 			}
+			for _, h := range site.registry {
+				if c, ok := h.(ChaosHandler); ok && c.Chaos() {
+					s.classChaos = true
+				}
+			}
+			if site.acceptsUpdates() {
+				s.update = true
+			}
 			// set trace handler in accordance with previously registered "trace" plugin
 			if handler, ok := site.registry["trace"]; ok {
 				s.trace = handler.(trace.Trace)
@@ -100,6 +116,13 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 			if _, ok := enableChaos[stack.Name()]; ok {
 				s.classChaos = true
 			}
+			if c, ok := stack.(ChaosHandler); ok && c.Chaos() {
+				s.classChaos = true
+			}
+			// Accept dynamic updates when any of these plugins are loaded.
+			if _, ok := enableUpdate[stack.Name()]; ok {
+				s.update = true
+			}
 		}
 		site.pluginChain = stack
 	}
@@ -111,7 +134,7 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 // This implements caddy.TCPServer interface.
 func (s *Server) Serve(l net.Listener) error {
 	s.m.Lock()
-	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp", TsigSecret: s.tsigSecret, MsgAcceptFunc: s.msgAcceptFunc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.WithValue(context.Background(), Key{}, s)
 		s.ServeDNS(ctx, w, r)
 	})}
@@ -124,7 +147,7 @@ func (s *Server) Serve(l net.Listener) error {
 // This implements caddy.UDPServer interface.
 func (s *Server) ServePacket(p net.PacketConn) error {
 	s.m.Lock()
-	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", TsigSecret: s.tsigSecret, MsgAcceptFunc: s.msgAcceptFunc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.WithValue(context.Background(), Key{}, s)
 		s.ServeDNS(ctx, w, r)
 	})}
@@ -257,6 +280,11 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 			// This will makes those metrics unique.
 			ctx = context.WithValue(ctx, plugin.ServerCtx{}, s.Addr)
 
+			if r.Opcode == dns.OpcodeUpdate && !h.acceptsUpdates() {
+				DefaultErrorFunc(ctx, w, r, dns.RcodeNotImplemented)
+				return
+			}
+
 			if r.Question[0].Qtype != dns.TypeDS {
 				if h.FilterFunc == nil {
 					rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
@@ -303,6 +331,11 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		// See comment above.
 		ctx = context.WithValue(ctx, plugin.ServerCtx{}, s.Addr)
 
+		if r.Opcode == dns.OpcodeUpdate && !h.acceptsUpdates() {
+			DefaultErrorFunc(ctx, w, r, dns.RcodeNotImplemented)
+			return
+		}
+
 		rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
 		if !plugin.ClientWrite(rcode) {
 			DefaultErrorFunc(ctx, w, r, rcode)
@@ -389,5 +422,33 @@ var enableChaos = map[string]bool{
 	"proxy":   true,
 }
 
+// ChaosHandler is implemented by plugins that only answer CH class queries in some configurations. CH
+// class queries are unblocked when Chaos returns true.
+type ChaosHandler interface {
+	Chaos() bool
+}
+
+// enableUpdate is a map with plugin names for which we accept dynamic updates (RFC 2136).
+var enableUpdate = map[string]bool{
+	"auto": true,
+	"file": true,
+}
+
+// msgAcceptFunc accepts the same messages as dns.DefaultMsgAcceptFunc. When a plugin that handles
+// dynamic updates is loaded it accepts those as well; they can carry RRs in all sections.
+func (s *Server) msgAcceptFunc(dh dns.Header) dns.MsgAcceptAction {
+	opcode := int(dh.Bits>>11) & 0xF
+	if !s.update || opcode != dns.OpcodeUpdate {
+		return dns.DefaultMsgAcceptFunc(dh)
+	}
+	if dh.Bits&(1<<15) != 0 { // QR bit is set, this is a response.
+		return dns.MsgIgnore
+	}
+	if dh.Qdcount != 1 {
+		return dns.MsgReject
+	}
+	return dns.MsgAccept
+}
+
 // Quiet mode will not show any informative output on initialization.
 var Quiet bool
@@ -174,7 +174,7 @@ func (r *gRPCresponse) Write(b []byte) (int, error) {
 
 // These methods implement the dns.ResponseWriter interface from Go DNS.
 func (r *gRPCresponse) Close() error              { return nil }
-func (r *gRPCresponse) TsigStatus() error         { return nil }
+func (r *gRPCresponse) TsigStatus() error         { return dns.ErrSecret }
 func (r *gRPCresponse) TsigTimersOnly(b bool)     { return }
 func (r *gRPCresponse) Hijack()                   { return }
 func (r *gRPCresponse) LocalAddr() net.Addr       { return r.localAddr }
@@ -0,0 +1,224 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/doq"
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// ServerQUIC represents an instance of a DNS-over-QUIC server, RFC 9250.
+type ServerQUIC struct {
+	*Server
+	tlsConfig  *tls.Config
+	quicConfig *quic.Config
+
+	mu       sync.Mutex // protects the fields below.
+	pc       net.PacketConn
+	listener *quic.Listener
+	conns    map[*quic.Conn]struct{}
+}
+
+// NewServerQUIC returns a new CoreDNS QUIC server and compiles all plugins in to it.
+func NewServerQUIC(addr string, group []*Config) (*ServerQUIC, error) {
+	s, err := NewServer(addr, group)
+	if err != nil {
+		return nil, err
+	}
+	// The *tls* plugin must make sure that multiple conflicting
+	// TLS configuration return an error: it can only be specified once.
+	var tlsConfig *tls.Config
+	for _, conf := range s.zones {
+		tlsConfig = conf.TLSConfig
+	}
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("DNS-over-QUIC requires TLS, no tls configured for %s", addr)
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{doq.NextProto}
+	tlsConfig.MinVersion = tls.VersionTLS13
+
+	quicConfig := &quic.Config{
+		MaxIdleTimeout:     idleTimeout,
+		MaxIncomingStreams: maxStreams,
+	}
+
+	return &ServerQUIC{Server: s, tlsConfig: tlsConfig, quicConfig: quicConfig, conns: make(map[*quic.Conn]struct{})}, nil
+}
+
+const (
+	idleTimeout = 30 * time.Second // idle timeout of a connection.
+	readTimeout = 5 * time.Second  // maximum time to read a query from a stream.
+	maxStreams  = 256              // maximum number of concurrent queries per connection.
+)
+
+// Serve implements caddy.TCPServer interface.
+func (s *ServerQUIC) Serve(l net.Listener) error { return nil }
+
+// ServePacket implements caddy.UDPServer interface.
+func (s *ServerQUIC) ServePacket(p net.PacketConn) error {
+	l, err := quic.Listen(p, s.tlsConfig, s.quicConfig)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.pc = p
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept(context.Background())
+		if err != nil {
+			if errors.Is(err, quic.ErrServerClosed) {
+				return nil
+			}
+			return err
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn handles the streams of conn, each stream carries one query.
+func (s *ServerQUIC) serveConn(conn *quic.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	for {
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			// The connection is closed.
+			return
+		}
+		go s.serveStream(conn, stream)
+	}
+}
+
+// serveStream reads a query from stream, calls the plugin chain and writes the response to stream.
+func (s *ServerQUIC) serveStream(conn *quic.Conn, stream *quic.Stream) {
+	stream.SetReadDeadline(time.Now().Add(readTimeout))
+	r, err := doq.Read(stream)
+	if err != nil {
+		if err == doq.ErrProtocol {
+			conn.CloseWithError(doq.ProtocolError, err.Error())
+			return
+		}
+		log.Debugf("Failed to read DNS-over-QUIC query from %s: %s", conn.RemoteAddr(), err)
+		stream.CancelRead(doq.RequestCancelled)
+		stream.CancelWrite(doq.RequestCancelled)
+		return
+	}
+
+	w := &DoQWriter{stream: stream, laddr: conn.LocalAddr(), raddr: streamAddr(conn.RemoteAddr())}
+	ctx := context.WithValue(context.Background(), Key{}, s.Server)
+	s.ServeDNS(ctx, w, r)
+	if !w.written {
+		// No response was written, i.e. the query was dropped.
+		stream.CancelWrite(doq.RequestCancelled)
+	}
+	stream.CancelRead(doq.NoError)
+}
+
+// Listen implements caddy.TCPServer interface.
+func (s *ServerQUIC) Listen() (net.Listener, error) { return nil, nil }
+
+// ListenPacket implements caddy.UDPServer interface.
+func (s *ServerQUIC) ListenPacket() (net.PacketConn, error) {
+	p, err := net.ListenPacket("udp", s.Addr[len(transport.QUIC+"://"):])
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// OnStartupComplete lists the sites served by this server
+// and any relevant information, assuming Quiet is false.
+func (s *ServerQUIC) OnStartupComplete() {
+	if Quiet {
+		return
+	}
+
+	out := startUpZones(transport.QUIC+"://", s.Addr, s.zones)
+	if out != "" {
+		fmt.Print(out)
+	}
+	return
+}
+
+// Stop stops the server. It closes the listener and all connections.
+func (s *ServerQUIC) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.CloseWithError(doq.NoError, "")
+	}
+	if s.pc != nil {
+		s.pc.Close()
+	}
+	return nil
+}
+
+// Shutdown stops the server (non gracefully).
+func (s *ServerQUIC) Shutdown() error { return s.Stop() }
+
+// DoQWriter is a dns.ResponseWriter that writes the response to a QUIC stream.
+type DoQWriter struct {
+	stream  *quic.Stream
+	laddr   net.Addr
+	raddr   net.Addr
+	written bool
+}
+
+// WriteMsg implements the dns.ResponseWriter interface. The stream is closed after the response is
+// written.
+func (w *DoQWriter) WriteMsg(m *dns.Msg) error {
+	w.written = true
+	err := doq.Write(w.stream, m)
+	w.stream.Close()
+	return err
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *DoQWriter) Write(b []byte) (int, error) {
+	m := new(dns.Msg)
+	if err := m.Unpack(b); err != nil {
+		return 0, err
+	}
+	return len(b), w.WriteMsg(m)
+}
+
+// These methods implement the dns.ResponseWriter interface.
+func (w *DoQWriter) LocalAddr() net.Addr   { return w.laddr }
+func (w *DoQWriter) RemoteAddr() net.Addr  { return w.raddr }
+func (w *DoQWriter) Close() error          { return w.stream.Close() }
+func (w *DoQWriter) TsigStatus() error     { return dns.ErrSecret }
+func (w *DoQWriter) TsigTimersOnly(b bool) {}
+func (w *DoQWriter) Hijack()               {}
+
+// streamAddr returns addr as a TCP address, so plugins treat the query as received over a stream
+// transport and don't truncate the response.
+func streamAddr(addr net.Addr) net.Addr {
+	if u, ok := addr.(*net.UDPAddr); ok {
+		return &net.TCPAddr{IP: u.IP, Port: u.Port, Zone: u.Zone}
+	}
+	return addr
+}
@@ -0,0 +1,164 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/doq"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// testTLSConfig returns a TLS config with a self-signed certificate for 127.0.0.1.
+func testTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "coredns"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func TestNewServerQUIC(t *testing.T) {
+	if _, err := NewServerQUIC("quic://127.0.0.1:853", []*Config{testConfig("quic", testPlugin{})}); err == nil {
+		t.Errorf("Expected error for NewServerQUIC without TLS")
+	}
+
+	c := testConfig("quic", testPlugin{})
+	c.TLSConfig = testTLSConfig(t)
+	s, err := NewServerQUIC("quic://127.0.0.1:853", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServerQUIC, got %s", err)
+	}
+	if len(s.tlsConfig.NextProtos) != 1 || s.tlsConfig.NextProtos[0] != doq.NextProto {
+		t.Errorf("Expected ALPN %q, got %v", doq.NextProto, s.tlsConfig.NextProtos)
+	}
+	if c.TLSConfig.NextProtos != nil {
+		t.Errorf("Expected the TLS config of the zone not to be changed")
+	}
+}
+
+func TestServerQUIC(t *testing.T) {
+	protos := make(chan string, 2) // Network of each query, as seen by the handler.
+	c := testConfig("quic", plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		protos <- w.RemoteAddr().Network()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, test.A("www.example.com. 3600 IN A 192.0.2.1"))
+		w.WriteMsg(m)
+		return 0, nil
+	}))
+	c.TLSConfig = testTLSConfig(t)
+
+	s, err := NewServerQUIC("quic://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServePacket(pc)
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, pc.LocalAddr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{doq.NextProto}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer conn.CloseWithError(doq.NoError, "")
+
+	// Two queries, each on its own stream.
+	for i := 0; i < 2; i++ {
+		stream, err := conn.OpenStreamSync(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.com.", dns.TypeA)
+		if err := doq.Write(stream, m); err != nil {
+			t.Fatal(err)
+		}
+		stream.Close()
+
+		resp, err := doq.Read(stream)
+		if err != nil {
+			t.Fatalf("Failed to read response: %s", err)
+		}
+		if len(resp.Answer) != 1 || resp.Answer[0].(*dns.A).A.String() != "192.0.2.1" {
+			t.Errorf("Expected an A record, got %s", resp)
+		}
+		if proto := <-protos; proto != "tcp" {
+			t.Errorf("Expected the query to be handled as a stream query, got %s", proto)
+		}
+	}
+}
+
+func TestServerQUICProtocolError(t *testing.T) {
+	c := testConfig("quic", testPlugin{})
+	c.TLSConfig = testTLSConfig(t)
+
+	s, err := NewServerQUIC("quic://127.0.0.1:0", []*Config{c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServePacket(pc)
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, pc.LocalAddr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{doq.NextProto}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A message ID other than 0 is a protocol error.
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.com.", dns.TypeA)
+	m.Id = 1
+	buf, _ := m.Pack()
+	stream.Write(append([]byte{byte(len(buf) >> 8), byte(len(buf))}, buf...))
+	stream.Close()
+
+	select {
+	case <-conn.Context().Done():
+	case <-ctx.Done():
+		t.Fatal("Expected the connection to be closed")
+	}
+	var appErr *quic.ApplicationError
+	if err := context.Cause(conn.Context()); !errors.As(err, &appErr) || appErr.ErrorCode != doq.ProtocolError {
+		t.Errorf("Expected the connection to be closed with DOQ_PROTOCOL_ERROR, got %v", err)
+	}
+}
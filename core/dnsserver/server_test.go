@@ -48,6 +48,33 @@ func TestNewServer(t *testing.T) {
 	}
 }
 
+type testUpdatePlugin struct{ testPlugin }
+
+func (tp testUpdatePlugin) Name() string { return "file" }
+
+func TestMsgAcceptFunc(t *testing.T) {
+	update := dns.Header{Bits: uint16(dns.OpcodeUpdate) << 11, Qdcount: 1, Ancount: 2, Nscount: 5, Arcount: 1}
+
+	s, _ := NewServer("127.0.0.1:53", []*Config{testConfig("dns", testPlugin{})})
+	if x := s.msgAcceptFunc(update); x != dns.MsgRejectNotImplemented {
+		t.Errorf("Expected update to be rejected, got %d", x)
+	}
+
+	s, _ = NewServer("127.0.0.1:53", []*Config{testConfig("dns", testUpdatePlugin{})})
+	if x := s.msgAcceptFunc(update); x != dns.MsgAccept {
+		t.Errorf("Expected update to be accepted, got %d", x)
+	}
+	update.Qdcount = 2
+	if x := s.msgAcceptFunc(update); x != dns.MsgReject {
+		t.Errorf("Expected update with two zones to be rejected, got %d", x)
+	}
+	// Queries are still checked as before.
+	query := dns.Header{Qdcount: 1, Nscount: 5}
+	if x := s.msgAcceptFunc(query); x != dns.MsgReject {
+		t.Errorf("Expected query to be rejected, got %d", x)
+	}
+}
+
 func TestIncrementDepthAndCheck(t *testing.T) {
 	ctx := context.Background()
 	var err error
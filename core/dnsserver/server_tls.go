@@ -43,7 +43,7 @@ func (s *ServerTLS) Serve(l net.Listener) error {
 	}
 
 	// Only fill out the TCP server for this one.
-	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp-tls", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp-tls", TsigSecret: s.tsigSecret, MsgAcceptFunc: s.msgAcceptFunc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.Background()
 		s.ServeDNS(ctx, w, r)
 	})}
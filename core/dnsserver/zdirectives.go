@@ -16,17 +16,23 @@ var Directives = []string{
 	"nsid",
 	"root",
 	"bind",
+	"tsig",
 	"debug",
 	"trace",
 	"health",
 	"pprof",
+	"admin",
 	"prometheus",
 	"errors",
 	"log",
 	"dnstap",
+	"acl",
+	"rrl",
 	"chaos",
 	"loadbalance",
+	"rpz",
 	"cache",
+	"validate",
 	"rewrite",
 	"dnssec",
 	"autopath",
@@ -41,6 +47,7 @@ var Directives = []string{
 	"etcd",
 	"loop",
 	"forward",
+	"recursive",
 	"proxy",
 	"erratic",
 	"whoami",
@@ -0,0 +1,134 @@
+// Package acl implements a plugin that enforces access control policies on the
+// queries a server block receives.
+package acl
+
+import (
+	"context"
+	"net"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// ACL enforces access control policies on DNS queries.
+type ACL struct {
+	Next  plugin.Handler
+	Rules []Rule
+}
+
+// Rule defines a list of zones and the policies that apply to queries for those zones.
+type Rule struct {
+	Zones    []string
+	Policies []Policy
+}
+
+// Policy is a single statement in a rule. It matches when the query type is in qtypes (or qtypes
+// is empty) and the client's address is in one of the sources (or there are no sources).
+type Policy struct {
+	action  action
+	qtypes  map[uint16]struct{}
+	sources *cidrSet
+	files   []*cidrFile
+}
+
+type action int
+
+const (
+	// actionNone means no policy matched the query.
+	actionNone action = iota
+	// actionAllow passes the query to the next plugin.
+	actionAllow
+	// actionBlock refuses the query with REFUSED.
+	actionBlock
+	// actionDrop silently drops the query; no reply is sent.
+	actionDrop
+)
+
+func (a action) String() string {
+	switch a {
+	case actionAllow:
+		return "allow"
+	case actionBlock:
+		return "block"
+	case actionDrop:
+		return "drop"
+	}
+	return "none"
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (a ACL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	ip := net.ParseIP(state.IP())
+	server := metrics.WithServer(ctx)
+
+	for _, rule := range a.Rules {
+		zone := plugin.Zones(rule.Zones).Matches(state.Name())
+		if zone == "" {
+			continue
+		}
+
+		act := match(rule.Policies, state.QType(), ip)
+		if act == actionNone {
+			continue
+		}
+		RequestCount.WithLabelValues(server, zone, act.String()).Inc()
+
+		if act == actionBlock {
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			state.SizeAndDo(m)
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		}
+		if act == actionDrop {
+			return dns.RcodeSuccess, nil
+		}
+		// actionAllow, stop evaluating and hand the query to the next plugin.
+		break
+	}
+
+	return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
+}
+
+// match returns the action of the first policy that matches qtype and ip.
+func match(policies []Policy, qtype uint16, ip net.IP) action {
+	for _, p := range policies {
+		if len(p.qtypes) > 0 {
+			if _, ok := p.qtypes[qtype]; !ok {
+				continue
+			}
+		}
+		if !p.contains(ip) {
+			continue
+		}
+		return p.action
+	}
+	return actionNone
+}
+
+// contains returns true if ip is in one of the sources of the policy. A policy without
+// any sources matches every address.
+func (p Policy) contains(ip net.IP) bool {
+	if p.sources == nil && len(p.files) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	if p.sources != nil && p.sources.contains(ip) {
+		return true
+	}
+	for _, f := range p.files {
+		if f.contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Name implements the plugin.Handler interface.
+func (a ACL) Name() string { return "acl" }
@@ -0,0 +1,152 @@
+package acl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func TestACLServeDNS(t *testing.T) {
+	tests := []struct {
+		config        string
+		qname         string
+		qtype         uint16
+		v6            bool
+		expectedRcode int
+		expectedWrite bool // false when the query is dropped
+	}{
+		// Test 0: block everything.
+		{
+			`acl {
+				block
+			}`,
+			"example.org.", dns.TypeA, false, dns.RcodeRefused, true,
+		},
+		// Test 1: block a type, others are allowed.
+		{
+			`acl {
+				block type ANY
+			}`,
+			"example.org.", dns.TypeA, false, dns.RcodeSuccess, true,
+		},
+		{
+			`acl {
+				block type ANY
+			}`,
+			"example.org.", dns.TypeANY, false, dns.RcodeRefused, true,
+		},
+		// Test 3: allow the client's network, block all others.
+		{
+			`acl {
+				allow net 10.240.0.0/16
+				block
+			}`,
+			"example.org.", dns.TypeA, false, dns.RcodeSuccess, true,
+		},
+		{
+			`acl {
+				allow net 192.168.0.0/16
+				block
+			}`,
+			"example.org.", dns.TypeA, false, dns.RcodeRefused, true,
+		},
+		// Test 5: drop.
+		{
+			`acl {
+				drop type AXFR net 10.240.0.1
+			}`,
+			"example.org.", dns.TypeAXFR, false, 0, false,
+		},
+		// Test 6: zones are honoured.
+		{
+			`acl example.net {
+				block
+			}`,
+			"example.org.", dns.TypeA, false, dns.RcodeSuccess, true,
+		},
+		{
+			`acl example.org {
+				block
+			}`,
+			"www.example.org.", dns.TypeA, false, dns.RcodeRefused, true,
+		},
+		// Test 8: IPv6 client.
+		{
+			`acl {
+				block net fe80::/16
+			}`,
+			"example.org.", dns.TypeA, true, dns.RcodeRefused, true,
+		},
+		{
+			`acl {
+				block net 10.0.0.0/8
+			}`,
+			"example.org.", dns.TypeA, true, dns.RcodeSuccess, true,
+		},
+		// Test 10: rules are evaluated in order.
+		{
+			`acl example.org {
+				allow type A
+			}
+			acl {
+				block
+			}`,
+			"example.org.", dns.TypeA, false, dns.RcodeSuccess, true,
+		},
+		{
+			`acl example.org {
+				allow type A
+			}
+			acl {
+				block
+			}`,
+			"example.org.", dns.TypeMX, false, dns.RcodeRefused, true,
+		},
+	}
+
+	ctx := context.TODO()
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.config)
+		c.ServerBlockKeys = []string{"."}
+		a, _, err := aclParse(c)
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse config: %s", i, err)
+		}
+		a.Next = test.NextHandler(dns.RcodeSuccess, nil)
+
+		var w dns.ResponseWriter = &test.ResponseWriter{}
+		if tc.v6 {
+			w = &test.ResponseWriter6{}
+		}
+		rec := dnstest.NewRecorder(w)
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+
+		code, err := a.ServeDNS(ctx, rec, m)
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+
+		if !tc.expectedWrite {
+			if rec.Msg != nil {
+				t.Errorf("Test %d: expected no reply to be written, got %v", i, rec.Msg)
+			}
+			continue
+		}
+
+		rcode := code
+		if rec.Msg != nil {
+			rcode = rec.Msg.Rcode
+		}
+		if rcode != tc.expectedRcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.expectedRcode], dns.RcodeToString[rcode])
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package acl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cidrSet is a set of networks stored in two binary tries, one for IPv4 and one for IPv6,
+// that allows fast lookups even when it holds many thousands of prefixes.
+type cidrSet struct {
+	v4 *node
+	v6 *node
+}
+
+type node struct {
+	child [2]*node
+	// leaf is true when a prefix ends in this node, every address below it is contained.
+	leaf bool
+}
+
+func newCidrSet() *cidrSet { return &cidrSet{v4: new(node), v6: new(node)} }
+
+// insert adds n to the set.
+func (s *cidrSet) insert(n *net.IPNet) {
+	ones, _ := n.Mask.Size()
+	ip, root := s.root(n.IP)
+
+	cur := root
+	for i := 0; i < ones; i++ {
+		if cur.leaf {
+			// A shorter prefix already covers n.
+			return
+		}
+		b := bit(ip, i)
+		if cur.child[b] == nil {
+			cur.child[b] = new(node)
+		}
+		cur = cur.child[b]
+	}
+	cur.leaf = true
+	cur.child = [2]*node{}
+}
+
+// contains returns true if ip is in one of the networks in the set.
+func (s *cidrSet) contains(ip net.IP) bool {
+	ip, cur := s.root(ip)
+	for i := 0; cur != nil; i++ {
+		if cur.leaf {
+			return true
+		}
+		if i == len(ip)*8 {
+			return false
+		}
+		cur = cur.child[bit(ip, i)]
+	}
+	return false
+}
+
+// root returns ip in its canonical length together with the trie for its family.
+func (s *cidrSet) root(ip net.IP) (net.IP, *node) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, s.v4
+	}
+	return ip.To16(), s.v6
+}
+
+func bit(ip net.IP, i int) int { return int(ip[i/8]>>uint(7-i%8)) & 1 }
+
+// parseCIDR parses s as a network in CIDR notation or as a single address.
+func parseCIDR(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address or network: %q", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// parseCIDRs reads networks from r, one per line. Empty lines and everything after a '#' are ignored.
+func parseCIDRs(r io.Reader) (*cidrSet, error) {
+	s := newCidrSet()
+	scanner := bufio.NewScanner(r)
+	l := 0
+	for scanner.Scan() {
+		l++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		n, err := parseCIDR(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", l, err)
+		}
+		s.insert(n)
+	}
+	return s, scanner.Err()
+}
+
+// cidrFile is a cidrSet that is loaded from a file and reloaded when the file changes on disk.
+type cidrFile struct {
+	sync.RWMutex
+	set *cidrSet
+
+	path string
+
+	// mtime and size are only read and modified by a single goroutine
+	mtime time.Time
+	size  int64
+}
+
+func (f *cidrFile) contains(ip net.IP) bool {
+	f.RLock()
+	defer f.RUnlock()
+	if f.set == nil {
+		return false
+	}
+	return f.set.contains(ip)
+}
+
+// readFile (re)reads the file if its size or modification time has changed since the last read.
+func (f *cidrFile) readFile() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err == nil && f.mtime.Equal(stat.ModTime()) && f.size == stat.Size() {
+		return nil
+	}
+
+	set, err := parseCIDRs(file)
+	if err != nil {
+		return fmt.Errorf("%s: %s", f.path, err)
+	}
+
+	f.Lock()
+	f.set = set
+	f.Unlock()
+
+	if stat != nil {
+		f.mtime = stat.ModTime()
+		f.size = stat.Size()
+	}
+	return nil
+}
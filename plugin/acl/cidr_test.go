@@ -0,0 +1,54 @@
+package acl
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestCidrSet(t *testing.T) {
+	set, err := parseCIDRs(strings.NewReader(`
+10.0.0.0/8
+192.168.1.1
+192.168.2.0/23 # includes 192.168.3.0/24
+2001:db8::/32
+::1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"10.1.2.3", true},
+		{"11.1.2.3", false},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"192.168.3.200", true},
+		{"192.168.4.1", false},
+		{"2001:db8::53", true},
+		{"2001:db9::53", false},
+		{"::1", true},
+		{"::2", false},
+		{"::ffff:10.0.0.1", true}, // IPv4 mapped
+	}
+	for i, tc := range tests {
+		if got := set.contains(net.ParseIP(tc.ip)); got != tc.expected {
+			t.Errorf("Test %d: expected %t for %s, got %t", i, tc.expected, tc.ip, got)
+		}
+	}
+}
+
+func TestCidrSetShorterPrefix(t *testing.T) {
+	set := newCidrSet()
+	n, _ := parseCIDR("10.0.0.0/24")
+	set.insert(n)
+	n, _ = parseCIDR("10.0.0.0/8")
+	set.insert(n)
+
+	if !set.contains(net.ParseIP("10.1.0.1")) {
+		t.Errorf("Expected 10.1.0.1 to be contained after inserting a shorter prefix")
+	}
+}
@@ -0,0 +1,18 @@
+package acl
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	// RequestCount is the number of queries for which a policy matched, by the action that was taken.
+	RequestCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "acl",
+		Name:      "requests_total",
+		Help:      "Counter of queries that matched a policy, by action.",
+	}, []string{"server", "zone", "action"})
+)
@@ -0,0 +1,177 @@
+package acl
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("acl")
+
+func init() {
+	caddy.RegisterPlugin("acl", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+// reloadInterval is how often files are checked for changes.
+var reloadInterval = 5 * time.Second
+
+func setup(c *caddy.Controller) error {
+	a, files, err := aclParse(c)
+	if err != nil {
+		return plugin.Error("acl", err)
+	}
+
+	if len(files) > 0 {
+		parseChan := make(chan bool)
+
+		c.OnStartup(func() error {
+			go func() {
+				ticker := time.NewTicker(reloadInterval)
+				for {
+					select {
+					case <-parseChan:
+						ticker.Stop()
+						return
+					case <-ticker.C:
+						for _, f := range files {
+							if err := f.readFile(); err != nil {
+								log.Warningf("Failed to reload %s", err)
+							}
+						}
+					}
+				}
+			}()
+			return nil
+		})
+
+		c.OnShutdown(func() error {
+			close(parseChan)
+			return nil
+		})
+	}
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, RequestCount)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		a.Next = next
+		return a
+	})
+
+	return nil
+}
+
+func aclParse(c *caddy.Controller) (ACL, []*cidrFile, error) {
+	a := ACL{}
+	files := map[string]*cidrFile{}
+	fileList := []*cidrFile{}
+
+	config := dnsserver.GetConfig(c)
+
+	for c.Next() {
+		r := Rule{}
+		r.Zones = make([]string, len(c.ServerBlockKeys))
+		copy(r.Zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			r.Zones = args
+		}
+		for i := range r.Zones {
+			r.Zones[i] = plugin.Host(r.Zones[i]).Normalize()
+		}
+
+		for c.NextBlock() {
+			p := Policy{}
+			switch strings.ToLower(c.Val()) {
+			case "allow":
+				p.action = actionAllow
+			case "block":
+				p.action = actionBlock
+			case "drop":
+				p.action = actionDrop
+			default:
+				return a, nil, c.Errf("unknown action '%s'", c.Val())
+			}
+
+			args := c.RemainingArgs()
+			section := ""
+			for _, arg := range args {
+				switch arg {
+				case "type", "net", "file":
+					section = arg
+					continue
+				}
+
+				switch section {
+				case "type":
+					if arg == "*" {
+						continue
+					}
+					qtype, ok := dns.StringToType[strings.ToUpper(arg)]
+					if !ok {
+						return a, nil, c.Errf("unknown query type '%s'", arg)
+					}
+					if p.qtypes == nil {
+						p.qtypes = make(map[uint16]struct{})
+					}
+					p.qtypes[qtype] = struct{}{}
+
+				case "net":
+					nets := []string{arg}
+					if arg == "*" {
+						nets = []string{"0.0.0.0/0", "::/0"}
+					}
+					if p.sources == nil {
+						p.sources = newCidrSet()
+					}
+					for _, s := range nets {
+						n, err := parseCIDR(s)
+						if err != nil {
+							return a, nil, c.Err(err.Error())
+						}
+						p.sources.insert(n)
+					}
+
+				case "file":
+					fileName := arg
+					if !path.IsAbs(fileName) && config.Root != "" {
+						fileName = path.Join(config.Root, fileName)
+					}
+					f, ok := files[fileName]
+					if !ok {
+						f = &cidrFile{path: fileName}
+						if err := f.readFile(); err != nil {
+							return a, nil, err
+						}
+						files[fileName] = f
+						fileList = append(fileList, f)
+					}
+					p.files = append(p.files, f)
+
+				default:
+					return a, nil, c.Errf("expected 'type', 'net' or 'file', got '%s'", arg)
+				}
+			}
+			r.Policies = append(r.Policies, p)
+		}
+		if len(r.Policies) == 0 {
+			return a, nil, fmt.Errorf("no policies defined for zones %v", r.Zones)
+		}
+		a.Rules = append(a.Rules, r)
+	}
+
+	return a, fileList, nil
+}
@@ -0,0 +1,115 @@
+package acl
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rules     int
+	}{
+		{`acl {
+			block
+		}`, false, 1},
+		{`acl example.org {
+			allow net 10.0.0.0/8 192.168.0.1 2001:db8::/32
+			block type ANY AXFR
+			drop net *
+		}`, false, 1},
+		{`acl example.org {
+			allow type A net 10.0.0.0/8
+		}
+		acl {
+			block
+		}`, false, 2},
+		// fails
+		{`acl`, true, 0},
+		{`acl {
+		}`, true, 0},
+		{`acl {
+			deny
+		}`, true, 0},
+		{`acl {
+			block type FOO
+		}`, true, 0},
+		{`acl {
+			block net 10.0.0.0/33
+		}`, true, 0},
+		{`acl {
+			block 10.0.0.0/8
+		}`, true, 0},
+		{`acl {
+			block file /does/not/exist
+		}`, true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		a, _, err := aclParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+		if len(a.Rules) != test.rules {
+			t.Errorf("Test %d: expected %d rules, got %d", i, test.rules, len(a.Rules))
+		}
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "blocklist")
+	if err := ioutil.WriteFile(name, []byte("# bad networks\n10.240.0.0/24\n\n192.0.2.1 # single host\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := caddy.NewTestController("dns", `acl {
+		block file `+name+`
+	}`)
+	a, files, err := aclParse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+	p := a.Rules[0].Policies[0]
+	for _, ip := range []string{"10.240.0.1", "192.0.2.1"} {
+		if !p.contains(net.ParseIP(ip)) {
+			t.Errorf("Expected %s to be contained in %s", ip, name)
+		}
+	}
+	if p.contains(net.ParseIP("192.0.2.2")) {
+		t.Errorf("Expected 192.0.2.2 not to be contained in %s", name)
+	}
+
+	if err := ioutil.WriteFile(name, []byte("not-a-network\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := files[0].readFile(); err == nil {
+		t.Errorf("Expected error when reading invalid file")
+	}
+	// The previous set must still be used.
+	if !p.contains(net.ParseIP("192.0.2.1")) {
+		t.Errorf("Expected 192.0.2.1 to still be contained after failed reload")
+	}
+}
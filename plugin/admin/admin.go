@@ -0,0 +1,87 @@
+// Package admin implements an authenticated HTTP API to inspect and change the state of the running
+// servers.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+// admin serves the admin API.
+type admin struct {
+	addr  string
+	token string
+
+	configs []*dnsserver.Config // The configs of all server blocks, set on startup.
+
+	ln  net.Listener
+	mux *http.ServeMux
+}
+
+func (a *admin) OnStartup() error {
+	ln, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		log.Errorf("Failed to start admin handler: %s", err)
+		return err
+	}
+	a.ln = ln
+
+	a.mux = http.NewServeMux()
+	a.mux.HandleFunc("/servers", a.auth(a.servers))
+	a.mux.HandleFunc("/cache", a.auth(a.cache))
+	a.mux.HandleFunc("/forward", a.auth(a.forward))
+	a.mux.HandleFunc("/zones", a.auth(a.zones))
+	a.mux.HandleFunc("/secondary/transfer", a.auth(a.transfer))
+
+	go func() { http.Serve(a.ln, a.mux) }()
+	return nil
+}
+
+func (a *admin) OnShutdown() error {
+	if a.ln == nil {
+		return nil
+	}
+	err := a.ln.Close()
+	a.ln = nil
+	return err
+}
+
+// auth returns a handler that calls h only when the request carries the token as a bearer token.
+func (a *admin) auth(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+		if !strings.HasPrefix(token, bearer) || subtle.ConstantTimeCompare([]byte(token[len(bearer):]), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="coredns"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// allow returns true if the method of r is one of methods, if not it writes an error to w.
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warningf("Failed to write response: %s", err)
+	}
+}
+
+const bearer = "Bearer "
@@ -0,0 +1,170 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/cache"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/forward"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func backend() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A(r.Question[0].Name + " 60 IN A 127.0.0.53")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+// newAdmin returns a started admin with one server block, that has cache, file and forward.
+func newAdmin(t *testing.T) *admin {
+	c := cache.New()
+	c.Next = backend()
+	for _, name := range []string{"example.org.", "www.example.org."} {
+		req := new(dns.Msg)
+		req.SetQuestion(name, dns.TypeA)
+		c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+	}
+
+	z := file.NewZone("example.net.", "stdin")
+	z.Insert(test.SOA("example.net. 3600 IN SOA ns.example.net. admin.example.net. 1234 3600 600 86400 300"))
+
+	f := forward.New()
+	f.SetProxy(forward.NewProxy("127.0.0.1:53", transport.DNS))
+
+	conf := &dnsserver.Config{Zone: ".", Port: "1053", Transport: transport.DNS, ListenHosts: []string{""}}
+	conf.AddPlugin(func(next plugin.Handler) plugin.Handler { return c })
+	conf.AddPlugin(func(next plugin.Handler) plugin.Handler {
+		return file.File{Next: next, Zones: file.Zones{Z: map[string]*file.Zone{"example.net.": z}, Names: []string{"example.net."}}}
+	})
+	conf.AddPlugin(func(next plugin.Handler) plugin.Handler { return f })
+	if _, err := dnsserver.NewServer("dns://:1053", []*dnsserver.Config{conf}); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &admin{addr: "127.0.0.1:0", token: "s3cr3t", configs: []*dnsserver.Config{conf}}
+	if err := a.OnStartup(); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func do(t *testing.T, a *admin, method, path, token string, v interface{}) int {
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s%s", a.ln.Addr(), path), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to query %s: %s", path, err)
+	}
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Unable to decode response of %s: %s", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAuth(t *testing.T) {
+	a := newAdmin(t)
+	defer a.OnShutdown()
+
+	for _, token := range []string{"", "wrong", "s3cr3t2"} {
+		if code := do(t, a, http.MethodGet, "/servers", token, nil); code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d for token %q, got %d", http.StatusUnauthorized, token, code)
+		}
+	}
+	if code := do(t, a, http.MethodPost, "/servers", "s3cr3t", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, code)
+	}
+}
+
+func TestServers(t *testing.T) {
+	a := newAdmin(t)
+	defer a.OnShutdown()
+
+	servers := []Server{}
+	if code := do(t, a, http.MethodGet, "/servers", "s3cr3t", &servers); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+	}
+	if len(servers) != 1 || servers[0].Server != "dns://.:1053" {
+		t.Fatalf("Expected server dns://.:1053, got %v", servers)
+	}
+	if p := strings.Join(servers[0].Plugins, ","); p != "cache,file,forward" {
+		t.Errorf("Expected plugins cache,file,forward, got %s", p)
+	}
+}
+
+func TestCache(t *testing.T) {
+	a := newAdmin(t)
+	defer a.OnShutdown()
+
+	entries := []CacheEntry{}
+	do(t, a, http.MethodGet, "/cache", "s3cr3t", &entries)
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 cache entries, got %d", len(entries))
+	}
+	do(t, a, http.MethodGet, "/cache?name=WWW.example.org&type=a", "s3cr3t", &entries)
+	if len(entries) != 1 || entries[0].Name != "www.example.org." || entries[0].Server != "dns://.:1053" {
+		t.Errorf("Expected 1 cache entry for www.example.org., got %v", entries)
+	}
+
+	if code := do(t, a, http.MethodDelete, "/cache", "s3cr3t", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a purge without a name, got %d", http.StatusBadRequest, code)
+	}
+	if code := do(t, a, http.MethodGet, "/cache?type=foo", "s3cr3t", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unknown type, got %d", http.StatusBadRequest, code)
+	}
+
+	purged := struct {
+		Purged int `json:"purged"`
+	}{}
+	do(t, a, http.MethodDelete, "/cache?name=www.example.org", "s3cr3t", &purged)
+	if purged.Purged != 1 {
+		t.Errorf("Expected 1 purged item, got %d", purged.Purged)
+	}
+	do(t, a, http.MethodGet, "/cache", "s3cr3t", &entries)
+	if len(entries) != 1 || entries[0].Name != "example.org." {
+		t.Errorf("Expected only example.org. to be left, got %v", entries)
+	}
+}
+
+func TestForwardAndZones(t *testing.T) {
+	a := newAdmin(t)
+	defer a.OnShutdown()
+
+	upstreams := []Upstream{}
+	do(t, a, http.MethodGet, "/forward", "s3cr3t", &upstreams)
+	if len(upstreams) != 1 || upstreams[0].Addr != "127.0.0.1:53" || upstreams[0].From != "." || upstreams[0].Down {
+		t.Errorf("Expected healthy upstream 127.0.0.1:53, got %v", upstreams)
+	}
+
+	zones := []Zone{}
+	do(t, a, http.MethodGet, "/zones", "s3cr3t", &zones)
+	if len(zones) != 1 || zones[0].Zone != "example.net." || zones[0].Plugin != "file" || zones[0].Serial != 1234 {
+		t.Errorf("Expected zone example.net. with serial 1234, got %v", zones)
+	}
+
+	if code := do(t, a, http.MethodPost, "/secondary/transfer?zone=example.net", "s3cr3t", nil); code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for a zone that isn't secondary, got %d", http.StatusNotFound, code)
+	}
+}
@@ -0,0 +1,251 @@
+package admin
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/auto"
+	"github.com/coredns/coredns/plugin/cache"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/forward"
+	"github.com/coredns/coredns/plugin/secondary"
+
+	"github.com/miekg/dns"
+)
+
+// Server is a server block, as returned by /servers.
+type Server struct {
+	Server    string   `json:"server"`
+	Zone      string   `json:"zone"`
+	Port      string   `json:"port"`
+	Transport string   `json:"transport"`
+	Plugins   []string `json:"plugins"` // The plugin chain, in the order queries pass through it.
+}
+
+// CacheEntry is an item of the cache of a server block, as returned by /cache.
+type CacheEntry struct {
+	Server string `json:"server"`
+	cache.Entry
+}
+
+// Upstream is an upstream of forward, as returned by /forward.
+type Upstream struct {
+	Server  string `json:"server"`
+	From    string `json:"from"`
+	Addr    string `json:"addr"`
+	Fails   uint32 `json:"fails"`
+	Down    bool   `json:"down"`
+	Reached bool   `json:"reached"` // Passed a health check at least once.
+}
+
+// Zone is a zone loaded by file, auto or secondary, as returned by /zones and /secondary/transfer.
+type Zone struct {
+	Server string `json:"server"`
+	Plugin string `json:"plugin"`
+	Zone   string `json:"zone"`
+	Serial int64  `json:"serial"` // -1 when the zone isn't loaded.
+	Error  string `json:"error,omitempty"`
+}
+
+// servers lists the server blocks and their plugin chains.
+func (a *admin) servers(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	servers := []Server{}
+	for _, conf := range a.configs {
+		servers = append(servers, Server{
+			Server:    serverName(conf),
+			Zone:      conf.Zone,
+			Port:      conf.Port,
+			Transport: conf.Transport,
+			Plugins:   chain(conf),
+		})
+	}
+	writeJSON(w, servers)
+}
+
+// cache dumps the cache entries with a GET and purges them with a DELETE. Both can be limited to a
+// name and type with the name and type query parameters, and to all names below name with subtree=true.
+// A purge requires a name.
+func (a *admin) cache(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet, http.MethodDelete) {
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name != "" {
+		name = plugin.Name(name).Normalize()
+	}
+	subtree := r.URL.Query().Get("subtree") == "true"
+	qtype := dns.TypeNone
+	if t := r.URL.Query().Get("type"); t != "" {
+		var ok bool
+		if qtype, ok = dns.StringToType[strings.ToUpper(t)]; !ok {
+			http.Error(w, "unknown type: "+t, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if r.Method == http.MethodDelete {
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		n := 0
+		for _, conf := range a.configs {
+			for _, h := range conf.Handlers() {
+				if c, ok := h.(*cache.Cache); ok {
+					n += c.Purge(name, qtype, subtree)
+				}
+			}
+		}
+		log.Infof("Purged %d items for %s from the cache", n, name)
+		writeJSON(w, struct {
+			Purged int `json:"purged"`
+		}{n})
+		return
+	}
+
+	if name == "" {
+		name, subtree = ".", true
+	}
+	entries := []CacheEntry{}
+	for _, conf := range a.configs {
+		for _, h := range conf.Handlers() {
+			c, ok := h.(*cache.Cache)
+			if !ok {
+				continue
+			}
+			for _, e := range c.Entries() {
+				if !e.Matches(name, qtype, subtree) {
+					continue
+				}
+				entries = append(entries, CacheEntry{Server: serverName(conf), Entry: e})
+			}
+		}
+	}
+	writeJSON(w, entries)
+}
+
+// forward lists the upstreams of forward with their health.
+func (a *admin) forward(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	upstreams := []Upstream{}
+	for _, conf := range a.configs {
+		for _, h := range conf.Handlers() {
+			f, ok := h.(*forward.Forward)
+			if !ok {
+				continue
+			}
+			proxies := f.List()
+			sort.Slice(proxies, func(i, j int) bool { return proxies[i].Addr() < proxies[j].Addr() })
+			for _, p := range proxies {
+				upstreams = append(upstreams, Upstream{
+					Server:  serverName(conf),
+					From:    f.From(),
+					Addr:    p.Addr(),
+					Fails:   p.Fails(),
+					Down:    p.Down(f.MaxFails()),
+					Reached: p.Reached(),
+				})
+			}
+		}
+	}
+	writeJSON(w, upstreams)
+}
+
+// zones lists the zones of file, auto and secondary with their serials.
+func (a *admin) zones(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	zones := []Zone{}
+	for _, conf := range a.configs {
+		for _, h := range conf.Handlers() {
+			switch x := h.(type) {
+			case secondary.Secondary:
+				zones = appendZones(zones, conf, "secondary", x.Zones.Names, func(name string) *file.Zone { return x.Zones.Z[name] })
+			case file.File:
+				zones = appendZones(zones, conf, "file", x.Zones.Names, func(name string) *file.Zone { return x.Zones.Z[name] })
+			case auto.Auto:
+				zones = appendZones(zones, conf, "auto", x.Zones.Names(), x.Zones.Zones)
+			}
+		}
+	}
+	writeJSON(w, zones)
+}
+
+// transfer transfers the zones of secondary from their primaries. With the zone query parameter only
+// that zone is transferred.
+func (a *admin) transfer(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	zone := r.URL.Query().Get("zone")
+	if zone != "" {
+		zone = plugin.Host(zone).Normalize()
+	}
+
+	zones := []Zone{}
+	for _, conf := range a.configs {
+		for _, h := range conf.Handlers() {
+			s, ok := h.(secondary.Secondary)
+			if !ok {
+				continue
+			}
+			for _, name := range s.Zones.Names {
+				if zone != "" && zone != name {
+					continue
+				}
+				z := s.Zones.Z[name]
+				if z == nil {
+					continue
+				}
+				zo := Zone{Server: serverName(conf), Plugin: "secondary", Zone: name}
+				if err := z.TransferIn(); err != nil {
+					zo.Error = err.Error()
+				}
+				zo.Serial = z.SOASerialIfDefined()
+				zones = append(zones, zo)
+			}
+		}
+	}
+	if zone != "" && len(zones) == 0 {
+		http.Error(w, "no secondary zone: "+zone, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, zones)
+}
+
+// appendZones appends the zones in names, which are looked up with get, to zones.
+func appendZones(zones []Zone, conf *dnsserver.Config, name string, names []string, get func(string) *file.Zone) []Zone {
+	for _, n := range names {
+		z := get(n)
+		if z == nil {
+			continue
+		}
+		zones = append(zones, Zone{Server: serverName(conf), Plugin: name, Zone: n, Serial: z.SOASerialIfDefined()})
+	}
+	return zones
+}
+
+// serverName returns the name of the server block of conf, i.e. dns://example.org.:53.
+func serverName(conf *dnsserver.Config) string {
+	return conf.Transport + "://" + conf.Zone + ":" + conf.Port
+}
+
+// chain returns the names of the plugins of conf, in the order of the plugin chain.
+func chain(conf *dnsserver.Config) []string {
+	names := []string{}
+	for _, d := range dnsserver.Directives {
+		if conf.Handler(d) != nil {
+			names = append(names, d)
+		}
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/mholt/caddy"
+)
+
+var log = clog.NewWithPlugin("admin")
+
+const defaultAddr = "localhost:8053"
+
+func init() {
+	caddy.RegisterPlugin("admin", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	a, err := adminParse(c)
+	if err != nil {
+		return plugin.Error("admin", err)
+	}
+
+	c.OnStartup(func() error {
+		a.configs = dnsserver.GetConfigs(c)
+		return nil
+	})
+	c.OnStartup(a.OnStartup)
+	c.OnRestart(a.OnShutdown)
+	c.OnFinalShutdown(a.OnShutdown)
+
+	// Don't do AddPlugin, as admin is not *really* a plugin just a separate webserver running.
+	return nil
+}
+
+func adminParse(c *caddy.Controller) (*admin, error) {
+	a := &admin{addr: defaultAddr}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			if _, _, err := net.SplitHostPort(args[0]); err != nil {
+				return nil, err
+			}
+			a.addr = args[0]
+		default:
+			return nil, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "token":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				a.token = c.Val()
+			case "token_file":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				path := c.Val()
+				if !filepath.IsAbs(path) && dnsserver.GetConfig(c).Root != "" {
+					path = filepath.Join(dnsserver.GetConfig(c).Root, path)
+				}
+				buf, err := ioutil.ReadFile(path)
+				if err != nil {
+					return nil, err
+				}
+				a.token = strings.TrimSpace(string(buf))
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+			if c.NextArg() {
+				return nil, c.ArgErr()
+			}
+		}
+	}
+	if a.token == "" {
+		return nil, errors.New("a token is required")
+	}
+	return a, nil
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetupAdmin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input         string
+		shouldErr     bool
+		expectedAddr  string
+		expectedToken string
+	}{
+		{"admin {\ntoken s3cr3t\n}", false, defaultAddr, "s3cr3t"},
+		{"admin localhost:8054 {\ntoken s3cr3t\n}", false, "localhost:8054", "s3cr3t"},
+		{"admin {\ntoken_file " + tokenFile + "\n}", false, defaultAddr, "s3cr3t"},
+		// fails
+		{"admin", true, "", ""},
+		{"admin localhost {\ntoken s3cr3t\n}", true, "", ""},
+		{"admin localhost:8054 localhost:8055 {\ntoken s3cr3t\n}", true, "", ""},
+		{"admin {\ntoken\n}", true, "", ""},
+		{"admin {\ntoken a b\n}", true, "", ""},
+		{"admin {\ntoken_file " + filepath.Join(dir, "missing") + "\n}", true, "", ""},
+		{"admin {\ntoken s3cr3t\nfoo\n}", true, "", ""},
+		{"admin {\ntoken s3cr3t\n}\nadmin {\ntoken s3cr3t\n}", true, "", ""},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		a, err := adminParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if a.addr != tc.expectedAddr {
+			t.Errorf("Test %d: expected address %s, got %s", i, tc.expectedAddr, a.addr)
+		}
+		if a.token != tc.expectedToken {
+			t.Errorf("Test %d: expected token %s, got %s", i, tc.expectedToken, a.token)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/request"
 
@@ -31,9 +32,14 @@ type (
 		re        *regexp.Regexp
 
 		// In the future this should be something like ZoneMeta that contains all this stuff.
-		transferTo []string
-		noReload   bool
-		upstream   upstream.Upstream // Upstream for looking up names during the resolution process.
+		transferTo   []string
+		transferKeys map[string]tsig.Key // TSIG keys for addresses in transferTo.
+		noReload     bool
+		upstream     upstream.Upstream // Upstream for looking up names during the resolution process.
+
+		updates *file.UpdatePolicy // Who may dynamically update the zones.
+		persist bool               // Write dynamic updates back to the zone files.
+		journal bool               // Keep a journal of dynamic updates next to each zone file.
 
 		duration time.Duration
 	}
@@ -61,6 +67,10 @@ func (a Auto) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeServerFailure, nil
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		return z.ServeUpdate(ctx, w, r)
+	}
+
 	if state.QType() == dns.TypeAXFR || state.QType() == dns.TypeIXFR {
 		xfr := file.Xfr{Zone: z}
 		return xfr.ServeDNS(ctx, w, r)
@@ -9,6 +9,7 @@ import (
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/metrics"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/parse"
@@ -158,8 +159,31 @@ func autoParse(c *caddy.Controller) (Auto, error) {
 					return a, err
 				}
 
+			case "update":
+				var err error
+				a.loader.updates, err = file.ParseUpdatePolicy(c.RemainingArgs(), dnsserver.GetConfig(c).TsigKeys)
+				if err != nil {
+					return a, c.Err(err.Error())
+				}
+
+			case "persist":
+				if c.NextArg() {
+					return a, c.ArgErr()
+				}
+				a.loader.persist = true
+
+			case "journal":
+				if c.NextArg() {
+					return a, c.ArgErr()
+				}
+				a.loader.journal = true
+
 			default:
-				t, _, e := parse.Transfer(c, false)
+				t, _, key, e := parse.Transfer(c, false)
+				if e != nil {
+					return a, e
+				}
+				a.loader.transferKeys, e = file.TransferKeys(c, a.loader.transferKeys, key, t)
 				if e != nil {
 					return a, e
 				}
@@ -3,7 +3,11 @@ package auto
 import (
 	"testing"
 
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
+
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
 func TestAutoParse(t *testing.T) {
@@ -56,7 +60,37 @@ func TestAutoParse(t *testing.T) {
 			}`,
 			false, "/tmp", "bliep", `(.*)`, []string{"127.0.0.1:53", "127.0.0.2:53"},
 		},
+		{
+			`auto {
+				directory /tmp
+				update key update.example.org. net 10.0.0.0/8
+				persist
+				journal
+			}`,
+			false, "/tmp", "${1}", `db\.(.*)`, nil,
+		},
 		// errors
+		{
+			`auto example.org {
+				directory /tmp
+				update key unknown.example.org.
+			}`,
+			true, "/tmp", "${1}", `db\.(.*)`, nil,
+		},
+		{
+			`auto example.org {
+				directory /tmp
+				update
+			}`,
+			true, "/tmp", "${1}", `db\.(.*)`, nil,
+		},
+		{
+			`auto example.org {
+				directory /tmp
+				journal /tmp/journal
+			}`,
+			true, "/tmp", "${1}", `db\.(.*)`, nil,
+		},
 		{
 			`auto example.org {
 				directory
@@ -95,8 +129,10 @@ func TestAutoParse(t *testing.T) {
 		},
 	}
 
+	k := tsig.Key{Name: "update.example.org.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"}
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.inputFileRules)
+		dnsserver.GetConfig(c).TsigKeys = map[string]tsig.Key{k.Name: k}
 		a, err := autoParse(c)
 
 		if err == nil && test.shouldErr {
@@ -5,6 +5,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/coredns/coredns/plugin/file"
 
@@ -26,6 +27,11 @@ func (a Auto) Walk() error {
 			return nil
 		}
 
+		// Skip journals and the temporary files used when writing updates back to a zone file.
+		if strings.HasPrefix(info.Name(), ".") || strings.HasSuffix(info.Name(), file.JournalExt) {
+			return nil
+		}
+
 		match, origin := matches(a.loader.re, info.Name(), a.loader.template)
 		if !match {
 			return nil
@@ -55,6 +61,15 @@ func (a Auto) Walk() error {
 		zo.NoReload = a.loader.noReload
 		zo.Upstream = a.loader.upstream
 		zo.TransferTo = a.loader.transferTo
+		zo.TransferKeys = a.loader.transferKeys
+		zo.Updates = a.loader.updates
+		zo.Persist = a.loader.persist
+		if a.loader.journal {
+			zo.Journal = path + file.JournalExt
+			if err := zo.ReplayJournal(); err != nil {
+				log.Warningf("Replaying journal for `%s': %v", origin, err)
+			}
+		}
 
 		a.Zones.Add(zo, origin)
 
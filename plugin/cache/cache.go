@@ -37,6 +37,17 @@ type Cache struct {
 	duration   time.Duration
 	percentage int
 
+	// Serve stale.
+	staleUpTo    time.Duration // How long expired items may be served, 0 disables serving stale.
+	staleTimeout time.Duration // How long to wait for the next plugin before serving a stale item.
+
+	// Persistence.
+	persist         string        // Snapshot file, empty disables persistence.
+	persistInterval time.Duration // How often the snapshot is written.
+
+	// Control queries, nil disables them.
+	control []*net.IPNet
+
 	// Testing.
 	now func() time.Time
 }
@@ -57,7 +68,12 @@ func New() *Cache {
 		prefetch:   0,
 		duration:   1 * time.Minute,
 		percentage: 10,
-		now:        time.Now,
+
+		staleTimeout: defaultStaleTimeout,
+
+		persistInterval: defaultPersistInterval,
+
+		now: time.Now,
 	}
 }
 
@@ -69,6 +85,10 @@ func key(m *dns.Msg, t response.Type, do bool) (bool, uint64) {
 	if m.Truncated {
 		return false, 0
 	}
+	// Nor responses to queries with checking disabled, these skipped DNSSEC validation.
+	if m.CheckingDisabled {
+		return false, 0
+	}
 	// Nor errors or Meta or Update
 	if t == response.OtherError || t == response.Meta || t == response.Update {
 		return false, 0
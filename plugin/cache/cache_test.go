@@ -20,6 +20,7 @@ type cacheTestCase struct {
 	Authoritative      bool
 	RecursionAvailable bool
 	Truncated          bool
+	CheckingDisabled   bool
 	shouldCache        bool
 }
 
@@ -69,6 +70,15 @@ var cacheTestCases = []cacheTestCase{
 		in:          test.Case{},
 		shouldCache: false,
 	},
+	{
+		CheckingDisabled: true,
+		Case: test.Case{
+			Qname: "cd.miek.nl.", Qtype: dns.TypeMX,
+			Answer: []dns.RR{test.MX("cd.miek.nl.	1800	IN	MX	1 aspmx.l.google.com.")},
+		},
+		in:          test.Case{},
+		shouldCache: false,
+	},
 	{
 		RecursionAvailable: true, Authoritative: true,
 		Case: test.Case{
@@ -139,6 +149,7 @@ func cacheMsg(m *dns.Msg, tc cacheTestCase) *dns.Msg {
 	m.Authoritative = tc.Authoritative
 	m.Rcode = tc.Rcode
 	m.Truncated = tc.Truncated
+	m.CheckingDisabled = tc.CheckingDisabled
 	m.Answer = tc.in.Answer
 	m.Ns = tc.in.Ns
 	// m.Extra = tc.in.Extra don't copy Extra, because we don't care and fake EDNS0 DO with tc.Do.
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// controlZone is the zone of the control queries, they use the CH class.
+const controlZone = "cache.coredns."
+
+// Operations of the control queries.
+const (
+	opDump      = "dump"      // list the items for a name and all names below it.
+	opPurge     = "purge"     // remove the items for a name.
+	opPurgeTree = "purgetree" // remove the items for a name and all names below it.
+)
+
+// serveControl answers a control query, that dumps or purges items in the cache. Queries from
+// addresses not in c.control are refused. The query name has the form NAME.OP[.TYPE].cache.coredns.,
+// the answer is one TXT record per item for a dump, and the number of removed items for a purge. A dump that
+// doesn't fit in the client's buffer is truncated.
+func (c *Cache) serveControl(w dns.ResponseWriter, state request.Request) (int, error) {
+	if !c.allowControl(state.IP()) {
+		log.Warningf("Refused control query %q from %s", state.Name(), state.IP())
+		return dns.RcodeRefused, nil
+	}
+	if state.QType() != dns.TypeTXT {
+		return dns.RcodeRefused, nil
+	}
+	op, name, qtype, ok := parseControl(state.Name())
+	if !ok {
+		return dns.RcodeNameError, nil
+	}
+
+	var txt []string
+	switch op {
+	case opDump:
+		for _, e := range c.Entries() {
+			if e.Matches(name, qtype, true) {
+				txt = append(txt, fmt.Sprintf("%s %s ttl=%d hits=%d %s %s", e.Name, e.Type, e.TTL, e.Hits, e.Class, e.Rcode))
+			}
+		}
+	case opPurge, opPurgeTree:
+		n := c.Purge(name, qtype, op == opPurgeTree)
+		log.Infof("Purged %d items for %s from the cache", n, name)
+		txt = append(txt, fmt.Sprintf("purged %d", n))
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative = true
+	hdr := dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeTXT, Class: dns.ClassCHAOS, Ttl: 0}
+	for _, t := range txt {
+		m.Answer = append(m.Answer, &dns.TXT{Hdr: hdr, Txt: []string{t}})
+	}
+	// A dump of a large cache doesn't fit, set TC so the client retries over TCP.
+	state.SizeAndDo(m)
+	m.Truncate(state.Size())
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Chaos implements the dnsserver.ChaosHandler interface, control queries use the CH class.
+func (c *Cache) Chaos() bool { return c.control != nil }
+
+// allowControl returns true when control queries are allowed from ip.
+func (c *Cache) allowControl(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, n := range c.control {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseControl parses the name of a control query. It returns the operation, the name and type it is
+// for; the type is dns.TypeNone when no type is given.
+func parseControl(qname string) (op, name string, qtype uint16, ok bool) {
+	labels := dns.SplitDomainName(strings.ToLower(qname))
+	labels = labels[:len(labels)-dns.CountLabel(controlZone)]
+	if len(labels) == 0 {
+		return "", "", 0, false
+	}
+
+	last := len(labels) - 1
+	if t, isType := dns.StringToType[strings.ToUpper(labels[last])]; isType && last > 0 && isOp(labels[last-1]) {
+		qtype = t
+		last--
+	}
+	if !isOp(labels[last]) {
+		return "", "", 0, false
+	}
+	return labels[last], dns.Fqdn(strings.Join(labels[:last], ".")), qtype, true
+}
+
+func isOp(s string) bool { return s == opDump || s == opPurge || s == opPurgeTree }
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseControl(t *testing.T) {
+	tests := []struct {
+		qname string
+		ok    bool
+		op    string
+		name  string
+		qtype uint16
+	}{
+		{"dump.cache.coredns.", true, opDump, ".", dns.TypeNone},
+		{"example.org.dump.cache.coredns.", true, opDump, "example.org.", dns.TypeNone},
+		{"example.org.purge.aaaa.cache.coredns.", true, opPurge, "example.org.", dns.TypeAAAA},
+		{"Example.ORG.purgetree.cache.coredns.", true, opPurgeTree, "example.org.", dns.TypeNone},
+		{"a.purge.example.org.purge.a.cache.coredns.", true, opPurge, "a.purge.example.org.", dns.TypeA},
+		{"a.cache.coredns.", false, "", "", 0},
+		{"cache.coredns.", false, "", "", 0},
+		{"example.org.cache.coredns.", false, "", "", 0},
+	}
+	for i, tc := range tests {
+		op, name, qtype, ok := parseControl(tc.qname)
+		if ok != tc.ok {
+			t.Errorf("Test %d: expected ok %t, got %t", i, tc.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if op != tc.op || name != tc.name || qtype != tc.qtype {
+			t.Errorf("Test %d: expected %s %s %d, got %s %s %d", i, tc.op, tc.name, tc.qtype, op, name, qtype)
+		}
+	}
+}
+
+func TestServeControl(t *testing.T) {
+	c := New()
+	c.Next = ttlBackend(60)
+	_, n, _ := net.ParseCIDR("10.240.0.0/24")
+	c.control = []*net.IPNet{n}
+
+	for _, name := range []string{"example.org.", "www.example.org.", "example.net."} {
+		req := new(dns.Msg)
+		req.SetQuestion(name, dns.TypeA)
+		c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+	}
+
+	control := func(w dns.ResponseWriter, qname string, qtype uint16) (*dnstest.Recorder, int) {
+		req := new(dns.Msg)
+		req.SetQuestion(qname, qtype)
+		req.Question[0].Qclass = dns.ClassCHAOS
+		rec := dnstest.NewRecorder(w)
+		rcode, _ := c.ServeDNS(context.TODO(), rec, req)
+		return rec, rcode
+	}
+
+	rec, _ := control(&test.ResponseWriter{}, "example.org.dump.cache.coredns.", dns.TypeTXT)
+	if rec.Msg == nil || len(rec.Msg.Answer) != 2 {
+		t.Fatalf("Expected 2 answers for the dump, got %v", rec.Msg)
+	}
+	if txt := rec.Msg.Answer[0].(*dns.TXT).Txt[0]; txt != "example.org. A ttl=60 hits=0 success NOERROR" && txt != "www.example.org. A ttl=60 hits=0 success NOERROR" {
+		t.Errorf("Unexpected dump %q", txt)
+	}
+
+	if _, rcode := control(&test.ResponseWriter6{}, "purgetree.cache.coredns.", dns.TypeTXT); rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED for a control query from outside the control networks, got %s", dns.RcodeToString[rcode])
+	}
+	if _, rcode := control(&test.ResponseWriter{}, "purgetree.cache.coredns.", dns.TypeA); rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED for a control query that isn't TXT, got %s", dns.RcodeToString[rcode])
+	}
+	if _, rcode := control(&test.ResponseWriter{}, "flush.cache.coredns.", dns.TypeTXT); rcode != dns.RcodeNameError {
+		t.Errorf("Expected NXDOMAIN for an invalid control query, got %s", dns.RcodeToString[rcode])
+	}
+
+	rec, _ = control(&test.ResponseWriter{}, "example.org.purgetree.a.cache.coredns.", dns.TypeTXT)
+	if rec.Msg == nil || len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.TXT).Txt[0] != "purged 2" {
+		t.Fatalf("Expected purged 2, got %v", rec.Msg)
+	}
+	if entries := c.Entries(); len(entries) != 1 || entries[0].Name != "example.net." {
+		t.Errorf("Expected only example.net. to be left, got %v", entries)
+	}
+}
+
+func TestServeControlTruncate(t *testing.T) {
+	c := New()
+	c.Next = ttlBackend(60)
+	_, n, _ := net.ParseCIDR("10.240.0.0/24")
+	c.control = []*net.IPNet{n}
+
+	for i := 0; i < 100; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion(fmt.Sprintf("host%d.example.org.", i), dns.TypeA)
+		c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+	}
+
+	for _, tcp := range []bool{false, true} {
+		req := new(dns.Msg)
+		req.SetQuestion("dump.cache.coredns.", dns.TypeTXT)
+		req.Question[0].Qclass = dns.ClassCHAOS
+		rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tcp})
+		c.ServeDNS(context.TODO(), rec, req)
+
+		if rec.Msg == nil {
+			t.Fatalf("Expected a reply over TCP %t", tcp)
+		}
+		if rec.Msg.Truncated != !tcp {
+			t.Errorf("Expected truncated %t over TCP %t, got %t", !tcp, tcp, rec.Msg.Truncated)
+		}
+		if !tcp && rec.Msg.Len() > dns.MinMsgSize {
+			t.Errorf("Expected the reply to fit in %d bytes, got %d", dns.MinMsgSize, rec.Msg.Len())
+		}
+		if tcp && len(rec.Msg.Answer) != 100 {
+			t.Errorf("Expected 100 answers over TCP, got %d", len(rec.Msg.Answer))
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
@@ -17,6 +18,10 @@ import (
 func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 	state := request.Request{W: w, Req: r}
 
+	if c.control != nil && state.QClass() == dns.ClassCHAOS && dns.IsSubDomain(controlZone, state.Name()) {
+		return c.serveControl(w, state)
+	}
+
 	zone := plugin.Zones(c.Zones).Matches(state.Name())
 	if zone == "" {
 		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
@@ -48,6 +53,7 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 					// into the new item that was stored in the cache.
 					if i1 := c.exists(state); i1 != nil {
 						i1.Freq.Reset(now, i.Freq.Hits())
+						atomic.StoreUint32(&i1.hits, atomic.LoadUint32(&i.hits))
 					}
 				}(cw)
 			}
@@ -55,6 +61,12 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		return dns.RcodeSuccess, nil
 	}
 
+	if c.staleUpTo > 0 {
+		if i := c.getStale(now, state); i != nil {
+			return c.serveStale(ctx, w, r, state, server, i, now)
+		}
+	}
+
 	crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server}
 	return plugin.NextOrFailure(c.Name(), c.Next, ctx, crr, r)
 }
@@ -67,11 +79,13 @@ func (c *Cache) get(now time.Time, state request.Request, server string) (*item,
 
 	if i, ok := c.ncache.Get(k); ok && i.(*item).ttl(now) > 0 {
 		cacheHits.WithLabelValues(server, Denial).Inc()
+		atomic.AddUint32(&i.(*item).hits, 1)
 		return i.(*item), true
 	}
 
 	if i, ok := c.pcache.Get(k); ok && i.(*item).ttl(now) > 0 {
 		cacheHits.WithLabelValues(server, Success).Inc()
+		atomic.AddUint32(&i.(*item).hits, 1)
 		return i.(*item), true
 	}
 	cacheMisses.WithLabelValues(server).Inc()
@@ -124,4 +138,11 @@ var (
 		Name:      "drops_total",
 		Help:      "The number responses that are not cached, because the reply is malformed.",
 	}, []string{"server"})
+
+	cacheServedStale = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "cache",
+		Name:      "served_stale_total",
+		Help:      "The number of requests served from stale cache entries.",
+	}, []string{"server"})
 )
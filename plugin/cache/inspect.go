@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+// Entry describes an item in the cache, see Entries.
+type Entry struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Do    bool   `json:"do"`
+	Class string `json:"class"` // Success or Denial.
+	Rcode string `json:"rcode"`
+	TTL   int    `json:"ttl"`  // Remaining TTL, negative when expired.
+	Hits  uint32 `json:"hits"` // Number of times the item was served from the cache.
+}
+
+// Matches returns true if e is for name and qtype. When qtype is dns.TypeNone e may be of any type. With
+// subtree, e may also be for a name below name.
+func (e Entry) Matches(name string, qtype uint16, subtree bool) bool {
+	return matches(e.Name, dns.StringToType[e.Type], name, qtype, subtree)
+}
+
+func matches(ename string, etype uint16, name string, qtype uint16, subtree bool) bool {
+	if qtype != dns.TypeNone && etype != qtype {
+		return false
+	}
+	if subtree {
+		return dns.IsSubDomain(name, ename)
+	}
+	return strings.EqualFold(ename, name)
+}
+
+// Entries returns the items in the cache. Items are only removed when evicted or replaced, so the list
+// includes expired items.
+func (c *Cache) Entries() []Entry {
+	now := c.now()
+	entries := []Entry{}
+	walk := func(ca *cache.Cache, class string) {
+		ca.Walk(func(items map[uint64]interface{}, key uint64) bool {
+			i := items[key].(*item)
+			entries = append(entries, Entry{
+				Name:  i.Name,
+				Type:  dns.Type(i.Type).String(),
+				Do:    i.Do,
+				Class: class,
+				Rcode: dns.RcodeToString[i.Rcode],
+				TTL:   i.ttl(now),
+				Hits:  atomic.LoadUint32(&i.hits),
+			})
+			return true
+		})
+	}
+	walk(c.pcache, Success)
+	walk(c.ncache, Denial)
+	return entries
+}
+
+// Purge removes the items for name from the cache. When qtype is dns.TypeNone items of any type are
+// removed, otherwise only those of qtype. With subtree the items for all names below name are removed
+// too. It returns the number of items removed.
+func (c *Cache) Purge(name string, qtype uint16, subtree bool) int {
+	name = dns.Fqdn(name)
+	n := 0
+	purge := func(ca *cache.Cache) {
+		ca.Walk(func(items map[uint64]interface{}, key uint64) bool {
+			i := items[key].(*item)
+			if !matches(i.Name, i.Type, name, qtype, subtree) {
+				return true
+			}
+			delete(items, key)
+			n++
+			return true
+		})
+	}
+	purge(c.pcache)
+	purge(c.ncache)
+	return n
+}
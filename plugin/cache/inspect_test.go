@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestEntriesAndPurge(t *testing.T) {
+	c := New()
+	c.Next = ttlBackend(60)
+
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+	}{
+		{"example.org.", dns.TypeA},
+		{"example.org.", dns.TypeAAAA},
+		{"www.example.org.", dns.TypeA},
+		{"www.example.org.", dns.TypeA},
+		{"a.www.example.org.", dns.TypeA},
+	} {
+		req := new(dns.Msg)
+		req.SetQuestion(q.name, q.qtype)
+		c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+	}
+
+	entries := c.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.Class != Success || e.Rcode != "NOERROR" || e.TTL != 60 {
+			t.Errorf("Unexpected entry %+v", e)
+		}
+		hits := uint32(0)
+		if e.Name == "www.example.org." {
+			hits = 1
+		}
+		if e.Hits != hits {
+			t.Errorf("Expected %d hits for %s, got %d", hits, e.Name, e.Hits)
+		}
+		if e.Type == "A" && !e.Matches("example.org.", dns.TypeA, true) {
+			t.Errorf("Expected %s to match example.org. subtree", e.Name)
+		}
+	}
+
+	if n := c.Purge("Example.org", dns.TypeAAAA, false); n != 1 {
+		t.Errorf("Expected 1 item to be purged, got %d", n)
+	}
+	if n := c.Purge("example.org.", dns.TypeNone, false); n != 1 {
+		t.Errorf("Expected 1 item to be purged, got %d", n)
+	}
+	if n := c.Purge("www.example.org.", dns.TypeAAAA, true); n != 0 {
+		t.Errorf("Expected 0 items to be purged, got %d", n)
+	}
+	if n := c.Purge("a.www.example.org.", dns.TypeA, true); n != 1 {
+		t.Errorf("Expected 1 item to be purged, got %d", n)
+	}
+	entries = c.Entries()
+	if len(entries) != 1 || entries[0].Name != "www.example.org." || entries[0].Type != "A" {
+		t.Errorf("Expected only www.example.org. A to be left, got %v", entries)
+	}
+}
@@ -16,8 +16,14 @@ type item struct {
 	Ns                 []dns.RR
 	Extra              []dns.RR
 
+	// The question this item answers, the key of the item is derived from it.
+	Name string
+	Type uint16
+	Do   bool
+
 	origTTL uint32
 	stored  time.Time
+	hits    uint32 // Number of times this item was served, updated atomically.
 
 	*freq.Freq
 }
@@ -42,6 +48,14 @@ func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 	}
 	i.Extra = i.Extra[:j]
 
+	if len(m.Question) > 0 {
+		i.Name = m.Question[0].Name
+		i.Type = m.Question[0].Qtype
+	}
+	if opt := m.IsEdns0(); opt != nil {
+		i.Do = opt.Do()
+	}
+
 	i.origTTL = uint32(d.Seconds())
 	i.stored = now.UTC()
 
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+// record is an item as it is written to the snapshot, one JSON object per line.
+type record struct {
+	Class   string    `json:"class"` // Success or Denial.
+	Stored  time.Time `json:"stored"`
+	OrigTTL uint32    `json:"orig_ttl"`
+	Msg     []byte    `json:"msg"` // The item as a response in wire format, with the original TTLs.
+}
+
+const defaultPersistInterval = 5 * time.Minute
+
+var errNoQuestion = errors.New("record without a question")
+
+// newRecord returns the record for i.
+func newRecord(i *item, class string) (record, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(i.Name, i.Type)
+	m.Response = true
+	m.Rcode = i.Rcode
+	m.Authoritative = i.Authoritative
+	m.AuthenticatedData = i.AuthenticatedData
+	m.RecursionAvailable = i.RecursionAvailable
+	m.Answer = i.Answer
+	m.Ns = i.Ns
+	m.Extra = append([]dns.RR{}, i.Extra...) // SetEdns0 appends to it.
+	if i.Do {
+		m.SetEdns0(4096, true)
+	}
+
+	buf, err := m.Pack()
+	if err != nil {
+		return record{}, err
+	}
+	return record{Class: class, Stored: i.stored, OrigTTL: i.origTTL, Msg: buf}, nil
+}
+
+// item returns the item for r, newItem recreates it from the message.
+func (r record) item() (*item, error) {
+	m := new(dns.Msg)
+	if err := m.Unpack(r.Msg); err != nil {
+		return nil, err
+	}
+	if len(m.Question) == 0 {
+		return nil, errNoQuestion
+	}
+	return newItem(m, r.Stored, time.Duration(r.OrigTTL)*time.Second), nil
+}
+
+// expired returns true if i can't be served anymore, not even when stale.
+func (c *Cache) expired(i *item, now time.Time) bool {
+	return i.ttl(now) <= -int(c.staleUpTo.Seconds())
+}
+
+// save writes the items in the cache to the snapshot file. The file is replaced atomically, the temporary
+// file is a hidden file in the same directory. Expired items are left out.
+func (c *Cache) save() error {
+	now := c.now()
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+
+	var err error
+	walk := func(ca *cache.Cache, class string) {
+		ca.Walk(func(items map[uint64]interface{}, key uint64) bool {
+			i := items[key].(*item)
+			if c.expired(i, now) {
+				return true
+			}
+			var r record
+			if r, err = newRecord(i, class); err != nil {
+				return false
+			}
+			err = enc.Encode(r)
+			return err == nil
+		})
+	}
+	walk(c.pcache, Success)
+	if err != nil {
+		return err
+	}
+	walk(c.ncache, Denial)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(c.persist), "."+filepath.Base(c.persist))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.persist)
+}
+
+// load adds the items in the snapshot file to the cache, expired items are discarded. A missing file is not
+// an error. It returns the number of items added.
+func (c *Cache) load() (int, error) {
+	f, err := os.Open(c.persist)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	now := c.now()
+	n := 0
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		r := record{}
+		if err := dec.Decode(&r); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		i, err := r.item()
+		if err != nil {
+			return n, err
+		}
+		if c.expired(i, now) {
+			continue
+		}
+
+		k := hash(i.Name, i.Type, i.Do)
+		switch r.Class {
+		case Success:
+			c.pcache.Add(k, i)
+		case Denial:
+			c.ncache.Add(k, i)
+		default:
+			continue
+		}
+		n++
+	}
+}
+
+// persistLoop saves the cache every c.persistInterval until stop is closed.
+func (c *Cache) persistLoop(stop chan struct{}) {
+	tick := time.NewTicker(c.persistInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			if err := c.save(); err != nil {
+				log.Warningf("Failed to save the cache to %s: %s", c.persist, err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	c := New()
+	c.persist = filepath.Join(dir, "cache.json")
+	c.now = func() time.Time { return now }
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Response, m.RecursionAvailable = true, true
+		switch r.Question[0].Name {
+		case "example.org.":
+			m.Answer = []dns.RR{test.A("example.org. 60 IN A 127.0.0.53")}
+		case "short.example.org.":
+			m.Answer = []dns.RR{test.A("short.example.org. 10 IN A 127.0.0.53")}
+		default:
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{test.SOA("example.org. 30 IN SOA ns.example.org. hostmaster.example.org. 1 3600 900 604800 30")}
+		}
+		if opt := r.IsEdns0(); opt != nil {
+			m.SetEdns0(4096, opt.Do())
+		}
+		w.WriteMsg(m)
+		return m.Rcode, nil
+	})
+
+	for _, name := range []string{"example.org.", "short.example.org.", "nx.example.org."} {
+		req := new(dns.Msg)
+		req.SetQuestion(name, dns.TypeA)
+		req.SetEdns0(4096, name == "example.org.")
+		c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+	}
+	if err := c.save(); err != nil {
+		t.Fatalf("Expected no error saving the cache, got %s", err)
+	}
+
+	// 20s later short.example.org. has expired.
+	c1 := New()
+	c1.persist = c.persist
+	c1.now = func() time.Time { return now.Add(20 * time.Second) }
+	n, err := c1.load()
+	if err != nil {
+		t.Fatalf("Expected no error loading the cache, got %s", err)
+	}
+	if n != 2 {
+		t.Fatalf("Expected 2 items to be loaded, got %d", n)
+	}
+	for _, e := range c1.Entries() {
+		switch e.Name {
+		case "example.org.":
+			if e.Class != Success || !e.Do || e.TTL != 40 {
+				t.Errorf("Unexpected entry %+v", e)
+			}
+		case "nx.example.org.":
+			if e.Class != Denial || e.Do || e.Rcode != "NXDOMAIN" || e.TTL != 10 {
+				t.Errorf("Unexpected entry %+v", e)
+			}
+		default:
+			t.Errorf("Unexpected entry %+v", e)
+		}
+	}
+
+	// The loaded items are served, with the remaining TTL.
+	c1.Next = servFailBackend()
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	req.SetEdns0(4096, true)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c1.ServeDNS(context.TODO(), rec, req)
+	if rec.Msg == nil || len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].Header().Ttl != 40 {
+		t.Errorf("Expected example.org. from the cache with a TTL of 40, got %v", rec.Msg)
+	}
+
+	// With serve_stale expired items are kept.
+	c2 := New()
+	c2.persist = c.persist
+	c2.staleUpTo = time.Hour
+	c2.now = func() time.Time { return now.Add(20 * time.Second) }
+	if n, _ := c2.load(); n != 3 {
+		t.Errorf("Expected 3 items to be loaded, got %d", n)
+	}
+}
+
+func TestPersistMissingFile(t *testing.T) {
+	c := New()
+	c.persist = filepath.Join(os.TempDir(), "coredns-cache-does-not-exist")
+	if n, err := c.load(); n != 0 || err != nil {
+		t.Errorf("Expected nothing to be loaded without an error, got %d, %v", n, err)
+	}
+}
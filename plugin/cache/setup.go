@@ -2,6 +2,8 @@ package cache
 
 import (
 	"fmt"
+	"net"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -36,10 +38,36 @@ func setup(c *caddy.Controller) error {
 	c.OnStartup(func() error {
 		metrics.MustRegister(c,
 			cacheSize, cacheHits, cacheMisses,
-			cachePrefetches, cacheDrops)
+			cachePrefetches, cacheDrops, cacheServedStale)
 		return nil
 	})
 
+	if ca.persist != "" {
+		stop := make(chan struct{})
+		c.OnStartup(func() error {
+			n, err := ca.load()
+			if err != nil {
+				log.Warningf("Failed to load the cache from %s: %s", ca.persist, err)
+			}
+			log.Infof("Loaded %d items from %s", n, ca.persist)
+			go ca.persistLoop(stop)
+			return nil
+		})
+		save := func() error {
+			if err := ca.save(); err != nil {
+				log.Warningf("Failed to save the cache to %s: %s", ca.persist, err)
+			}
+			return nil
+		}
+		// Save before a reload, so the new instance starts with the items of this one.
+		c.OnRestart(save)
+		c.OnShutdown(func() error {
+			close(stop)
+			return nil
+		})
+		c.OnFinalShutdown(save)
+	}
+
 	return nil
 }
 
@@ -181,6 +209,57 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 					ca.percentage = num
 				}
 
+			case "serve_stale":
+				args := c.RemainingArgs()
+				if len(args) > 1 {
+					return nil, c.ArgErr()
+				}
+				ca.staleUpTo = defaultStaleUpTo
+				if len(args) > 0 {
+					d, err := time.ParseDuration(args[0])
+					if err != nil {
+						return nil, err
+					}
+					if d <= 0 {
+						return nil, fmt.Errorf("invalid value for serve_stale duration: %s", args[0])
+					}
+					ca.staleUpTo = d
+				}
+
+			case "persist":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				ca.persist = args[0]
+				if !filepath.IsAbs(ca.persist) && dnsserver.GetConfig(c).Root != "" {
+					ca.persist = filepath.Join(dnsserver.GetConfig(c).Root, ca.persist)
+				}
+				if len(args) > 1 {
+					d, err := time.ParseDuration(args[1])
+					if err != nil {
+						return nil, err
+					}
+					if d <= 0 {
+						return nil, fmt.Errorf("invalid value for persist interval: %s", args[1])
+					}
+					ca.persistInterval = d
+				}
+
+			case "control":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					args = []string{"127.0.0.1/32", "::1/128"}
+				}
+				ca.control = nil
+				for _, a := range args {
+					n, err := parseCIDR(a)
+					if err != nil {
+						return nil, c.Errf("invalid network for control: %s", a)
+					}
+					ca.control = append(ca.control, n)
+				}
+
 			default:
 				return nil, c.ArgErr()
 			}
@@ -197,3 +276,16 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 
 	return ca, nil
 }
+
+// parseCIDR parses a network in CIDR notation, or a single IP address.
+func parseCIDR(s string) (*net.IPNet, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		if ip.To4() != nil {
+			s += "/32"
+		} else {
+			s += "/128"
+		}
+	}
+	_, n, err := net.ParseCIDR(s)
+	return n, err
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -113,3 +114,110 @@ func TestSetup(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupServeStale(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		staleUpTo time.Duration
+	}{
+		{"serve_stale", false, 1 * time.Hour},
+		{"serve_stale 20m", false, 20 * time.Minute},
+		{"serve_stale 1h20m", false, 80 * time.Minute},
+		{"serve_stale 0m", true, 0},
+		{"serve_stale 0", true, 0},
+		{"serve_stale -20m", true, 0},
+		{"serve_stale 1h 20m", true, 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+		if ca.staleUpTo != test.staleUpTo {
+			t.Errorf("Test %v: Expected stale %v but found: %v", i, test.staleUpTo, ca.staleUpTo)
+		}
+	}
+}
+
+func TestSetupControl(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		control   []string
+	}{
+		{"", false, nil},
+		{"control", false, []string{"127.0.0.1/32", "::1/128"}},
+		{"control 10.0.0.0/8 192.168.1.1", false, []string{"10.0.0.0/8", "192.168.1.1/32"}},
+		{"control 2001:db8::/32", false, []string{"2001:db8::/32"}},
+		{"control 10.0.0.0/33", true, nil},
+		{"control example.org", true, nil},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+		if len(ca.control) != len(test.control) {
+			t.Errorf("Test %v: Expected %d control networks but found: %v", i, len(test.control), ca.control)
+			continue
+		}
+		for j, n := range ca.control {
+			if n.String() != test.control[j] {
+				t.Errorf("Test %v: Expected control network %s but found: %s", i, test.control[j], n)
+			}
+		}
+	}
+}
+
+func TestSetupPersist(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		persist   string
+		interval  time.Duration
+	}{
+		{"", false, "", defaultPersistInterval},
+		{"persist /var/lib/coredns/cache.json", false, "/var/lib/coredns/cache.json", defaultPersistInterval},
+		{"persist /var/lib/coredns/cache.json 30s", false, "/var/lib/coredns/cache.json", 30 * time.Second},
+		{"persist", true, "", 0},
+		{"persist /var/lib/coredns/cache.json 0s", true, "", 0},
+		{"persist /var/lib/coredns/cache.json 1m 1m", true, "", 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+		if ca.persist != test.persist {
+			t.Errorf("Test %v: Expected persist %q but found: %q", i, test.persist, ca.persist)
+		}
+		if ca.persistInterval != test.interval {
+			t.Errorf("Test %v: Expected persist interval %v but found: %v", i, test.interval, ca.persistInterval)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// getStale returns the expired item for state, if it expired less than staleUpTo ago.
+func (c *Cache) getStale(now time.Time, state request.Request) *item {
+	k := hash(state.Name(), state.QType(), state.Do())
+	min := -int(c.staleUpTo.Seconds())
+
+	if i, ok := c.ncache.Get(k); ok && i.(*item).ttl(now) > min {
+		return i.(*item)
+	}
+	if i, ok := c.pcache.Get(k); ok && i.(*item).ttl(now) > min {
+		return i.(*item)
+	}
+	return nil
+}
+
+// serveStale asks the next plugin for a fresh reply. If that fails with SERVFAIL, or takes longer than
+// staleTimeout, the stale item i is returned to the client instead (RFC 8767). In the latter case the
+// next plugin keeps running in the background and its reply is cached when it arrives.
+func (c *Cache) serveStale(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, state request.Request, server string, i *item, now time.Time) (int, error) {
+	sw := &staleResponseWriter{ResponseWriter: newPrefetchResponseWriter(server, state, c)}
+
+	result := make(chan staleResult)
+	abandon := make(chan struct{})
+	go func() {
+		rcode, err := plugin.NextOrFailure(c.Name(), c.Next, ctx, sw, r)
+		select {
+		case result <- staleResult{rcode: rcode, err: err}:
+		case <-abandon:
+			// A stale reply was sent, cache the fresh one for the next client.
+			if sw.msg != nil {
+				sw.ResponseWriter.WriteMsg(sw.msg)
+			}
+		}
+	}()
+
+	select {
+	case res := <-result:
+		if res.err == nil && plugin.ClientWrite(res.rcode) && sw.msg != nil && sw.msg.Rcode != dns.RcodeServerFailure {
+			crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server}
+			crr.WriteMsg(sw.msg)
+			return res.rcode, nil
+		}
+	case <-time.After(c.staleTimeout):
+		close(abandon)
+	}
+
+	cacheServedStale.WithLabelValues(server).Inc()
+
+	resp := i.toMsg(r, now)
+	for _, rr := range resp.Answer {
+		rr.Header().Ttl = staleTTL
+	}
+	for _, rr := range resp.Ns {
+		rr.Header().Ttl = staleTTL
+	}
+	for _, rr := range resp.Extra {
+		rr.Header().Ttl = staleTTL
+	}
+	w.WriteMsg(resp)
+	return dns.RcodeSuccess, nil
+}
+
+type staleResult struct {
+	rcode int
+	err   error
+}
+
+// staleResponseWriter holds on to the reply of the next plugin, so we can decide to serve a stale item instead.
+// The embedded ResponseWriter is a prefetch one, which caches the reply without writing it to the client.
+type staleResponseWriter struct {
+	*ResponseWriter
+	msg *dns.Msg
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *staleResponseWriter) WriteMsg(res *dns.Msg) error {
+	w.msg = res
+	return nil
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *staleResponseWriter) Write(buf []byte) (int, error) {
+	log.Warning("Caching called with Write: not serving stale")
+	return len(buf), nil
+}
+
+const (
+	defaultStaleUpTo    = 1 * time.Hour
+	defaultStaleTimeout = 1800 * time.Millisecond // RFC 8767, section 5.
+	staleTTL            = 30                      // RFC 8767, section 4.
+)
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestServeStale(t *testing.T) {
+	c := New()
+	c.staleUpTo = 1 * time.Hour
+	c.Next = ttlBackend(60)
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+
+	// Upstream is down and the item has expired, we should get the stale item.
+	c.Next = servFailBackend()
+	c.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if rec.Msg.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected NOERROR, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].Header().Ttl != staleTTL {
+		t.Errorf("Expected one stale answer with TTL %d, got %v", staleTTL, rec.Msg.Answer)
+	}
+
+	// Expired for longer than staleUpTo, we should get the SERVFAIL.
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	rcode, _ := c.ServeDNS(context.TODO(), rec, req)
+	if rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected SERVFAIL, got %s", dns.RcodeToString[rcode])
+	}
+
+	// Upstream is back, we should get the fresh answer.
+	c.Next = ttlBackend(120)
+	c.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].Header().Ttl != 120 {
+		t.Errorf("Expected one fresh answer with TTL 120, got %v", rec.Msg.Answer)
+	}
+}
+
+func TestServeStaleTimeout(t *testing.T) {
+	c := New()
+	c.staleUpTo = 1 * time.Hour
+	c.staleTimeout = 10 * time.Millisecond
+	c.Next = ttlBackend(60)
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+
+	// Upstream is slow, we should get the stale item, while the fresh one is cached in the background.
+	done := make(chan struct{})
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		defer close(done)
+		time.Sleep(50 * time.Millisecond)
+		return ttlBackend(120).ServeDNS(ctx, w, r)
+	})
+	later := time.Now().Add(10 * time.Minute)
+	c.now = func() time.Time { return later }
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].Header().Ttl != staleTTL {
+		t.Fatalf("Expected one stale answer with TTL %d, got %v", staleTTL, rec.Msg.Answer)
+	}
+
+	<-done
+	time.Sleep(10 * time.Millisecond) // Give the background refresh time to write to the cache.
+
+	c.Next = servFailBackend()
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].Header().Ttl != 120 {
+		t.Errorf("Expected one refreshed answer with TTL 120, got %v", rec.Msg.Answer)
+	}
+}
+
+func ttlBackend(ttl int) plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Response, m.RecursionAvailable = true, true
+
+		m.Answer = []dns.RR{test.A(r.Question[0].Name + fmt.Sprintf(" %d IN A 127.0.0.53", ttl))}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func servFailBackend() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		return dns.RcodeServerFailure, nil
+	})
+}
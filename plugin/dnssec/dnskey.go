@@ -1,11 +1,15 @@
 package dnssec
 
 import (
+	"bufio"
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"errors"
+	"io/ioutil"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/request"
@@ -19,24 +23,32 @@ type DNSKEY struct {
 	D   *dns.DS
 	s   crypto.Signer
 	tag uint16
+
+	// Key timing metadata as written by dnssec-keygen. The zero time means the event is not scheduled.
+	Publish  time.Time // When the key is added to the DNSKEY RRset.
+	Activate time.Time // When the key starts signing.
+	Inactive time.Time // When the key stops signing (retired).
+	Delete   time.Time // When the key is removed from the DNSKEY RRset.
 }
 
 // ParseKeyFile read a DNSSEC keyfile as generated by dnssec-keygen or other
 // utilities. It adds ".key" for the public key and ".private" for the private key.
+// The key timing metadata is read from the comments in the public key file.
 func ParseKeyFile(pubFile, privFile string) (*DNSKEY, error) {
-	f, e := os.Open(pubFile)
+	buf, e := ioutil.ReadFile(pubFile)
 	if e != nil {
 		return nil, e
 	}
-	k, e := dns.ReadRR(f, pubFile)
+	k, e := dns.ReadRR(bytes.NewReader(buf), pubFile)
 	if e != nil {
 		return nil, e
 	}
 
-	f, e = os.Open(privFile)
+	f, e := os.Open(privFile)
 	if e != nil {
 		return nil, e
 	}
+	defer f.Close()
 
 	dk, ok := k.(*dns.DNSKEY)
 	if !ok {
@@ -47,31 +59,138 @@ func ParseKeyFile(pubFile, privFile string) (*DNSKEY, error) {
 		return nil, e
 	}
 
+	key := &DNSKEY{K: dk, D: dk.ToDS(dns.SHA256)}
+	if e := key.parseTiming(buf); e != nil {
+		return nil, e
+	}
+
 	if s, ok := p.(*rsa.PrivateKey); ok {
-		return &DNSKEY{K: dk, D: dk.ToDS(dns.SHA256), s: s, tag: dk.KeyTag()}, nil
+		key.s, key.tag = s, dk.KeyTag()
+		return key, nil
 	}
 	if s, ok := p.(*ecdsa.PrivateKey); ok {
-		return &DNSKEY{K: dk, D: dk.ToDS(dns.SHA256), s: s, tag: dk.KeyTag()}, nil
+		key.s, key.tag = s, dk.KeyTag()
+		return key, nil
+	}
+	return key, errors.New("no private key found")
+}
+
+// parseTiming parses the key timing metadata from the comments in a public key file, i.e.:
+//
+//	; Publish: 20170901060531 (Fri Sep  1 08:05:31 2017)
+//	; Activate: 20170901060531 (Fri Sep  1 08:05:31 2017)
+func (k *DNSKEY) parseTiming(buf []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, ";") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, ";"))
+		if len(fields) < 2 {
+			continue
+		}
+
+		var t *time.Time
+		switch fields[0] {
+		case "Publish:":
+			t = &k.Publish
+		case "Activate:":
+			t = &k.Activate
+		case "Inactive:":
+			t = &k.Inactive
+		case "Delete:":
+			t = &k.Delete
+		default:
+			continue
+		}
+		tm, err := time.Parse(timingFormat, fields[1])
+		if err != nil {
+			return err
+		}
+		*t = tm
+	}
+	return scanner.Err()
+}
+
+// KSK returns true if k is a key signing key, i.e. it has the SEP flag set.
+func (k *DNSKEY) KSK() bool { return k.K.Flags&dns.SEP == dns.SEP }
+
+// published returns true if k should be in the DNSKEY RRset at time t.
+func (k *DNSKEY) published(t time.Time) bool {
+	if !k.Publish.IsZero() && t.Before(k.Publish) {
+		return false
+	}
+	return k.Delete.IsZero() || t.Before(k.Delete)
+}
+
+// active returns true if k should be used for signing at time t.
+func (k *DNSKEY) active(t time.Time) bool {
+	if !k.Activate.IsZero() && t.Before(k.Activate) {
+		return false
+	}
+	if !k.Inactive.IsZero() && !t.Before(k.Inactive) {
+		return false
+	}
+	return k.published(t)
+}
+
+// timingFormat is the format of the timestamps dnssec-keygen uses.
+const timingFormat = "20060102150405"
+
+// split returns true if the keys are split in KSKs and ZSKs, i.e. we have keys with and without the
+// SEP flag. When the keys aren't split, each key is used as a CSK (common signing key).
+func (d Dnssec) split() bool {
+	ksk, zsk := false, false
+	for _, k := range d.keys {
+		if k.KSK() {
+			ksk = true
+		} else {
+			zsk = true
+		}
+	}
+	return ksk && zsk
+}
+
+// signingKeys returns the keys that are active at time t and should sign an RRset with type qtype.
+// When the keys are split, the DNSKEY RRset is signed with the KSKs and all other RRsets with the ZSKs.
+func (d Dnssec) signingKeys(qtype uint16, t time.Time) []*DNSKEY {
+	split := d.split()
+	keys := make([]*DNSKEY, 0, len(d.keys))
+	for _, k := range d.keys {
+		if !k.active(t) {
+			continue
+		}
+		if split && k.KSK() != (qtype == dns.TypeDNSKEY) {
+			continue
+		}
+		keys = append(keys, k)
 	}
-	return &DNSKEY{K: dk, D: dk.ToDS(dns.SHA256), s: nil, tag: 0}, errors.New("no private key found")
+	return keys
 }
 
 // getDNSKEY returns the correct DNSKEY to the client. Signatures are added when do is true.
+// Only keys that are published are returned.
 func (d Dnssec) getDNSKEY(state request.Request, zone string, do bool, server string) *dns.Msg {
-	keys := make([]dns.RR, len(d.keys))
-	for i, k := range d.keys {
-		keys[i] = dns.Copy(k.K)
-		keys[i].Header().Name = zone
+	now := time.Now().UTC()
+	keys := make([]dns.RR, 0, len(d.keys))
+	for _, k := range d.keys {
+		if !k.published(now) {
+			continue
+		}
+		key := dns.Copy(k.K)
+		key.Header().Name = zone
+		keys = append(keys, key)
 	}
 	m := new(dns.Msg)
 	m.SetReply(state.Req)
 	m.Answer = keys
-	if !do {
+	if !do || len(keys) == 0 {
 		return m
 	}
 
-	incep, expir := incepExpir(time.Now().UTC())
-	if sigs, err := d.sign(keys, zone, 3600, incep, expir, server); err == nil {
+	incep, expir := incepExpir(now)
+	if sigs, err := d.sign(keys, zone, 3600, incep, expir, now, server); err == nil {
 		m.Answer = append(m.Answer, sigs...)
 	}
 	return m
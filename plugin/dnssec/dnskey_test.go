@@ -0,0 +1,190 @@
+package dnssec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseKeyFileTiming(t *testing.T) {
+	fPriv, rmPriv, _ := test.TempFile(".", privKey1)
+	fPub, rmPub, _ := test.TempFile(".", zskPubKeyTiming)
+	defer rmPriv()
+	defer rmPub()
+
+	k, err := ParseKeyFile(fPub, fPriv)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	if k.KSK() {
+		t.Errorf("Expected key to be a ZSK")
+	}
+
+	tests := []struct {
+		name     string
+		got      time.Time
+		expected time.Time
+	}{
+		{"Publish", k.Publish, time.Date(2017, 9, 1, 6, 5, 31, 0, time.UTC)},
+		{"Activate", k.Activate, time.Date(2017, 9, 8, 6, 5, 31, 0, time.UTC)},
+		{"Inactive", k.Inactive, time.Date(2018, 9, 1, 6, 5, 31, 0, time.UTC)},
+		{"Delete", k.Delete, time.Date(2018, 9, 8, 6, 5, 31, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		if !tc.got.Equal(tc.expected) {
+			t.Errorf("Expected %s to be %s, got %s", tc.name, tc.expected, tc.got)
+		}
+	}
+}
+
+func TestKeyState(t *testing.T) {
+	k := &DNSKEY{
+		Publish:  time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC),
+		Activate: time.Date(2017, 9, 8, 0, 0, 0, 0, time.UTC),
+		Inactive: time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC),
+		Delete:   time.Date(2018, 9, 8, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		now       time.Time
+		published bool
+		active    bool
+	}{
+		{time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC), false, false},
+		{time.Date(2017, 9, 2, 0, 0, 0, 0, time.UTC), true, false},
+		{time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), true, true},
+		{time.Date(2018, 9, 2, 0, 0, 0, 0, time.UTC), true, false},
+		{time.Date(2018, 9, 8, 0, 0, 0, 0, time.UTC), false, false},
+	}
+	for i, tc := range tests {
+		if x := k.published(tc.now); x != tc.published {
+			t.Errorf("Test %d, expected published to be %t, got %t", i, tc.published, x)
+		}
+		if x := k.active(tc.now); x != tc.active {
+			t.Errorf("Test %d, expected active to be %t, got %t", i, tc.active, x)
+		}
+	}
+
+	// No timing metadata at all: always published and active.
+	k = &DNSKEY{}
+	if !k.published(time.Now()) || !k.active(time.Now()) {
+		t.Errorf("Expected key without timing metadata to be published and active")
+	}
+}
+
+func TestSigningKSKZSK(t *testing.T) {
+	ksk, rm1, rm2 := newKey(t)
+	defer rm1()
+	defer rm2()
+	zsk, rm3, rm4 := newZSK(t, zskPubKey)
+	defer rm3()
+	defer rm4()
+
+	d := New([]string{"miek.nl."}, []*DNSKEY{ksk, zsk}, nil, cache.New(defaultCap))
+
+	m := d.Sign(request.Request{Req: testMsg(), Zone: "miek.nl."}, time.Now().UTC(), server)
+	if tags := keyTags(m.Answer); len(tags) != 1 || tags[0] != zsk.tag {
+		t.Errorf("Expected answer to be signed with ZSK %d only, got %v", zsk.tag, tags)
+	}
+
+	state := request.Request{Req: new(dns.Msg), Zone: "miek.nl."}
+	state.Req.SetQuestion("miek.nl.", dns.TypeDNSKEY)
+	m = d.getDNSKEY(state, "miek.nl.", true, server)
+	if tags := keyTags(m.Answer); len(tags) != 1 || tags[0] != ksk.tag {
+		t.Errorf("Expected DNSKEY RRset to be signed with KSK %d only, got %v", ksk.tag, tags)
+	}
+	if x := len(m.Answer) - 1; x != 2 {
+		t.Errorf("Expected 2 DNSKEYs, got %d", x)
+	}
+}
+
+func TestSigningRollover(t *testing.T) {
+	ksk, rm1, rm2 := newKey(t)
+	defer rm1()
+	defer rm2()
+	zsk, rm3, rm4 := newZSK(t, zskPubKey)
+	defer rm3()
+	defer rm4()
+
+	d := New([]string{"miek.nl."}, []*DNSKEY{ksk, zsk}, nil, cache.New(defaultCap))
+	m := d.Sign(request.Request{Req: testMsg(), Zone: "miek.nl."}, time.Now().UTC(), server)
+	if tags := keyTags(m.Answer); len(tags) != 1 || tags[0] != zsk.tag {
+		t.Fatalf("Expected answer to be signed with ZSK %d, got %v", zsk.tag, tags)
+	}
+
+	// Pre-publish the ZSK, it should not be used for signing, but the signatures in the cache are then stale.
+	zsk.Activate = time.Now().UTC().Add(time.Hour)
+	m = d.Sign(request.Request{Req: testMsg(), Zone: "miek.nl."}, time.Now().UTC(), server)
+	if tags := keyTags(m.Answer); len(tags) != 0 {
+		t.Errorf("Expected answer not to be signed, got %v", tags)
+	}
+
+	state := request.Request{Req: new(dns.Msg), Zone: "miek.nl."}
+	state.Req.SetQuestion("miek.nl.", dns.TypeDNSKEY)
+	m = d.getDNSKEY(state, "miek.nl.", false, server)
+	if len(m.Answer) != 2 {
+		t.Errorf("Expected pre-published ZSK to be in the DNSKEY RRset, got %d keys", len(m.Answer))
+	}
+
+	// Delete it, it's now gone from the DNSKEY RRset as well.
+	zsk.Delete = time.Now().UTC().Add(-time.Hour)
+	m = d.getDNSKEY(state, "miek.nl.", false, server)
+	if len(m.Answer) != 1 {
+		t.Errorf("Expected deleted ZSK not to be in the DNSKEY RRset, got %d keys", len(m.Answer))
+	}
+}
+
+func TestSigningAt(t *testing.T) {
+	ksk, rm1, rm2 := newKey(t)
+	defer rm1()
+	defer rm2()
+	zsk, rm3, rm4 := newZSK(t, zskPubKeyTiming)
+	defer rm3()
+	defer rm4()
+
+	d := New([]string{"miek.nl."}, []*DNSKEY{ksk, zsk}, nil, cache.New(defaultCap))
+
+	// The ZSK is inactive now, but was active at the time given to Sign.
+	then := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := d.Sign(request.Request{Req: testMsg(), Zone: "miek.nl."}, then, server)
+	if tags := keyTags(m.Answer); len(tags) != 1 || tags[0] != zsk.tag {
+		t.Errorf("Expected answer to be signed with ZSK %d, got %v", zsk.tag, tags)
+	}
+}
+
+func newZSK(t *testing.T, pub string) (*DNSKEY, func(), func()) {
+	fPriv, rmPriv, _ := test.TempFile(".", privKey1)
+	fPub, rmPub, _ := test.TempFile(".", pub)
+
+	key, err := ParseKeyFile(fPub, fPriv)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v\n", err)
+	}
+	return key, rmPriv, rmPub
+}
+
+func keyTags(rrs []dns.RR) []uint16 {
+	tags := []uint16{}
+	for _, r := range rrs {
+		if sig, ok := r.(*dns.RRSIG); ok {
+			tags = append(tags, sig.KeyTag)
+		}
+	}
+	return tags
+}
+
+const (
+	zskPubKey       = `example.org. IN DNSKEY 256 3 13 tVRWNSGpHZbCi7Pr7OmbADVUO3MxJ0Lb8Lk3o/HBHqCxf5K/J50lFqRa 98lkdAIiFOVRy8LyMvjwmxZKwB5MNw==`
+	zskPubKeyTiming = `; This is a zone-signing key, for example.org.
+; Created: 20170901060531 (Fri Sep  1 08:05:31 2017)
+; Publish: 20170901060531 (Fri Sep  1 08:05:31 2017)
+; Activate: 20170908060531 (Fri Sep  8 08:05:31 2017)
+; Inactive: 20180901060531 (Sat Sep  1 08:05:31 2018)
+; Delete: 20180908060531 (Sat Sep  8 08:05:31 2018)
+` + zskPubKey
+)
@@ -1,8 +1,9 @@
 // Package dnssec implements a plugin that signs responses on-the-fly using
-// NSEC black lies.
+// NSEC or NSEC3 black lies.
 package dnssec
 
 import (
+	"errors"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
@@ -18,10 +19,11 @@ import (
 type Dnssec struct {
 	Next plugin.Handler
 
-	zones    []string
-	keys     []*DNSKEY
-	inflight *singleflight.Group
-	cache    *cache.Cache
+	zones       []string
+	keys        []*DNSKEY
+	nsec3params *nsec3Params // When not nil, use NSEC3 for authenticated denial of existence.
+	inflight    *singleflight.Group
+	cache       *cache.Cache
 }
 
 // New returns a new Dnssec.
@@ -35,7 +37,7 @@ func New(zones []string, keys []*DNSKEY, next plugin.Handler, c *cache.Cache) Dn
 }
 
 // Sign signs the message in state. it takes care of negative or nodata responses. It
-// uses NSEC black lies for authenticated denial of existence. For delegations it
+// uses NSEC (or NSEC3) black lies for authenticated denial of existence. For delegations it
 // will insert DS records and sign those.
 // Signatures will be cached for a short while. By default we sign for 8 days,
 // starting 3 hours ago.
@@ -44,7 +46,7 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 
 	incep, expir := incepExpir(now)
 
-	mt, _ := response.Typify(req, time.Now().UTC()) // TODO(miek): need opt record here?
+	mt, _ := response.Typify(req, now) // TODO(miek): need opt record here?
 	if mt == response.Delegation {
 		return req
 	}
@@ -56,10 +58,14 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 
 		ttl := req.Ns[0].Header().Ttl
 
-		if sigs, err := d.sign(req.Ns, state.Zone, ttl, incep, expir, server); err == nil {
+		if sigs, err := d.sign(req.Ns, state.Zone, ttl, incep, expir, now, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
 		}
-		if sigs, err := d.nsec(state, mt, ttl, incep, expir, server); err == nil {
+		denial := d.nsec
+		if d.nsec3params != nil {
+			denial = d.nsec3
+		}
+		if sigs, err := denial(state, mt, ttl, incep, expir, now, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
 		}
 		if len(req.Ns) > 1 { // actually added nsec and sigs, reset the rcode
@@ -70,38 +76,43 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 
 	for _, r := range rrSets(req.Answer) {
 		ttl := r[0].Header().Ttl
-		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
+		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, now, server); err == nil {
 			req.Answer = append(req.Answer, sigs...)
 		}
 	}
 	for _, r := range rrSets(req.Ns) {
 		ttl := r[0].Header().Ttl
-		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
+		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, now, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
 		}
 	}
 	for _, r := range rrSets(req.Extra) {
 		ttl := r[0].Header().Ttl
-		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
+		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, now, server); err == nil {
 			req.Extra = append(req.Extra, sigs...)
 		}
 	}
 	return req
 }
 
-func (d Dnssec) sign(rrs []dns.RR, signerName string, ttl, incep, expir uint32, server string) ([]dns.RR, error) {
+func (d Dnssec) sign(rrs []dns.RR, signerName string, ttl, incep, expir uint32, now time.Time, server string) ([]dns.RR, error) {
+	keys := d.signingKeys(rrs[0].Header().Rrtype, now)
+	if len(keys) == 0 {
+		return nil, errNoActiveKeys
+	}
+
 	k := hash(rrs)
 	sgs, ok := d.get(k, server)
-	if ok {
+	if ok && signedBy(sgs, keys) {
 		return sgs, nil
 	}
 
 	sigs, err := d.inflight.Do(k, func() (interface{}, error) {
-		sigs := make([]dns.RR, len(d.keys))
+		sigs := make([]dns.RR, len(keys))
 		var e error
-		for i, k := range d.keys {
-			sig := k.newRRSIG(signerName, ttl, incep, expir)
-			e = sig.Sign(k.s, rrs)
+		for i, key := range keys {
+			sig := key.newRRSIG(signerName, ttl, incep, expir)
+			e = sig.Sign(key.s, rrs)
 			sigs[i] = sig
 		}
 		d.set(k, sigs)
@@ -110,6 +121,20 @@ func (d Dnssec) sign(rrs []dns.RR, signerName string, ttl, incep, expir uint32,
 	return sigs.([]dns.RR), err
 }
 
+// signedBy returns true if sigs are made by exactly the keys in keys. Signatures in the cache
+// made by keys that are since retired, or lacking keys that are since activated, need to be redone.
+func signedBy(sigs []dns.RR, keys []*DNSKEY) bool {
+	if len(sigs) != len(keys) {
+		return false
+	}
+	for i := range keys {
+		if sigs[i].(*dns.RRSIG).KeyTag != keys[i].tag {
+			return false
+		}
+	}
+	return true
+}
+
 func (d Dnssec) set(key uint64, sigs []dns.RR) { d.cache.Add(key, sigs) }
 
 func (d Dnssec) get(key uint64, server string) ([]dns.RR, bool) {
@@ -136,6 +161,8 @@ func incepExpir(now time.Time) (uint32, uint32) {
 	return incep, expir
 }
 
+var errNoActiveKeys = errors.New("no active keys")
+
 const (
 	eightDays  = 8 * 24 * time.Hour
 	sixDays    = 6 * 24 * time.Hour
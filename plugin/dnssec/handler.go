@@ -40,6 +40,19 @@ func (d Dnssec) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		}
 	}
 
+	// Same for NSEC3PARAM, when we use NSEC3.
+	if qtype == dns.TypeNSEC3PARAM && d.nsec3params != nil {
+		for _, z := range d.zones {
+			if qname == z {
+				resp := d.getNSEC3PARAM(state, z, do, server)
+				resp.Authoritative = true
+				state.SizeAndDo(resp)
+				w.WriteMsg(resp)
+				return dns.RcodeSuccess, nil
+			}
+		}
+	}
+
 	if do {
 		drr := &ResponseWriter{w, d, server}
 		return plugin.NextOrFailure(d.Name(), d.Next, ctx, drr, r)
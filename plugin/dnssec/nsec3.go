@@ -0,0 +1,157 @@
+package dnssec
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// nsec3Params holds the NSEC3 parameters used for authenticated denial of existence.
+type nsec3Params struct {
+	iterations uint16
+	salt       string // hex encoded, empty for no salt.
+	optOut     bool
+}
+
+// newNSEC3Params returns the NSEC3 parameters parsed from the iterations and salt strings. A salt of "-"
+// means no salt.
+func newNSEC3Params(iterations, salt string, optOut bool) (*nsec3Params, error) {
+	n := &nsec3Params{optOut: optOut}
+	if iterations != "" {
+		i, err := strconv.ParseUint(iterations, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NSEC3 iterations: %q", iterations)
+		}
+		if i > maxNSEC3Iterations {
+			return nil, fmt.Errorf("NSEC3 iterations must be between 0 and %d: %d", maxNSEC3Iterations, i)
+		}
+		n.iterations = uint16(i)
+	}
+	if salt != "" && salt != "-" {
+		if _, err := hex.DecodeString(salt); err != nil {
+			return nil, fmt.Errorf("invalid NSEC3 salt: %q", salt)
+		}
+		if len(salt)/2 > 255 {
+			return nil, fmt.Errorf("NSEC3 salt is too long: %q", salt)
+		}
+		n.salt = strings.ToUpper(salt)
+	}
+	return n, nil
+}
+
+// nsec3 returns an NSEC3 useful for NXDOMAIN and NODATA responses. Just as with NSEC black lies, we
+// pretend the queried name exists, but not with the type asked for. The NSEC3 record matches the hashed
+// owner name and its next hashed owner name is the hash plus one, so it covers nothing else.
+// For example, a request for the non-existing name a.example.com would cause the following NSEC3 record
+// to be generated:
+//
+//	<hash(a.example.com)>.example.com. 3600 IN NSEC3 1 0 0 - <hash(a.example.com)+1> ( RRSIG ... )
+//
+// This in turn makes every NXDOMAIN answer a NODATA one, don't forget to flip the header rcode to NOERROR.
+func (d Dnssec) nsec3(state request.Request, mt response.Type, ttl, incep, expir uint32, now time.Time, server string) ([]dns.RR, error) {
+	hash := dns.HashName(state.Name(), dns.SHA1, d.nsec3params.iterations, d.nsec3params.salt)
+	if hash == "" {
+		return nil, fmt.Errorf("failed to hash %q", state.Name())
+	}
+	next, err := nextHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	nsec3 := &dns.NSEC3{}
+	nsec3.Hdr = dns.RR_Header{Name: strings.ToLower(hash) + "." + state.Zone, Ttl: ttl, Class: dns.ClassINET, Rrtype: dns.TypeNSEC3}
+	nsec3.Hash = dns.SHA1
+	if d.nsec3params.optOut {
+		nsec3.Flags = 1
+	}
+	nsec3.Iterations = d.nsec3params.iterations
+	nsec3.Salt = d.nsec3params.salt
+	nsec3.SaltLength = uint8(len(d.nsec3params.salt) / 2)
+	nsec3.HashLength = uint8(base32.HexEncoding.DecodedLen(len(next)))
+	nsec3.NextDomain = next
+	if state.Name() == state.Zone {
+		nsec3.TypeBitMap = filter(state.QType(), apexBitmapNSEC3[:], mt)
+	} else {
+		nsec3.TypeBitMap = filter(state.QType(), zoneBitmapNSEC3[:], mt)
+	}
+
+	sigs, err := d.sign([]dns.RR{nsec3}, state.Zone, ttl, incep, expir, now, server)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(sigs, nsec3), nil
+}
+
+// nextHash returns the base32hex encoded hash plus one, wrapping around at the end of the hash space.
+func nextHash(hash string) (string, error) {
+	buf, err := base32.HexEncoding.DecodeString(hash)
+	if err != nil {
+		return "", err
+	}
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i]++
+		if buf[i] != 0 {
+			break
+		}
+	}
+	return base32.HexEncoding.EncodeToString(buf), nil
+}
+
+// getNSEC3PARAM returns the NSEC3PARAM record for the apex of zone. Signatures are added when do is true.
+func (d Dnssec) getNSEC3PARAM(state request.Request, zone string, do bool, server string) *dns.Msg {
+	param := &dns.NSEC3PARAM{
+		Hdr:        dns.RR_Header{Name: zone, Rrtype: dns.TypeNSEC3PARAM, Class: dns.ClassINET, Ttl: 3600},
+		Hash:       dns.SHA1,
+		Flags:      0, // Opt-out is never set in the NSEC3PARAM, RFC 5155 section 4.1.2.
+		Iterations: d.nsec3params.iterations,
+		SaltLength: uint8(len(d.nsec3params.salt) / 2),
+		Salt:       d.nsec3params.salt,
+	}
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Answer = []dns.RR{param}
+	if !do {
+		return m
+	}
+
+	now := time.Now().UTC()
+	incep, expir := incepExpir(now)
+	if sigs, err := d.sign(m.Answer, zone, 3600, incep, expir, now, server); err == nil {
+		m.Answer = append(m.Answer, sigs...)
+	}
+	return m
+}
+
+// The NSEC3 bit maps we return, these are the NSEC bitmaps without NSEC. The apex one adds NSEC3PARAM.
+var (
+	zoneBitmapNSEC3 = [...]uint16{dns.TypeA, dns.TypeHINFO, dns.TypeTXT, dns.TypeAAAA, dns.TypeLOC, dns.TypeSRV, dns.TypeCERT, dns.TypeSSHFP, dns.TypeRRSIG, dns.TypeTLSA, dns.TypeHIP, dns.TypeOPENPGPKEY, dns.TypeSPF}
+	apexBitmapNSEC3 = [...]uint16{dns.TypeA, dns.TypeNS, dns.TypeSOA, dns.TypeHINFO, dns.TypeMX, dns.TypeTXT, dns.TypeAAAA, dns.TypeLOC, dns.TypeSRV, dns.TypeCERT, dns.TypeSSHFP, dns.TypeRRSIG, dns.TypeDNSKEY, dns.TypeNSEC3PARAM, dns.TypeTLSA, dns.TypeHIP, dns.TypeOPENPGPKEY, dns.TypeSPF}
+)
+
+// filter returns a copy of bitmap without t (if it exists). If mt is not an NODATA response, just
+// return the entire bitmap.
+func filter(t uint16, bitmap []uint16, mt response.Type) []uint16 {
+	if mt != response.NoData && mt != response.NameError {
+		return bitmap
+	}
+	b := make([]uint16, 0, len(bitmap))
+	for i := range bitmap {
+		if bitmap[i] != t {
+			b = append(b, bitmap[i])
+		}
+	}
+	return b
+}
+
+// maxNSEC3Iterations is the maximum number of iterations we allow. RFC 5155 section 10.3 limits
+// the number of iterations to 150 for 1024 bit keys, we use that as the upper bound.
+const maxNSEC3Iterations = 150
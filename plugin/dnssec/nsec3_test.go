@@ -0,0 +1,112 @@
+package dnssec
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestZoneSigningNSEC3BlackLies(t *testing.T) {
+	d, rm1, rm2 := newDnssec(t, []string{"miek.nl."})
+	defer rm1()
+	defer rm2()
+	d.nsec3params = &nsec3Params{iterations: 1, salt: "AABB", optOut: true}
+
+	m := testNxdomainMsg()
+	state := request.Request{Req: m, Zone: "miek.nl."}
+	m = d.Sign(state, time.Now().UTC(), server)
+	if !section(m.Ns, 2) {
+		t.Errorf("Authority section should have 2 sigs")
+	}
+	if m.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeSuccess, m.Rcode)
+	}
+
+	var nsec3 *dns.NSEC3
+	for _, r := range m.Ns {
+		if r.Header().Rrtype == dns.TypeNSEC {
+			t.Errorf("Expected no NSEC records, got %s", r)
+		}
+		if r.Header().Rrtype == dns.TypeNSEC3 {
+			nsec3 = r.(*dns.NSEC3)
+		}
+	}
+	if nsec3 == nil {
+		t.Fatalf("Expected NSEC3, got none")
+	}
+
+	hash := dns.HashName("ww.miek.nl.", dns.SHA1, 1, "AABB")
+	if expected := strings.ToLower(hash) + ".miek.nl."; nsec3.Hdr.Name != expected {
+		t.Errorf("Expected %s, got %s", expected, nsec3.Hdr.Name)
+	}
+	if !nsec3.Match("ww.miek.nl.") {
+		t.Errorf("Expected NSEC3 to match ww.miek.nl.")
+	}
+	if next, _ := nextHash(hash); nsec3.NextDomain != next {
+		t.Errorf("Expected next hashed owner name %s, got %s", next, nsec3.NextDomain)
+	}
+	if nsec3.Iterations != 1 || nsec3.Salt != "AABB" || nsec3.SaltLength != 2 || nsec3.Flags != 1 {
+		t.Errorf("Expected NSEC3 parameters 1 1 AABB, got %d %d %s", nsec3.Flags, nsec3.Iterations, nsec3.Salt)
+	}
+	for _, typ := range nsec3.TypeBitMap {
+		if typ == dns.TypeTXT {
+			t.Errorf("Expected TXT not to be in the type bitmap")
+		}
+	}
+}
+
+func TestNextHash(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"00000000000000000000000000000000", "00000000000000000000000000000001"},
+		{"0000000000000000000000000000000V", "00000000000000000000000000000010"},
+		{"VVVVVVVVVVVVVVVVVVVVVVVVVVVVVVVV", "00000000000000000000000000000000"},
+	}
+	for i, tc := range tests {
+		next, err := nextHash(tc.in)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if next != tc.out {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.out, next)
+		}
+	}
+}
+
+func TestLookupNSEC3PARAM(t *testing.T) {
+	d, rm1, rm2 := newDnssec(t, []string{"miek.nl."})
+	defer rm1()
+	defer rm2()
+	d.nsec3params = &nsec3Params{iterations: 5, salt: "AABB", optOut: true}
+
+	m := new(dns.Msg)
+	m.SetQuestion("miek.nl.", dns.TypeNSEC3PARAM)
+	m.SetEdns0(4096, true)
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := d.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if len(rec.Msg.Answer) != 2 {
+		t.Fatalf("Expected 2 RRs in the answer section, got %d", len(rec.Msg.Answer))
+	}
+	param, ok := rec.Msg.Answer[0].(*dns.NSEC3PARAM)
+	if !ok {
+		t.Fatalf("Expected NSEC3PARAM, got %s", rec.Msg.Answer[0])
+	}
+	if param.Iterations != 5 || param.Salt != "AABB" || param.Flags != 0 {
+		t.Errorf("Expected NSEC3PARAM 1 0 5 AABB, got %s", param)
+	}
+	if _, ok := rec.Msg.Answer[1].(*dns.RRSIG); !ok {
+		t.Errorf("Expected RRSIG, got %s", rec.Msg.Answer[1])
+	}
+}
@@ -24,14 +24,16 @@ func init() {
 }
 
 func setup(c *caddy.Controller) error {
-	zones, keys, capacity, err := dnssecParse(c)
+	zones, keys, capacity, nsec3params, err := dnssecParse(c)
 	if err != nil {
 		return plugin.Error("dnssec", err)
 	}
 
 	ca := cache.New(capacity)
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
-		return New(zones, keys, next, ca)
+		d := New(zones, keys, next, ca)
+		d.nsec3params = nsec3params
+		return d
 	})
 
 	c.OnStartup(func() error {
@@ -42,17 +44,19 @@ func setup(c *caddy.Controller) error {
 	return nil
 }
 
-func dnssecParse(c *caddy.Controller) ([]string, []*DNSKEY, int, error) {
+func dnssecParse(c *caddy.Controller) ([]string, []*DNSKEY, int, *nsec3Params, error) {
 	zones := []string{}
 
 	keys := []*DNSKEY{}
 
 	capacity := defaultCap
 
+	var nsec3params *nsec3Params
+
 	i := 0
 	for c.Next() {
 		if i > 0 {
-			return nil, nil, 0, plugin.ErrOnce
+			return nil, nil, 0, nil, plugin.ErrOnce
 		}
 		i++
 
@@ -70,21 +74,27 @@ func dnssecParse(c *caddy.Controller) ([]string, []*DNSKEY, int, error) {
 			case "key":
 				k, e := keyParse(c)
 				if e != nil {
-					return nil, nil, 0, e
+					return nil, nil, 0, nil, e
 				}
 				keys = append(keys, k...)
 			case "cache_capacity":
 				if !c.NextArg() {
-					return nil, nil, 0, c.ArgErr()
+					return nil, nil, 0, nil, c.ArgErr()
 				}
 				value := c.Val()
 				cacheCap, err := strconv.Atoi(value)
 				if err != nil {
-					return nil, nil, 0, err
+					return nil, nil, 0, nil, err
 				}
 				capacity = cacheCap
+			case "nsec3":
+				n, e := nsec3Parse(c)
+				if e != nil {
+					return nil, nil, 0, nil, e
+				}
+				nsec3params = n
 			default:
-				return nil, nil, 0, c.Errf("unknown property '%s'", x)
+				return nil, nil, 0, nil, c.Errf("unknown property '%s'", x)
 			}
 
 		}
@@ -104,11 +114,33 @@ func dnssecParse(c *caddy.Controller) ([]string, []*DNSKEY, int, error) {
 			}
 		}
 		if !ok {
-			return zones, keys, capacity, fmt.Errorf("key %s (keyid: %d) can not sign any of the zones", string(kname), k.tag)
+			return zones, keys, capacity, nsec3params, fmt.Errorf("key %s (keyid: %d) can not sign any of the zones", string(kname), k.tag)
 		}
 	}
 
-	return zones, keys, capacity, nil
+	return zones, keys, capacity, nsec3params, nil
+}
+
+// nsec3Parse parses: nsec3 [ITERATIONS [SALT]] [opt_out]
+func nsec3Parse(c *caddy.Controller) (*nsec3Params, error) {
+	args := c.RemainingArgs()
+	optOut := false
+	if len(args) > 0 && args[len(args)-1] == "opt_out" {
+		optOut = true
+		args = args[:len(args)-1]
+	}
+	if len(args) > 2 {
+		return nil, c.ArgErr()
+	}
+
+	iterations, salt := "", ""
+	if len(args) > 0 {
+		iterations = args[0]
+	}
+	if len(args) > 1 {
+		salt = args[1]
+	}
+	return newNSEC3Params(iterations, salt, optOut)
 }
 
 func keyParse(c *caddy.Controller) ([]*DNSKEY, error) {
@@ -67,7 +67,7 @@ func TestSetupDnssec(t *testing.T) {
 
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.input)
-		zones, keys, capacity, err := dnssecParse(c)
+		zones, keys, capacity, _, err := dnssecParse(c)
 
 		if test.shouldErr && err == nil {
 			t.Errorf("Test %d: Expected error but found %s for input %s", i, err, test.input)
@@ -120,3 +120,70 @@ Created: 20170901060531
 Publish: 20170901060531
 Activate: 20170901060531
 `
+
+func TestSetupDnssecNSEC3(t *testing.T) {
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedNSEC3      *nsec3Params
+		expectedErrContent string
+	}{
+		{`dnssec`, false, nil, ""},
+		{`dnssec example.org {
+			nsec3
+		}`, false, &nsec3Params{}, ""},
+		{`dnssec example.org {
+			nsec3 10
+		}`, false, &nsec3Params{iterations: 10}, ""},
+		{`dnssec example.org {
+			nsec3 10 aabbccdd
+		}`, false, &nsec3Params{iterations: 10, salt: "AABBCCDD"}, ""},
+		{`dnssec example.org {
+			nsec3 0 - opt_out
+		}`, false, &nsec3Params{optOut: true}, ""},
+		{`dnssec example.org {
+			nsec3 opt_out
+		}`, false, &nsec3Params{optOut: true}, ""},
+		// fails
+		{`dnssec example.org {
+			nsec3 151
+		}`, true, nil, "iterations"},
+		{`dnssec example.org {
+			nsec3 foo
+		}`, true, nil, "iterations"},
+		{`dnssec example.org {
+			nsec3 1 xyz
+		}`, true, nil, "salt"},
+		{`dnssec example.org {
+			nsec3 1 aa bb
+		}`, true, nil, "argument count"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		_, _, _, nsec3params, err := dnssecParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if test.expectedNSEC3 == nil {
+			if nsec3params != nil {
+				t.Errorf("Test %d: Expected no NSEC3 parameters, got %v", i, nsec3params)
+			}
+			continue
+		}
+		if nsec3params == nil || *nsec3params != *test.expectedNSEC3 {
+			t.Errorf("Test %d: Expected NSEC3 parameters %v, got %v", i, test.expectedNSEC3, nsec3params)
+		}
+	}
+}
@@ -3,27 +3,128 @@ package errors
 
 import (
 	"context"
+	golog "log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/output"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
 )
 
 // errorHandler handles DNS errors (and errors from other plugin).
-type errorHandler struct{ Next plugin.Handler }
+type errorHandler struct {
+	Next     plugin.Handler
+	Output   *output.Config // where errors are written; nil is standard output.
+	patterns []*pattern
+
+	log *golog.Logger // set when the server starts, if Output is set.
+}
 
 // ServeDNS implements the plugin.Handler interface.
-func (h errorHandler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+func (h *errorHandler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 	rcode, err := plugin.NextOrFailure(h.Name(), h.Next, ctx, w, r)
 
 	if err != nil {
+		server := metrics.WithServer(ctx)
+		ErrorCount.WithLabelValues(server, pluginName(err)).Inc()
+
+		s := err.Error()
+		for _, p := range h.patterns {
+			if p.pattern.MatchString(s) {
+				if h.consolidate(p) {
+					ConsolidatedCount.WithLabelValues(server, p.pattern.String()).Inc()
+					return rcode, err
+				}
+				break
+			}
+		}
+
 		state := request.Request{W: w, Req: r}
-		clog.Errorf("%d %s %s: %v", rcode, state.Name(), state.Type(), err)
+		h.errorf("%d %s %s: %v", rcode, state.Name(), state.Type(), err)
 	}
 
 	return rcode, err
 }
 
-func (h errorHandler) Name() string { return "errors" }
+// Name implements the plugin.Handler interface.
+func (h *errorHandler) Name() string { return "errors" }
+
+func (h *errorHandler) errorf(format string, v ...interface{}) {
+	if h.log != nil {
+		h.log.Printf("[ERROR] "+format, v...)
+		return
+	}
+	clog.Errorf(format, v...)
+}
+
+// pattern consolidates the errors that match a regular expression: they are counted and a single
+// line is logged for them after period.
+type pattern struct {
+	period  time.Duration
+	pattern *regexp.Regexp
+
+	mu      sync.Mutex
+	count   int
+	timer   *time.Timer
+	stopped bool
+}
+
+// consolidate counts an error matching p, the first one starts the period. It returns false if p
+// has been stopped, the error should then be logged as is.
+func (h *errorHandler) consolidate(p *pattern) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return false
+	}
+	p.count++
+	if p.count == 1 {
+		p.timer = time.AfterFunc(p.period, func() { h.report(p) })
+	}
+	return true
+}
+
+// report logs the number of errors counted for p and resets the count.
+func (h *errorHandler) report(p *pattern) {
+	p.mu.Lock()
+	n := p.count
+	p.count = 0
+	p.mu.Unlock()
+	if n > 0 {
+		h.errorf("%d errors like '%s' occurred in last %s", n, p.pattern, p.period)
+	}
+}
+
+// stop stops the consolidation of errors and reports the errors counted so far.
+func (h *errorHandler) stop() {
+	for _, p := range h.patterns {
+		p.mu.Lock()
+		p.stopped = true
+		pending := p.timer != nil && p.timer.Stop()
+		p.mu.Unlock()
+		if pending {
+			h.report(p)
+		}
+	}
+}
+
+// pluginName returns the name of the plugin that returned err, if err was created with plugin.Error.
+func pluginName(err error) string {
+	s := err.Error()
+	if !strings.HasPrefix(s, "plugin/") {
+		return "unknown"
+	}
+	s = s[len("plugin/"):]
+	i := strings.Index(s, ":")
+	if i <= 0 || strings.ContainsAny(s[:i], " /") {
+		return "unknown"
+	}
+	return s[:i]
+}
@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
@@ -67,6 +70,90 @@ func TestErrors(t *testing.T) {
 	}
 }
 
+func TestErrorsOutput(t *testing.T) {
+	buf := bytes.Buffer{}
+	em := errorHandler{
+		Next: genErrorHandler(dns.RcodeServerFailure, errors.New("test error")),
+		log:  log.New(&buf, "", 0),
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	em.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+
+	if expected := "[ERROR] 2 example.org. A: test error\n"; buf.String() != expected {
+		t.Errorf("Expected log %q, but got %q", expected, buf.String())
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	buf := bytes.Buffer{}
+	var mu sync.Mutex
+	em := &errorHandler{
+		patterns: []*pattern{{period: 50 * time.Millisecond, pattern: regexp.MustCompile("^unreachable")}},
+		log:      log.New(writerFunc(func(p []byte) (int, error) { mu.Lock(); defer mu.Unlock(); return buf.Write(p) }), "", 0),
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	serve := func(err error) {
+		em.Next = genErrorHandler(dns.RcodeServerFailure, err)
+		em.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+	}
+	for i := 0; i < 5; i++ {
+		serve(errors.New("unreachable backend"))
+	}
+	serve(errors.New("other error"))
+
+	mu.Lock()
+	if expected := "[ERROR] 2 example.org. A: other error\n"; buf.String() != expected {
+		t.Errorf("Expected only the error not matching to be logged, got %q", buf.String())
+	}
+	buf.Reset()
+	mu.Unlock()
+
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	if expected := "[ERROR] 5 errors like '^unreachable' occurred in last 50ms\n"; buf.String() != expected {
+		t.Errorf("Expected log %q, but got %q", expected, buf.String())
+	}
+	buf.Reset()
+	mu.Unlock()
+
+	// Errors counted when the handler is stopped are reported, later ones are logged as is.
+	serve(errors.New("unreachable backend"))
+	em.stop()
+	serve(errors.New("unreachable backend"))
+	mu.Lock()
+	defer mu.Unlock()
+	expected := "[ERROR] 1 errors like '^unreachable' occurred in last 50ms\n[ERROR] 2 example.org. A: unreachable backend\n"
+	if buf.String() != expected {
+		t.Errorf("Expected log %q, but got %q", expected, buf.String())
+	}
+}
+
+func TestPluginName(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{plugin.Error("forward", errors.New("no healthy upstreams")), "forward"},
+		{plugin.Error("kubernetes", fmt.Errorf("x: y")), "kubernetes"},
+		{errors.New("no healthy upstreams"), "unknown"},
+		{errors.New("plugin/ bad"), "unknown"},
+		{errors.New("plugin/a b: c"), "unknown"},
+	}
+	for i, tc := range tests {
+		if name := pluginName(tc.err); name != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, name)
+		}
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
 func genErrorHandler(rcode int, err error) plugin.Handler {
 	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 		return rcode, err
@@ -0,0 +1,26 @@
+package errors
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	// ErrorCount is the number of errors returned by the plugins.
+	ErrorCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "errors",
+		Name:      "total",
+		Help:      "Counter of errors returned by plugins, by plugin.",
+	}, []string{"server", "plugin"})
+
+	// ConsolidatedCount is the number of errors that were consolidated instead of logged.
+	ConsolidatedCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "errors",
+		Name:      "consolidated_total",
+		Help:      "Counter of errors that were consolidated, by pattern.",
+	}, []string{"server", "pattern"})
+)
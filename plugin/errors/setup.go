@@ -2,9 +2,14 @@ package errors
 
 import (
 	"fmt"
+	golog "log"
+	"regexp"
+	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/output"
 
 	"github.com/mholt/caddy"
 )
@@ -22,6 +27,43 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("errors", err)
 	}
 
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, ErrorCount, ConsolidatedCount)
+		return nil
+	})
+	c.OnShutdown(func() error {
+		handler.stop()
+		return nil
+	})
+
+	if handler.Output != nil {
+		var w *output.Writer
+		c.OnStartup(func() error {
+			cfg := *handler.Output
+			cfg.Severity = output.SeverityErr
+			var err error
+			if w, err = output.Open(cfg); err != nil {
+				return plugin.Error("errors", err)
+			}
+			flags := golog.LstdFlags
+			if cfg.Kind == output.Syslog {
+				// Syslog adds its own timestamp.
+				flags = 0
+			}
+			handler.log = golog.New(w, "", flags)
+			metrics.MustRegister(c, output.DroppedCount)
+			return nil
+		})
+		c.OnShutdown(func() error {
+			if w == nil {
+				return nil
+			}
+			err := w.Close()
+			w = nil
+			return err
+		})
+	}
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		handler.Next = next
 		return handler
@@ -30,8 +72,8 @@ func setup(c *caddy.Controller) error {
 	return nil
 }
 
-func errorsParse(c *caddy.Controller) (errorHandler, error) {
-	handler := errorHandler{}
+func errorsParse(c *caddy.Controller) (*errorHandler, error) {
+	handler := &errorHandler{}
 
 	i := 0
 	for c.Next() {
@@ -50,6 +92,42 @@ func errorsParse(c *caddy.Controller) (errorHandler, error) {
 		default:
 			return handler, c.ArgErr()
 		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "output":
+				cfg, err := output.Parse(c, dnsserver.GetConfig(c).Root)
+				if err != nil {
+					return handler, err
+				}
+				handler.Output = &cfg
+			case "consolidate":
+				p, err := parsePattern(c)
+				if err != nil {
+					return handler, err
+				}
+				handler.patterns = append(handler.patterns, p)
+			default:
+				return handler, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
 	}
 	return handler, nil
 }
+
+// parsePattern parses 'consolidate DURATION REGEXP'.
+func parsePattern(c *caddy.Controller) (*pattern, error) {
+	args := c.RemainingArgs()
+	if len(args) != 2 {
+		return nil, c.ArgErr()
+	}
+	period, err := time.ParseDuration(args[0])
+	if err != nil || period <= 0 {
+		return nil, c.Errf("invalid duration '%s'", args[0])
+	}
+	re, err := regexp.Compile(args[1])
+	if err != nil {
+		return nil, c.Errf("invalid regular expression '%s': %s", args[1], err)
+	}
+	return &pattern{period: period, pattern: re}, nil
+}
@@ -19,6 +19,31 @@ func TestErrorsParse(t *testing.T) {
 		{`errors
 		errors `, true},
 		{`errors a b`, true},
+		{`errors {
+			output file /var/log/errors.log
+		}`, false},
+		{`errors stdout {
+			output syslog udp://127.0.0.1:514
+		}`, false},
+		{`errors {
+			output file
+		}`, true},
+		{`errors {
+			unknown
+		}`, true},
+		{`errors {
+			consolidate 1m ^unreachable
+			consolidate 5s "i/o timeout$"
+		}`, false},
+		{`errors {
+			consolidate 1m
+		}`, true},
+		{`errors {
+			consolidate 0s ^unreachable
+		}`, true},
+		{`errors {
+			consolidate 1m (
+		}`, true},
 	}
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.inputErrorsRules)
@@ -70,6 +70,10 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeSuccess, nil
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		return z.ServeUpdate(ctx, w, r)
+	}
+
 	if z.Expired != nil && *z.Expired {
 		log.Errorf("Zone %s is expired", zone)
 		return dns.RcodeServerFailure, nil
@@ -128,9 +132,9 @@ func Parse(f io.Reader, origin, fileName string, serial int64) (*Zone, error) {
 			return nil, x.Error
 		}
 
-		if !seenSOA && serial >= 0 {
+		if !seenSOA {
 			if s, ok := x.RR.(*dns.SOA); ok {
-				if s.Serial == uint32(serial) { // same serial
+				if serial >= 0 && s.Serial == uint32(serial) { // same serial
 					return nil, &serialErr{err: "no change in SOA serial", origin: origin, zone: fileName, serial: serial}
 				}
 				seenSOA = true
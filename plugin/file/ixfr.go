@@ -0,0 +1,152 @@
+package file
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// historyLen is the number of changes kept per zone for answering IXFR (RFC 1995) queries.
+const historyLen = 16
+
+// record adds c to the history of z, when c doesn't follow the last change in the history, the history is
+// discarded first. The caller must hold z.reloadMu.
+func (z *Zone) record(c *change) {
+	from, to := c.del[0].(*dns.SOA).Serial, c.add[0].(*dns.SOA).Serial
+	if !newer(to, from) {
+		z.history = nil
+		return
+	}
+	if l := len(z.history); l > 0 && z.history[l-1].add[0].(*dns.SOA).Serial != from {
+		z.history = nil
+	}
+	z.history = append(z.history, c)
+	if len(z.history) > historyLen {
+		z.history = z.history[len(z.history)-historyLen:]
+	}
+}
+
+// ixfr returns the records for an incremental transfer to a client that has serial. It returns nil
+// when serial is not in the history, the client needs a full transfer then. If the client is up to
+// date only the SOA is returned.
+func (z *Zone) ixfr(serial uint32) []dns.RR {
+	z.reloadMu.RLock()
+	defer z.reloadMu.RUnlock()
+
+	if z.Apex.SOA == nil {
+		return nil
+	}
+	if !newer(z.Apex.SOA.Serial, serial) {
+		return []dns.RR{z.Apex.SOA}
+	}
+	for i, c := range z.history {
+		if c.del[0].(*dns.SOA).Serial != serial {
+			continue
+		}
+		records := []dns.RR{z.Apex.SOA}
+		for _, c := range z.history[i:] {
+			records = append(records, c.del...)
+			records = append(records, c.add...)
+		}
+		return append(records, z.Apex.SOA)
+	}
+	return nil
+}
+
+// diff returns the change from z to zone, or nil if either has no SOA record.
+func (z *Zone) diff(zone *Zone) *change {
+	if z.SOASerialIfDefined() < 0 || zone.SOASerialIfDefined() < 0 {
+		return nil
+	}
+	return diff(z.All(), zone.All())
+}
+
+// diff returns the change from the records in old to the ones in new. Both must start with the SOA
+// record, as returned by All.
+func diff(old, new []dns.RR) *change {
+	c := &change{del: []dns.RR{old[0]}, add: []dns.RR{new[0]}}
+
+	inOld := make(map[string]bool, len(old))
+	for _, rr := range old[1:] {
+		inOld[rr.String()] = true
+	}
+	inNew := make(map[string]bool, len(new))
+	for _, rr := range new[1:] {
+		s := rr.String()
+		inNew[s] = true
+		if !inOld[s] {
+			c.add = append(c.add, rr)
+		}
+	}
+	for _, rr := range old[1:] {
+		if !inNew[rr.String()] {
+			c.del = append(c.del, rr)
+		}
+	}
+	return c
+}
+
+// parseIxfr parses the difference sequences of an incremental transfer, see RFC 1995, section 4.
+// The records must start and end with the primary's SOA.
+func parseIxfr(records []dns.RR) ([]*change, error) {
+	if len(records) < 3 {
+		return nil, fmt.Errorf("short incremental transfer")
+	}
+	first, ok1 := records[0].(*dns.SOA)
+	last, ok2 := records[len(records)-1].(*dns.SOA)
+	if !ok1 || !ok2 || first.Serial != last.Serial {
+		return nil, fmt.Errorf("incremental transfer does not start and end with the same SOA")
+	}
+
+	changes := []*change{}
+	var c *change
+	del := false
+	for _, rr := range records[1 : len(records)-1] {
+		if soa, ok := rr.(*dns.SOA); ok {
+			if !del {
+				if c != nil && c.add[0].(*dns.SOA).Serial != soa.Serial {
+					return nil, fmt.Errorf("incremental transfer has a gap at serial %d", soa.Serial)
+				}
+				c = &change{del: []dns.RR{soa}}
+				changes = append(changes, c)
+			} else {
+				c.add = []dns.RR{soa}
+			}
+			del = !del
+			continue
+		}
+		if c == nil {
+			return nil, fmt.Errorf("incremental transfer does not start with a SOA")
+		}
+		if del {
+			c.del = append(c.del, rr)
+		} else {
+			c.add = append(c.add, rr)
+		}
+	}
+	if c == nil || len(c.add) == 0 || c.add[0].(*dns.SOA).Serial != last.Serial {
+		return nil, fmt.Errorf("incomplete incremental transfer")
+	}
+	return changes, nil
+}
+
+// applyIxfr applies the changes of an incremental transfer to z. The first change must start at the
+// serial of z. The changes are added to the history.
+func (z *Zone) applyIxfr(changes []*change) error {
+	z.reloadMu.Lock()
+	defer z.reloadMu.Unlock()
+
+	if z.Apex.SOA == nil || changes[0].del[0].(*dns.SOA).Serial != z.Apex.SOA.Serial {
+		return fmt.Errorf("incremental transfer does not start at serial of zone")
+	}
+	for _, c := range changes {
+		for _, rr := range c.del[1:] {
+			z.remove(rr)
+		}
+		for _, rr := range c.add {
+			z.Insert(rr)
+		}
+		z.record(c)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package file
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestIxfr(t *testing.T) {
+	z := newUpdateZone(t)
+	serial := z.Apex.SOA.Serial
+
+	m := new(dns.Msg)
+	m.SetUpdate("miek.nl.")
+	m.Ns = newRRs(t, []string{"b.miek.nl. 3600 IN A 127.0.0.1", "a.miek.nl. 0 ANY AAAA"})
+	serveUpdate(z, m, &test.ResponseWriter{})
+	m.Ns = newRRs(t, []string{`b.miek.nl. 3600 IN TXT "hello"`})
+	serveUpdate(z, m, &test.ResponseWriter{})
+
+	// soa, (soa(serial), AAAA, soa(serial+1), A), (soa(serial+1), soa(serial+2), TXT), soa
+	records := z.ixfr(serial)
+	if len(records) != 9 {
+		t.Fatalf("Expected 9 records, got %d: %v", len(records), records)
+	}
+	for i, s := range map[int]uint32{0: serial + 2, 1: serial, 3: serial + 1, 5: serial + 1, 6: serial + 2, 8: serial + 2} {
+		if soa, ok := records[i].(*dns.SOA); !ok || soa.Serial != s {
+			t.Errorf("Expected SOA with serial %d at %d, got %s", s, i, records[i])
+		}
+	}
+
+	if records := z.ixfr(serial + 2); len(records) != 1 {
+		t.Errorf("Expected only the SOA when up to date, got %v", records)
+	}
+	if records := z.ixfr(serial - 1); records != nil {
+		t.Errorf("Expected no records for serial not in history, got %v", records)
+	}
+
+	// Apply the difference sequences to the original zone.
+	changes, err := parseIxfr(records)
+	if err != nil {
+		t.Fatalf("Failed to parse incremental transfer: %s", err)
+	}
+	z1 := newUpdateZone(t)
+	if err := z1.applyIxfr(changes); err != nil {
+		t.Fatalf("Failed to apply incremental transfer: %s", err)
+	}
+	if c := diff(z.All(), z1.All()); len(c.del) != 1 || len(c.add) != 1 {
+		t.Errorf("Expected zones to be equal after incremental transfer, got difference %v", c)
+	}
+	if err := z1.applyIxfr(changes); err == nil {
+		t.Errorf("Expected error when applying incremental transfer twice")
+	}
+}
+
+func TestIxfrServe(t *testing.T) {
+	z := newUpdateZone(t)
+	z.TransferTo = []string{"*"}
+	serial := z.Apex.SOA.Serial
+
+	m := new(dns.Msg)
+	m.SetUpdate("miek.nl.")
+	m.Ns = newRRs(t, []string{"b.miek.nl. 3600 IN A 127.0.0.1"})
+	serveUpdate(z, m, &test.ResponseWriter{})
+
+	tests := []struct {
+		serial  uint32
+		tcp     bool
+		answers int
+	}{
+		{serial, true, 5},                    // soa, soa(serial), soa(serial+1), A, soa
+		{serial, false, 1},                   // doesn't fit in UDP, only soa
+		{serial + 1, true, 1},                // up to date
+		{serial - 1, true, len(z.All()) + 1}, // not in history, full transfer
+	}
+	for i, tc := range tests {
+		w := dnstest.NewMultiRecorder(&test.ResponseWriter{TCP: tc.tcp})
+		m := new(dns.Msg)
+		m.SetIxfr("miek.nl.", tc.serial, "ns.miek.nl.", "miek.miek.nl.")
+		Xfr{z}.ServeDNS(context.TODO(), w, m)
+
+		answers := 0
+		for _, m := range w.Msgs {
+			answers += len(m.Answer)
+		}
+		if answers != tc.answers {
+			t.Errorf("Test %d: expected %d records, got %d", i, tc.answers, answers)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	z := NewZone("miek.nl.", "stdin")
+	for i := uint32(0); i < historyLen+4; i++ {
+		z.record(newChange(i, i+1))
+	}
+	if len(z.history) != historyLen {
+		t.Fatalf("Expected history of %d changes, got %d", historyLen, len(z.history))
+	}
+	if s := z.history[0].del[0].(*dns.SOA).Serial; s != 4 {
+		t.Errorf("Expected oldest change to start at serial 4, got %d", s)
+	}
+
+	// A gap discards the history.
+	z.record(newChange(historyLen+10, historyLen+11))
+	if len(z.history) != 1 {
+		t.Errorf("Expected history of 1 change after a gap, got %d", len(z.history))
+	}
+	// So does a decreasing serial.
+	z.record(newChange(historyLen+11, 1))
+	if len(z.history) != 0 {
+		t.Errorf("Expected empty history after decreasing serial, got %d", len(z.history))
+	}
+}
+
+func TestParseIxfr(t *testing.T) {
+	tests := []struct {
+		records   []string
+		changes   int
+		shouldErr bool
+	}{
+		{[]string{"3", "1", "2", "2", "3", "3"}, 2, false},
+		{[]string{"2", "1", "2", "2"}, 1, false},
+		{[]string{"3", "1", "2", "2"}, 0, true},           // doesn't end with primary's SOA
+		{[]string{"3", "1", "2", "3"}, 0, true},           // last change doesn't end at 3
+		{[]string{"4", "1", "2", "3", "4", "4"}, 0, true}, // gap
+		{[]string{"3", "3"}, 0, true},
+	}
+	for i, tc := range tests {
+		records := []dns.RR{}
+		for _, s := range tc.records {
+			records = append(records, test.SOA("miek.nl. 1800 IN SOA linode.atoom.net. miek.miek.nl. "+s+" 14400 3600 604800 14400"))
+		}
+		changes, err := parseIxfr(records)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(changes) != tc.changes {
+			t.Errorf("Test %d: expected %d changes, got %d", i, tc.changes, len(changes))
+		}
+	}
+}
+
+func newChange(from, to uint32) *change {
+	soa := func(serial uint32) dns.RR {
+		return &dns.SOA{Hdr: dns.RR_Header{Name: "miek.nl.", Rrtype: dns.TypeSOA, Class: dns.ClassINET}, Ns: "ns.miek.nl.", Mbox: "miek.miek.nl.", Serial: serial}
+	}
+	return &change{del: []dns.RR{soa(from)}, add: []dns.RR{soa(to)}}
+}
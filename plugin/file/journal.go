@@ -0,0 +1,193 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// JournalExt is the extension of a zone's journal, when no explicit file is configured the journal is
+// the zone file with this extension added.
+const JournalExt = ".jnl"
+
+// The journal is a text file with one entry per update. Each entry starts with the new serial, followed
+// by the RRs that were deleted and added:
+//
+//	serial 1282630058
+//	del example.org. 3600 IN SOA ...
+//	del a.example.org. 3600 IN A 127.0.0.1
+//	add example.org. 3600 IN SOA ...
+//	add a.example.org. 3600 IN A 127.0.0.2
+
+// appendJournal appends change c to the journal in file.
+func appendJournal(file string, c *change) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "serial %d\n", c.add[0].(*dns.SOA).Serial)
+	for _, rr := range c.del {
+		fmt.Fprintf(buf, "del %s\n", rr)
+	}
+	for _, rr := range c.add {
+		fmt.Fprintf(buf, "add %s\n", rr)
+	}
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReplayJournal applies the updates in the zone's journal that are newer than the zone's serial. A missing
+// journal is not an error.
+func (z *Zone) ReplayJournal() error {
+	if z.Journal == "" {
+		return nil
+	}
+	f, err := os.Open(z.Journal)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	z.reloadMu.Lock()
+	defer z.reloadMu.Unlock()
+
+	replayed := 0
+	skip := true
+	var c *change
+	scanner := bufio.NewScanner(f)
+	for i := 1; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: malformed journal entry", z.Journal, i)
+		}
+
+		switch fields[0] {
+		case "serial":
+			serial, err := strconv.ParseUint(fields[1], 10, 32)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %s", z.Journal, i, err)
+			}
+			z.recordJournal(c)
+			c = nil
+			skip = z.Apex.SOA == nil || !newer(uint32(serial), z.Apex.SOA.Serial)
+			if !skip {
+				replayed++
+				c = &change{}
+			}
+		case "del", "add":
+			if skip {
+				continue
+			}
+			rr, err := dns.NewRR(fields[1])
+			if err != nil {
+				return fmt.Errorf("%s:%d: %s", z.Journal, i, err)
+			}
+			if fields[0] == "del" {
+				c.del = append(c.del, rr)
+				if rr.Header().Rrtype != dns.TypeSOA {
+					z.remove(rr)
+				}
+				continue
+			}
+			c.add = append(c.add, rr)
+			z.Insert(rr)
+		default:
+			return fmt.Errorf("%s:%d: unknown journal entry %q", z.Journal, i, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	z.recordJournal(c)
+	if replayed > 0 {
+		log.Infof("Replayed %d updates from journal %s for %s, serial is now %d", replayed, z.Journal, z.origin, z.Apex.SOA.Serial)
+	}
+	return nil
+}
+
+// recordJournal adds the replayed journal entry c to the history of z.
+func (z *Zone) recordJournal(c *change) {
+	if c == nil || len(c.del) == 0 || len(c.add) == 0 {
+		return
+	}
+	_, ok1 := c.del[0].(*dns.SOA)
+	_, ok2 := c.add[0].(*dns.SOA)
+	if ok1 && ok2 {
+		z.record(c)
+	}
+}
+
+// compactJournal removes the entries from the journal in file that are not newer than serial, i.e. the
+// updates that are in the zone file on disk. A missing journal is not an error.
+func compactJournal(file string, serial uint32) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	keep := false
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "serial" {
+			s, err := strconv.ParseUint(fields[1], 10, 32)
+			keep = err != nil || newer(uint32(s), serial)
+		}
+		if keep {
+			buf.WriteString(line)
+		}
+	}
+	return writeFile(file, buf.Bytes())
+}
+
+// writeZone writes records to file in the zone file format.
+func writeZone(file string, records []dns.RR) error {
+	buf := &bytes.Buffer{}
+	for _, rr := range records {
+		fmt.Fprintln(buf, rr.String())
+	}
+	return writeFile(file, buf.Bytes())
+}
+
+// writeFile replaces file with data atomically, the temporary file is a hidden file in the same directory.
+func writeFile(file string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if s, err := os.Stat(file); err == nil {
+		os.Chmod(tmp.Name(), s.Mode())
+	}
+	return os.Rename(tmp.Name(), file)
+}
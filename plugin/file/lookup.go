@@ -30,14 +30,10 @@ func (z *Zone) Lookup(state request.Request, qname string) ([]dns.RR, []dns.RR,
 	qtype := state.QType()
 	do := state.Do()
 
-	if !z.NoReload {
+	if z.mutable() {
 		z.reloadMu.RLock()
+		defer z.reloadMu.RUnlock()
 	}
-	defer func() {
-		if !z.NoReload {
-			z.reloadMu.RUnlock()
-		}
-	}()
 
 	// If z is a secondary zone we might not have transferred it, meaning we have
 	// all zone context setup, except the actual record. This means (for one thing) the apex
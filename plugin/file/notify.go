@@ -27,7 +27,7 @@ func (z *Zone) isNotify(state request.Request) bool {
 		if err != nil {
 			continue
 		}
-		if from == remote {
+		if from == remote && z.signedBy(f, state) {
 			return true
 		}
 	}
@@ -36,21 +36,24 @@ func (z *Zone) isNotify(state request.Request) bool {
 
 // Notify will send notifies to all configured TransferTo IP addresses.
 func (z *Zone) Notify() {
-	go notify(z.origin, z.TransferTo)
+	go z.notify()
 }
 
 // notify sends notifies to the configured remote servers. It will try up to three times
 // before giving up on a specific remote. We will sequentially loop through "to"
-// until they all have replied (or have 3 failed attempts).
-func notify(zone string, to []string) error {
-	m := new(dns.Msg)
-	m.SetNotify(zone)
-	c := new(dns.Client)
-
-	for _, t := range to {
+// until they all have replied (or have 3 failed attempts). Notifies are signed when
+// a TSIG key is configured for the remote.
+func (z *Zone) notify() error {
+	zone := z.origin
+	for _, t := range z.TransferTo {
 		if t == "*" {
 			continue
 		}
+		m := new(dns.Msg)
+		m.SetNotify(zone)
+		c := new(dns.Client)
+		c.TsigSecret = z.sign(t, m)
+
 		if err := notifyAddr(c, m, t); err != nil {
 			log.Error(err.Error())
 		} else {
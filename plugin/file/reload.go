@@ -29,20 +29,51 @@ func (z *Zone) Reload() error {
 					continue
 				}
 
+				// Hold off updates until the new zone is in place, or they are lost; an update applied while
+				// the journal is replayed isn't seen by the replay.
+				z.updateMu.Lock()
 				serial := z.SOASerialIfDefined()
 				zone, err := Parse(reader, z.origin, zFile, serial)
 				if err != nil {
+					z.updateMu.Unlock()
 					if _, ok := err.(*serialErr); !ok {
 						log.Errorf("Parsing zone %q: %v", z.origin, err)
 					}
 					continue
 				}
 
+				// With dynamic updates the serial in memory is ahead of the file, only reload when the file is newer.
+				if z.Updates != nil && serial >= 0 && !newer(zone.Apex.SOA.Serial, uint32(serial)) {
+					z.updateMu.Unlock()
+					continue
+				}
+
+				fileSerial := zone.Apex.SOA.Serial
+				zone.Journal = z.Journal
+				if err := zone.ReplayJournal(); err != nil {
+					z.updateMu.Unlock()
+					log.Errorf("Replaying journal for zone %q: %v", z.origin, err)
+					continue
+				}
+
+				// Record the difference with the current zone, so we can answer IXFR queries.
+				c := z.diff(zone)
+
 				// copy elements we need
 				z.reloadMu.Lock()
+				if c != nil {
+					z.record(c)
+				}
 				z.Apex = zone.Apex
 				z.Tree = zone.Tree
 				z.reloadMu.Unlock()
+				if z.Journal != "" {
+					// Updates that made it into the zone file don't need to be replayed again.
+					if err := compactJournal(z.Journal, fileSerial); err != nil {
+						log.Errorf("Failed to compact journal %s for %s: %s", z.Journal, z.origin, err)
+					}
+				}
+				z.updateMu.Unlock()
 
 				log.Infof("Successfully reloaded zone %q in %q with serial %d", z.origin, zFile, z.Apex.SOA.Serial)
 				z.Notify()
@@ -58,6 +58,11 @@ func TestZoneReload(t *testing.T) {
 	if len(z.All()) != 3 {
 		t.Fatalf("Expected 3 RRs, got %d", len(z.All()))
 	}
+
+	// soa, soa(1460175181), NS, NS, soa(1460175182), soa
+	if records := z.ixfr(1460175181); len(records) != 6 {
+		t.Fatalf("Expected 6 RRs in incremental transfer after reload, got %d", len(records))
+	}
 }
 
 func TestZoneReloadSOAChange(t *testing.T) {
@@ -1,82 +1,164 @@
 package file
 
 import (
+	"fmt"
 	"math/rand"
+	"os"
 	"time"
 
 	"github.com/miekg/dns"
 )
 
-// TransferIn retrieves the zone from the masters, parses it and sets it live.
+// TransferIn retrieves the zone from the masters, parses it and sets it live. When we already have a copy
+// of the zone an incremental transfer (RFC 1995) is tried first, falling back to a full transfer.
 func (z *Zone) TransferIn() error {
 	if len(z.TransferFrom) == 0 {
 		return nil
 	}
-	m := new(dns.Msg)
-	m.SetAxfr(z.origin)
-
-	z1 := z.CopyWithoutApex()
 	var (
 		Err error
 		tr  string
 	)
 
-Transfer:
 	for _, tr = range z.TransferFrom {
-		t := new(dns.Transfer)
-		c, err := t.In(m, tr)
-		if err != nil {
-			log.Errorf("Failed to setup transfer `%s' with `%q': %v", z.origin, tr, err)
-			Err = err
-			continue Transfer
-		}
-		for env := range c {
-			if env.Error != nil {
-				log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, env.Error)
-				Err = env.Error
-				continue Transfer
+		z.reloadMu.RLock()
+		soa := z.Apex.SOA
+		z.reloadMu.RUnlock()
+
+		if soa != nil {
+			records, err := z.transfer(tr, soa)
+			if err == nil {
+				err = z.setIxfr(records)
 			}
-			for _, rr := range env.RR {
-				if err := z1.Insert(rr); err != nil {
-					log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, err)
-					Err = err
-					continue Transfer
-				}
+			if err == nil {
+				Err = nil
+				break
 			}
+			log.Warningf("Failed incremental transfer `%s' from %q, trying full transfer: %v", z.origin, tr, err)
 		}
-		Err = nil
-		break
+
+		records, err := z.transfer(tr, nil)
+		if err == nil {
+			err = z.setAxfr(records)
+		}
+		if err == nil {
+			Err = nil
+			break
+		}
+		log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, err)
+		Err = err
 	}
 	if Err != nil {
 		return Err
 	}
 
-	z.Tree = z1.Tree
-	z.Apex = z1.Apex
 	*z.Expired = false
 	log.Infof("Transferred: %s from %s", z.origin, tr)
+	if z.Cache != "" {
+		if err := writeZone(z.Cache, z.All()); err != nil {
+			log.Errorf("Failed to write %s to cache %s: %s", z.origin, z.Cache, err)
+		}
+	}
+	z.refreshed()
+	return nil
+}
+
+// transfer transfers the zone from tr. If soa is not nil an IXFR is requested, otherwise an AXFR.
+func (z *Zone) transfer(tr string, soa *dns.SOA) ([]dns.RR, error) {
+	m := new(dns.Msg)
+	if soa != nil {
+		m.SetIxfr(z.origin, soa.Serial, soa.Ns, soa.Mbox)
+	} else {
+		m.SetAxfr(z.origin)
+	}
+	t := new(dns.Transfer)
+	t.TsigSecret = z.sign(tr, m)
+	c, err := t.In(m, tr)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []dns.RR{}
+	for env := range c {
+		if env.Error != nil {
+			err = env.Error
+			continue
+		}
+		records = append(records, env.RR...)
+	}
+	return records, err
+}
+
+// setIxfr applies the reply to an IXFR to z. The reply can be a single SOA when we're up to date, the
+// difference sequences or the full zone.
+func (z *Zone) setIxfr(records []dns.RR) error {
+	if len(records) == 0 {
+		return fmt.Errorf("empty incremental transfer")
+	}
+	if len(records) == 1 {
+		soa, ok := records[0].(*dns.SOA)
+		if serial := z.SOASerialIfDefined(); ok && serial >= 0 && newer(soa.Serial, uint32(serial)) {
+			return fmt.Errorf("only SOA with serial %d in incremental transfer", soa.Serial)
+		}
+		return nil
+	}
+	if _, ok := records[1].(*dns.SOA); !ok || len(records) == 2 {
+		return z.setAxfr(records)
+	}
+	changes, err := parseIxfr(records)
+	if err != nil {
+		return err
+	}
+	return z.applyIxfr(changes)
+}
+
+// setAxfr replaces the contents of z with records.
+func (z *Zone) setAxfr(records []dns.RR) error {
+	z1 := z.CopyWithoutApex()
+	for _, rr := range records {
+		if err := z1.Insert(rr); err != nil {
+			return err
+		}
+	}
+	if z1.Apex.SOA == nil {
+		return fmt.Errorf("no SOA in transfer")
+	}
+	c := z.diff(z1)
+
+	z.reloadMu.Lock()
+	if c != nil {
+		z.record(c)
+	}
+	z.Tree = z1.Tree
+	z.Apex = z1.Apex
+	z.reloadMu.Unlock()
 	return nil
 }
 
 // shouldTransfer checks the primaries of zone, retrieves the SOA record, checks the current serial
 // and the remote serial and will return true if the remote one is higher than the locally configured one.
 func (z *Zone) shouldTransfer() (bool, error) {
-	c := new(dns.Client)
-	c.Net = "tcp" // do this query over TCP to minimize spoofing
-	m := new(dns.Msg)
-	m.SetQuestion(z.origin, dns.TypeSOA)
-
 	var Err error
 	serial := -1
 
 Transfer:
 	for _, tr := range z.TransferFrom {
 		Err = nil
+		c := new(dns.Client)
+		c.Net = "tcp" // do this query over TCP to minimize spoofing
+		m := new(dns.Msg)
+		m.SetQuestion(z.origin, dns.TypeSOA)
+		c.TsigSecret = z.sign(tr, m)
+
 		ret, _, err := c.Exchange(m, tr)
 		if err != nil || ret.Rcode != dns.RcodeSuccess {
 			Err = err
 			continue
 		}
+		if c.TsigSecret != nil && ret.IsTsig() == nil {
+			Err = fmt.Errorf("unsigned reply from %q for zone %q", tr, z.origin)
+			continue
+		}
 		for _, a := range ret.Answer {
 			if a.Header().Rrtype == dns.TypeSOA {
 				serial = int(a.(*dns.SOA).Serial)
@@ -110,16 +192,23 @@ func (z *Zone) Update() error {
 	for z.Apex.SOA == nil {
 		time.Sleep(1 * time.Second)
 	}
-	retryActive := false
+	var retryActive bool
 
 Restart:
 	refresh := time.Second * time.Duration(z.Apex.SOA.Refresh)
 	retry := time.Second * time.Duration(z.Apex.SOA.Retry)
 	expire := time.Second * time.Duration(z.Apex.SOA.Expire)
 
-	refreshTicker := time.NewTicker(refresh)
+	// The timers run from the last transfer, which can be a while ago when the zone was loaded from its cache.
+	age := z.age()
+	if age >= expire {
+		*z.Expired = true
+	}
+	retryActive = age >= refresh
+
+	refreshTicker := time.NewTicker(remaining(refresh, age))
 	retryTicker := time.NewTicker(retry)
-	expireTicker := time.NewTicker(expire)
+	expireTicker := time.NewTicker(remaining(expire, age))
 
 	for {
 		select {
@@ -147,6 +236,8 @@ Restart:
 					// transfer failed, leave retryActive true
 					break
 				}
+			} else {
+				z.refreshed()
 			}
 
 			// no errors, stop timers and restart
@@ -173,6 +264,8 @@ Restart:
 					retryActive = true
 					break
 				}
+			} else {
+				z.refreshed()
 			}
 
 			// no errors, stop timers and restart
@@ -186,6 +279,78 @@ Restart:
 	}
 }
 
+// LoadCache loads the zone from its cache file. The modification time of the file is the time of the last
+// transfer, if the zone has expired since then it is not loaded. A missing cache file is ignored, as is one
+// that can't be read or parsed, the zone is then transferred as usual.
+func (z *Zone) LoadCache() {
+	if z.Cache == "" {
+		return
+	}
+	f, err := os.Open(z.Cache)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warningf("Failed to load %s from cache %s, ignoring it: %s", z.origin, z.Cache, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		log.Warningf("Failed to load %s from cache %s, ignoring it: %s", z.origin, z.Cache, err)
+		return
+	}
+	z1, err := Parse(f, z.origin, z.Cache, -1)
+	if err != nil {
+		log.Warningf("Failed to load %s from cache %s, ignoring it: %s", z.origin, z.Cache, err)
+		return
+	}
+	if time.Since(fi.ModTime()) >= time.Second*time.Duration(z1.Apex.SOA.Expire) {
+		log.Infof("Cached zone %s in %s has expired, not loading it", z.origin, z.Cache)
+		return
+	}
+
+	z.reloadMu.Lock()
+	z.Tree = z1.Tree
+	z.Apex = z1.Apex
+	z.transferred = fi.ModTime()
+	z.reloadMu.Unlock()
+
+	log.Infof("Loaded %s with serial %d from cache %s", z.origin, z1.Apex.SOA.Serial, z.Cache)
+}
+
+// refreshed records that the zone was transferred, or found to be up to date, just now.
+func (z *Zone) refreshed() {
+	now := time.Now()
+	z.reloadMu.Lock()
+	z.transferred = now
+	z.reloadMu.Unlock()
+
+	if z.Cache != "" {
+		if err := os.Chtimes(z.Cache, now, now); err != nil && !os.IsNotExist(err) {
+			log.Warningf("Failed to update time of cache %s: %s", z.Cache, err)
+		}
+	}
+}
+
+// age returns the time since the zone was last transferred, or found to be up to date.
+func (z *Zone) age() time.Duration {
+	z.reloadMu.RLock()
+	defer z.reloadMu.RUnlock()
+	if z.transferred.IsZero() {
+		return 0
+	}
+	return time.Since(z.transferred)
+}
+
+// remaining returns what is left of d after elapsed. If nothing is left, d is returned.
+func remaining(d, elapsed time.Duration) time.Duration {
+	if elapsed < d {
+		return d - elapsed
+	}
+	return d
+}
+
 // jitter returns a random duration between [0,n) * time.Millisecond
 func jitter(n int) time.Duration {
 	r := rand.Intn(n)
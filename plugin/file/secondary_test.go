@@ -2,8 +2,14 @@ package file
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
 
@@ -138,6 +144,118 @@ func TestTransferIn(t *testing.T) {
 	}
 }
 
+func TestTransferInCache(t *testing.T) {
+	soa := soa{250}
+
+	dns.HandleFunc(testZone, soa.Handler)
+	defer dns.HandleRemove(testZone)
+
+	s, addrstr, err := test.TCPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	dir, err := ioutil.TempDir("", "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{addrstr}
+	z.Cache = filepath.Join(dir, "db."+testZone)
+
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Unable to run TransferIn: %v", err)
+	}
+
+	f, err := os.Open(z.Cache)
+	if err != nil {
+		t.Fatalf("Expected zone to be written to cache: %v", err)
+	}
+	defer f.Close()
+	z1, err := Parse(f, testZone, z.Cache, 0)
+	if err != nil {
+		t.Fatalf("Unable to parse cache: %v", err)
+	}
+	if z1.Apex.SOA.Serial != 250 {
+		t.Fatalf("Expected SOA with serial 250 in cache, got %d", z1.Apex.SOA.Serial)
+	}
+}
+
+func TestLoadCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	z := NewZone("miek.nl.", "stdin")
+	z.Cache = filepath.Join(dir, "db.miek.nl")
+
+	// A missing cache is not an error.
+	z.LoadCache()
+	if z.Apex.SOA != nil {
+		t.Fatalf("Expected no SOA for missing cache")
+	}
+
+	if err := ioutil.WriteFile(z.Cache, []byte(dbMiekNL), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Transferred 1 day ago, expire is 604800 (1 week).
+	then := time.Now().Add(-24 * time.Hour)
+	os.Chtimes(z.Cache, then, then)
+	z.LoadCache()
+	if z.Apex.SOA == nil {
+		t.Fatalf("Expected zone to be loaded from cache")
+	}
+	if age := z.age(); age < 24*time.Hour || age > 25*time.Hour {
+		t.Errorf("Expected zone to be a day old, got %s", age)
+	}
+
+	// Transferred 2 weeks ago, the zone has expired.
+	z = NewZone("miek.nl.", "stdin")
+	z.Cache = filepath.Join(dir, "db.miek.nl")
+	then = time.Now().Add(-14 * 24 * time.Hour)
+	os.Chtimes(z.Cache, then, then)
+	z.LoadCache()
+	if z.Apex.SOA != nil {
+		t.Fatalf("Expected expired zone not to be loaded from cache")
+	}
+
+	// A zone with serial 0 is loaded.
+	z = NewZone("miek.nl.", "stdin")
+	z.Cache = filepath.Join(dir, "db.miek.nl")
+	if err := ioutil.WriteFile(z.Cache, []byte(strings.Replace(dbMiekNL, "1282630057 ; Serial", "0 ; Serial", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	z.LoadCache()
+	if z.Apex.SOA == nil || z.Apex.SOA.Serial != 0 {
+		t.Fatalf("Expected zone with serial 0 to be loaded from cache")
+	}
+
+	// A corrupt cache is ignored.
+	z = NewZone("miek.nl.", "stdin")
+	z.Cache = filepath.Join(dir, "db.miek.nl")
+	if err := ioutil.WriteFile(z.Cache, []byte("miek.nl. IN SOA garbage\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	z.LoadCache()
+	if z.Apex.SOA != nil {
+		t.Fatalf("Expected corrupt cache not to be loaded")
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	if r := remaining(time.Hour, 20*time.Minute); r != 40*time.Minute {
+		t.Errorf("Expected 40m, got %s", r)
+	}
+	if r := remaining(time.Hour, 2*time.Hour); r != time.Hour {
+		t.Errorf("Expected 1h, got %s", r)
+	}
+}
+
 func TestIsNotify(t *testing.T) {
 	z := new(Zone)
 	z.Expired = new(bool)
@@ -154,6 +272,17 @@ func TestIsNotify(t *testing.T) {
 	if z.isNotify(state) {
 		t.Fatal("Should have been invalid notify")
 	}
+
+	k := tsig.Key{Name: "xfr.example.org.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"}
+	z.TransferFrom = []string{"10.240.0.1:53"}
+	z.TransferKeys = map[string]tsig.Key{"10.240.0.1:53": k}
+	if z.isNotify(state) {
+		t.Fatal("Should have been invalid notify, as it is not signed")
+	}
+	k.SetTsig(state.Req)
+	if !z.isNotify(state) {
+		t.Fatal("Should have been valid notify, as it is signed")
+	}
 }
 
 func newRequest(zone string, qtype uint16) request.Request {
@@ -7,6 +7,7 @@ import (
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 
 	"github.com/mholt/caddy"
@@ -94,12 +95,21 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 		noReload := false
 		upstr := upstream.Upstream{}
 		t := []string{}
+		var keys map[string]tsig.Key
 		var e error
+		var updates *UpdatePolicy
+		persist := false
+		journal := ""
 
 		for c.NextBlock() {
 			switch c.Val() {
 			case "transfer":
-				t, _, e = parse.Transfer(c, false)
+				var key string
+				t, _, key, e = parse.Transfer(c, false)
+				if e != nil {
+					return Zones{}, e
+				}
+				keys, e = TransferKeys(c, keys, key, t)
 				if e != nil {
 					return Zones{}, e
 				}
@@ -114,6 +124,38 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 					return Zones{}, err
 				}
 
+			case "update":
+				updates, err = ParseUpdatePolicy(c.RemainingArgs(), dnsserver.GetConfig(c).TsigKeys)
+				if err != nil {
+					return Zones{}, c.Err(err.Error())
+				}
+
+			case "persist":
+				if c.NextArg() {
+					return Zones{}, c.ArgErr()
+				}
+				if len(origins) > 1 {
+					return Zones{}, c.Err("persist can only be used with a single zone")
+				}
+				persist = true
+
+			case "journal":
+				args := c.RemainingArgs()
+				switch len(args) {
+				case 0:
+					journal = fileName + JournalExt
+				case 1:
+					journal = args[0]
+					if !path.IsAbs(journal) && config.Root != "" {
+						journal = path.Join(config.Root, journal)
+					}
+				default:
+					return Zones{}, c.ArgErr()
+				}
+				if len(origins) > 1 {
+					return Zones{}, c.Err("a journal can only be used with a single zone")
+				}
+
 			default:
 				return Zones{}, c.Errf("unknown property '%s'", c.Val())
 			}
@@ -122,10 +164,39 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 				if t != nil {
 					z[origin].TransferTo = append(z[origin].TransferTo, t...)
 				}
+				z[origin].TransferKeys = keys
 				z[origin].NoReload = noReload
 				z[origin].Upstream = upstr
+				z[origin].Updates = updates
+				z[origin].Persist = persist
+				z[origin].Journal = journal
+			}
+		}
+
+		for _, origin := range origins {
+			if err := z[origin].ReplayJournal(); err != nil {
+				return Zones{}, err
 			}
 		}
 	}
 	return Zones{Z: z, Names: names}, nil
 }
+
+// TransferKeys adds the TSIG key name, as defined with the tsig plugin, for each address in addrs to keys.
+// The (possibly newly allocated) keys are returned. If name is empty, keys is returned as is.
+func TransferKeys(c *caddy.Controller, keys map[string]tsig.Key, name string, addrs []string) (map[string]tsig.Key, error) {
+	if name == "" {
+		return keys, nil
+	}
+	k, ok := dnsserver.GetConfig(c).TsigKeys[name]
+	if !ok {
+		return nil, c.Errf("unknown TSIG key '%s'", name)
+	}
+	if keys == nil {
+		keys = make(map[string]tsig.Key)
+	}
+	for _, a := range addrs {
+		keys[a] = k
+	}
+	return keys, nil
+}
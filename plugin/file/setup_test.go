@@ -3,9 +3,12 @@ package file
 import (
 	"testing"
 
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
 func TestFileParse(t *testing.T) {
@@ -67,10 +70,56 @@ func TestFileParse(t *testing.T) {
 			true,
 			Zones{Names: []string{}},
 		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				update key update.miek.nl. net 10.0.0.0/8
+				persist
+				journal
+			}`,
+			false,
+			Zones{Names: []string{"miek.nl."}},
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				update key unknown.miek.nl.
+			}`,
+			true,
+			Zones{Names: []string{}},
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				update
+			}`,
+			true,
+			Zones{Names: []string{}},
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				update net 10.0.0.0/40
+			}`,
+			true,
+			Zones{Names: []string{}},
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. example.org. {
+				journal
+			}`,
+			true,
+			Zones{Names: []string{}},
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				persist always
+			}`,
+			true,
+			Zones{Names: []string{}},
+		},
 	}
 
+	k := tsig.Key{Name: "update.miek.nl.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"}
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.inputFileRules)
+		dnsserver.GetConfig(c).TsigKeys = map[string]tsig.Key{k.Name: k}
 		actualZones, err := fileParse(c)
 
 		if err == nil && test.shouldErr {
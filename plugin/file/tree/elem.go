@@ -1,6 +1,10 @@
 package tree
 
-import "github.com/miekg/dns"
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
 
 // Elem is an element in the tree.
 type Elem struct {
@@ -97,11 +101,10 @@ func (e *Elem) Delete(rr dns.RR) (empty bool) {
 		if equalRdata(er, rr) {
 			rrs = removeFromSlice(rrs, i)
 			e.m[t] = rrs
-			empty = len(rrs) == 0
-			if empty {
+			if len(rrs) == 0 {
 				delete(e.m, t)
 			}
-			return
+			return len(e.m) == 0
 		}
 	}
 	return
@@ -113,7 +116,6 @@ func Less(a *Elem, name string) int { return less(name, a.Name()) }
 // Assuming the same type and name this will check if the rdata is equal as well.
 func equalRdata(a, b dns.RR) bool {
 	switch x := a.(type) {
-	// TODO(miek): more types, i.e. all types. + tests for this.
 	case *dns.A:
 		return x.A.Equal(b.(*dns.A).A)
 	case *dns.AAAA:
@@ -122,8 +124,10 @@ func equalRdata(a, b dns.RR) bool {
 		if x.Mx == b.(*dns.MX).Mx && x.Preference == b.(*dns.MX).Preference {
 			return true
 		}
+		return false
 	}
-	return false
+	// Other types: compare the rdata in presentation format.
+	return strings.TrimPrefix(a.String(), a.Header().String()) == strings.TrimPrefix(b.String(), b.Header().String())
 }
 
 // removeFromSlice removes index i from the slice.
@@ -0,0 +1,504 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/tsig"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// UpdatePolicy defines who is allowed to dynamically update (RFC 2136) a zone.
+type UpdatePolicy struct {
+	Keys []tsig.Key   // TSIG keys, the update must be signed with one of these.
+	Nets []*net.IPNet // Networks, the update must be sent from one of these.
+
+	verify []tsig.Key // All TSIG keys of the server block, signed updates must verify with one of these.
+}
+
+// ParseUpdatePolicy parses the arguments of the update property: [key KEY...] [net NETWORK...]. Each KEY
+// must be in keys, the TSIG keys of the server block.
+func ParseUpdatePolicy(args []string, keys map[string]tsig.Key) (*UpdatePolicy, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("update needs at least one key or network")
+	}
+	p := &UpdatePolicy{}
+	for _, k := range keys {
+		p.verify = append(p.verify, k)
+	}
+	section := ""
+	for _, a := range args {
+		switch a {
+		case "key", "net":
+			section = a
+			continue
+		}
+		switch section {
+		case "key":
+			k, ok := keys[strings.ToLower(dns.Fqdn(a))]
+			if !ok {
+				return nil, fmt.Errorf("unknown TSIG key '%s'", a)
+			}
+			p.Keys = append(p.Keys, k)
+		case "net":
+			if !strings.Contains(a, "/") {
+				if ip := net.ParseIP(a); ip != nil && ip.To4() != nil {
+					a += "/32"
+				} else {
+					a += "/128"
+				}
+			}
+			_, n, err := net.ParseCIDR(a)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %s", a, err)
+			}
+			p.Nets = append(p.Nets, n)
+		default:
+			return nil, fmt.Errorf("expected 'key' or 'net', got %q", a)
+		}
+	}
+	if len(p.Keys) == 0 && len(p.Nets) == 0 {
+		return nil, fmt.Errorf("update needs at least one key or network")
+	}
+	return p, nil
+}
+
+// allowed returns true when an update from ip, signed with key, is allowed. When the policy has both keys and
+// networks, the update must match both.
+func (p *UpdatePolicy) allowed(ip net.IP, key string) bool {
+	if len(p.Keys) > 0 {
+		found := false
+		for _, k := range p.Keys {
+			if k.Name == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(p.Nets) > 0 {
+		for _, n := range p.Nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// change is the result of applying an update: the RRs that were removed from and added to the zone. The
+// old SOA is the first RR in del and the new SOA the first RR in add.
+type change struct {
+	del []dns.RR
+	add []dns.RR
+}
+
+// ServeUpdate handles a dynamic update (RFC 2136) for zone z and writes the reply to the client.
+func (z *Zone) ServeUpdate(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r, Context: ctx}
+
+	m := new(dns.Msg)
+	m.SetRcode(r, z.update(state))
+	tsig.Sign(m, r)
+	w.WriteMsg(m)
+
+	return dns.RcodeSuccess, nil
+}
+
+// update checks and applies the update in state and returns the rcode for the reply.
+func (z *Zone) update(state request.Request) int {
+	r := state.Req
+	if state.QType() != dns.TypeSOA || state.QClass() != dns.ClassINET {
+		return dns.RcodeFormatError
+	}
+	if strings.ToLower(state.Name()) != z.origin {
+		return dns.RcodeNotAuth
+	}
+
+	var verify []tsig.Key
+	if z.Updates != nil {
+		verify = z.Updates.verify
+	}
+	key := tsig.Verified(state.W, r, verify...)
+	if r.IsTsig() != nil && key == "" {
+		log.Warningf("Update from %s for %s: TSIG did not verify", state.IP(), z.origin)
+		return dns.RcodeNotAuth
+	}
+	if z.Updates == nil || !z.Updates.allowed(net.ParseIP(state.IP()), key) {
+		log.Infof("Refusing update from %s for %s", state.IP(), z.origin)
+		return dns.RcodeRefused
+	}
+	// Secondaries should forward the update to the primary, we don't.
+	if len(z.TransferFrom) > 0 {
+		return dns.RcodeRefused
+	}
+
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	z.reloadMu.Lock()
+	if z.Apex.SOA == nil || len(z.Apex.SIGSOA) > 0 { // Signed zones can't be updated, we can't sign the changes.
+		z.reloadMu.Unlock()
+		return dns.RcodeRefused
+	}
+	if rcode := z.prerequisites(r.Answer); rcode != dns.RcodeSuccess {
+		z.reloadMu.Unlock()
+		return rcode
+	}
+	if rcode := z.prescan(r.Ns); rcode != dns.RcodeSuccess {
+		z.reloadMu.Unlock()
+		return rcode
+	}
+	c := z.apply(r.Ns)
+	if c != nil {
+		z.record(c)
+	}
+	var records []dns.RR
+	if c != nil && z.Persist {
+		records = z.all()
+	}
+	file := z.file
+	z.reloadMu.Unlock()
+
+	if c == nil {
+		return dns.RcodeSuccess
+	}
+	log.Infof("Applied update from %s for %s, serial is now %d", state.IP(), z.origin, c.add[0].(*dns.SOA).Serial)
+
+	if z.Journal != "" {
+		if err := appendJournal(z.Journal, c); err != nil {
+			log.Errorf("Failed to write update for %s to journal %s: %s", z.origin, z.Journal, err)
+		}
+	}
+	if z.Persist {
+		if err := writeZone(file, records); err != nil {
+			log.Errorf("Failed to write update for %s to %s: %s", z.origin, file, err)
+		} else if z.Journal != "" {
+			// The zone file has all updates now, the journal can be emptied.
+			if err := compactJournal(z.Journal, c.add[0].(*dns.SOA).Serial); err != nil {
+				log.Errorf("Failed to compact journal %s for %s: %s", z.Journal, z.origin, err)
+			}
+		}
+	}
+	z.Notify()
+
+	return dns.RcodeSuccess
+}
+
+// prerequisites checks the prerequisite section of an update, see RFC 2136, section 3.2.
+func (z *Zone) prerequisites(prereqs []dns.RR) int {
+	// RRsets that must exist with exactly these values.
+	exact := map[rrsetKey][]dns.RR{}
+
+	for _, rr := range prereqs {
+		h := rr.Header()
+		name := strings.ToLower(h.Name)
+		if h.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+		if !dns.IsSubDomain(z.origin, name) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+		case dns.ClassANY:
+			if h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeANY {
+				if !z.nameUsed(name) {
+					return dns.RcodeNameError
+				}
+				continue
+			}
+			if len(z.rrset(name, h.Rrtype)) == 0 {
+				return dns.RcodeNXRrset
+			}
+
+		case dns.ClassNONE:
+			if h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeANY {
+				if z.nameUsed(name) {
+					return dns.RcodeYXDomain
+				}
+				continue
+			}
+			if len(z.rrset(name, h.Rrtype)) > 0 {
+				return dns.RcodeYXRrset
+			}
+
+		case dns.ClassINET:
+			k := rrsetKey{name, h.Rrtype}
+			exact[k] = append(exact[k], rr)
+
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for k, rrs := range exact {
+		if !equalRRset(z.rrset(k.name, k.qtype), rrs) {
+			return dns.RcodeNXRrset
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// prescan checks the update section of an update, see RFC 2136, section 3.4.1.
+func (z *Zone) prescan(updates []dns.RR) int {
+	for _, rr := range updates {
+		h := rr.Header()
+		if !dns.IsSubDomain(z.origin, strings.ToLower(h.Name)) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Rrtype {
+		case dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB, dns.TypeOPT, dns.TypeTSIG:
+			return dns.RcodeFormatError
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeNSEC3PARAM, dns.TypeDNSKEY:
+			return dns.RcodeRefused
+		}
+
+		switch h.Class {
+		case dns.ClassINET:
+			if h.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassANY:
+			if h.Ttl != 0 || h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassNONE:
+			if h.Ttl != 0 || h.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// apply applies the updates to the zone, see RFC 2136, section 3.4.2. When the zone was changed the SOA serial
+// is increased and the change is returned, otherwise nil is returned.
+func (z *Zone) apply(updates []dns.RR) *change {
+	c := &change{}
+	soa := z.Apex.SOA
+
+	for _, rr := range updates {
+		h := rr.Header()
+		name := strings.ToLower(h.Name)
+
+		switch h.Class {
+		case dns.ClassINET:
+			if h.Rrtype == dns.TypeSOA {
+				if name != z.origin || !newer(rr.(*dns.SOA).Serial, z.Apex.SOA.Serial) {
+					continue
+				}
+				rr = dns.Copy(rr)
+				z.Insert(rr)
+				continue
+			}
+			cname := len(z.rrset(name, dns.TypeCNAME)) > 0
+			if h.Rrtype == dns.TypeCNAME {
+				if name == z.origin || (!cname && z.nameUsed(name)) {
+					continue // Can't add a CNAME next to other data.
+				}
+			} else if cname {
+				continue // Can't add data next to a CNAME.
+			}
+
+			// Replace an existing RR with the same rdata, but a different TTL. A CNAME replaces the existing one.
+			found := false
+			for _, old := range append([]dns.RR{}, z.rrset(name, h.Rrtype)...) {
+				same := equalRdata(old, rr)
+				if !same && h.Rrtype != dns.TypeCNAME {
+					continue
+				}
+				if same && old.Header().Ttl == h.Ttl {
+					found = true
+					continue
+				}
+				z.remove(old)
+				c.del = append(c.del, old)
+			}
+			if !found {
+				rr = dns.Copy(rr)
+				z.Insert(rr)
+				c.add = append(c.add, rr)
+			}
+
+		case dns.ClassANY:
+			types := []uint16{h.Rrtype}
+			if h.Rrtype == dns.TypeANY {
+				types = z.types(name)
+			}
+			for _, t := range types {
+				if name == z.origin && (t == dns.TypeSOA || t == dns.TypeNS) {
+					continue
+				}
+				rrs := z.rrset(name, t)
+				for _, old := range append([]dns.RR{}, rrs...) {
+					z.remove(old)
+					c.del = append(c.del, old)
+				}
+			}
+
+		case dns.ClassNONE:
+			if h.Rrtype == dns.TypeSOA {
+				continue
+			}
+			rrs := z.rrset(name, h.Rrtype)
+			if name == z.origin && h.Rrtype == dns.TypeNS && len(rrs) == 1 {
+				continue // Never delete the last NS record of the zone.
+			}
+			for _, old := range append([]dns.RR{}, rrs...) {
+				if equalRdata(old, rr) {
+					z.remove(old)
+					c.del = append(c.del, old)
+				}
+			}
+		}
+	}
+
+	if len(c.del) == 0 && len(c.add) == 0 && z.Apex.SOA == soa {
+		return nil
+	}
+
+	// Bump the serial, unless the update did so itself.
+	if z.Apex.SOA == soa {
+		s := dns.Copy(soa).(*dns.SOA)
+		s.Serial++
+		z.Apex.SOA = s
+	}
+	c.del = append([]dns.RR{soa}, c.del...)
+	c.add = append([]dns.RR{z.Apex.SOA}, c.add...)
+	return c
+}
+
+// rrsetKey identifies an RRset.
+type rrsetKey struct {
+	name  string
+	qtype uint16
+}
+
+// rrset returns the RRset for name and qtype.
+func (z *Zone) rrset(name string, qtype uint16) []dns.RR {
+	if name == z.origin {
+		switch qtype {
+		case dns.TypeSOA:
+			return []dns.RR{z.Apex.SOA}
+		case dns.TypeNS:
+			return z.Apex.NS
+		}
+	}
+	e, ok := z.Tree.Search(name)
+	if !ok {
+		return nil
+	}
+	return e.Types(qtype)
+}
+
+// types returns the types of the RRsets that exist for name.
+func (z *Zone) types(name string) []uint16 {
+	types := []uint16{}
+	if name == z.origin {
+		types = append(types, dns.TypeSOA, dns.TypeNS)
+	}
+	e, ok := z.Tree.Search(name)
+	if !ok {
+		return types
+	}
+	seen := map[uint16]bool{}
+	for _, rr := range e.All() {
+		if t := rr.Header().Rrtype; !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// nameUsed returns true if name has any RRs in the zone.
+func (z *Zone) nameUsed(name string) bool {
+	if name == z.origin {
+		return true
+	}
+	e, ok := z.Tree.Search(name)
+	return ok && !e.Empty()
+}
+
+// remove removes rr from the zone, this also handles the NS records and signatures at the apex.
+func (z *Zone) remove(rr dns.RR) {
+	if strings.ToLower(rr.Header().Name) == z.origin {
+		switch x := rr.(type) {
+		case *dns.NS:
+			z.Apex.NS = without(z.Apex.NS, rr)
+			return
+		case *dns.RRSIG:
+			switch x.TypeCovered {
+			case dns.TypeSOA:
+				z.Apex.SIGSOA = without(z.Apex.SIGSOA, rr)
+				return
+			case dns.TypeNS:
+				z.Apex.SIGNS = without(z.Apex.SIGNS, rr)
+				return
+			}
+		}
+	}
+	z.Delete(rr)
+}
+
+// without returns rrs without the RRs that have the same rdata as rr.
+func without(rrs []dns.RR, rr dns.RR) []dns.RR {
+	w := make([]dns.RR, 0, len(rrs))
+	for _, r := range rrs {
+		if !equalRdata(r, rr) {
+			w = append(w, r)
+		}
+	}
+	return w
+}
+
+// equalRRset returns true if a and b contain the same RRs, TTLs are not compared.
+func equalRRset(a, b []dns.RR) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if equalRdata(x, y) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// equalRdata returns true if the rdata of a and b is equal. Names in the rdata are compared case insensitive.
+func equalRdata(a, b dns.RR) bool {
+	if a.Header().Rrtype != b.Header().Rrtype {
+		return false
+	}
+	return strings.EqualFold(rdata(a), rdata(b))
+}
+
+// rdata returns the rdata of rr in presentation format.
+func rdata(rr dns.RR) string { return strings.TrimPrefix(rr.String(), rr.Header().String()) }
+
+// newer returns true if serial a is newer than serial b, using serial number arithmetic (RFC 1982).
+func newer(a, b uint32) bool { return int32(a-b) > 0 }
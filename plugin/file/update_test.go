@@ -0,0 +1,369 @@
+package file
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		prereq  []string // RRs for the prerequisite section, the class is part of the RR.
+		update  []string // RRs for the update section.
+		rcode   int
+		qname   string // After the update lookup qname/qtype.
+		qtype   uint16
+		answers int
+	}{
+		// Add a name.
+		{nil, []string{"b.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeSuccess, "b.miek.nl.", dns.TypeA, 1},
+		// Add a TXT RRset, twice the same RR results in one RR.
+		{nil, []string{`b.miek.nl. 3600 IN TXT "hello"`, `b.miek.nl. 3600 IN TXT "hello"`}, dns.RcodeSuccess, "b.miek.nl.", dns.TypeTXT, 1},
+		// Name is in use.
+		{[]string{"a.miek.nl. 0 ANY ANY"}, []string{"a.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeSuccess, "a.miek.nl.", dns.TypeA, 2},
+		{[]string{"b.miek.nl. 0 ANY ANY"}, []string{"b.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeNameError, "b.miek.nl.", dns.TypeA, 0},
+		// Name is not in use.
+		{[]string{"a.miek.nl. 0 NONE ANY"}, []string{"a.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeYXDomain, "a.miek.nl.", dns.TypeA, 1},
+		// RRset exists (value independent).
+		{[]string{"a.miek.nl. 0 ANY MX"}, []string{"a.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeNXRrset, "a.miek.nl.", dns.TypeA, 1},
+		// RRset does not exist.
+		{[]string{"a.miek.nl. 0 NONE AAAA"}, []string{"a.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeYXRrset, "a.miek.nl.", dns.TypeA, 1},
+		// RRset exists (value dependent).
+		{[]string{"a.miek.nl. 0 IN A 139.162.196.78"}, []string{"a.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeSuccess, "a.miek.nl.", dns.TypeA, 2},
+		{[]string{"a.miek.nl. 0 IN A 127.0.0.1"}, []string{"a.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeNXRrset, "a.miek.nl.", dns.TypeA, 1},
+		// Delete an RRset.
+		{nil, []string{"a.miek.nl. 0 ANY AAAA"}, dns.RcodeSuccess, "a.miek.nl.", dns.TypeAAAA, 0},
+		// Delete all RRsets of a name.
+		{nil, []string{"www.miek.nl. 0 ANY ANY"}, dns.RcodeSuccess, "www.miek.nl.", dns.TypeCNAME, 0},
+		// Delete an RR.
+		{nil, []string{"a.miek.nl. 0 NONE A 139.162.196.78"}, dns.RcodeSuccess, "a.miek.nl.", dns.TypeAAAA, 1},
+		{nil, []string{"miek.nl. 0 NONE NS linode.atoom.net."}, dns.RcodeSuccess, "miek.nl.", dns.TypeNS, 3},
+		// The SOA and apex NS RRsets can't be deleted.
+		{nil, []string{"miek.nl. 0 ANY NS", "miek.nl. 0 ANY SOA"}, dns.RcodeSuccess, "miek.nl.", dns.TypeNS, 4},
+		{nil, []string{"miek.nl. 0 ANY ANY"}, dns.RcodeSuccess, "miek.nl.", dns.TypeSOA, 1},
+		// No data next to a CNAME.
+		{nil, []string{"www.miek.nl. 3600 IN A 127.0.0.1"}, dns.RcodeSuccess, "www.miek.nl.", dns.TypeCNAME, 1},
+		// Not in the zone.
+		{nil, []string{"example.org. 3600 IN A 127.0.0.1"}, dns.RcodeNotZone, "miek.nl.", dns.TypeA, 1},
+		{[]string{"example.org. 0 ANY ANY"}, nil, dns.RcodeNotZone, "miek.nl.", dns.TypeA, 1},
+		// Malformed.
+		{nil, []string{"a.miek.nl. 3600 ANY A"}, dns.RcodeFormatError, "a.miek.nl.", dns.TypeA, 1},
+		{nil, []string{"a.miek.nl. 3600 IN AXFR"}, dns.RcodeFormatError, "a.miek.nl.", dns.TypeA, 1},
+		{[]string{"a.miek.nl. 3600 ANY ANY"}, nil, dns.RcodeFormatError, "a.miek.nl.", dns.TypeA, 1},
+	}
+
+	for i, tc := range tests {
+		z := newUpdateZone(t)
+		serial := z.Apex.SOA.Serial
+
+		m := new(dns.Msg)
+		m.SetUpdate("miek.nl.")
+		m.Answer = newRRs(t, tc.prereq)
+		m.Ns = newRRs(t, tc.update)
+
+		if rcode := serveUpdate(z, m, &test.ResponseWriter{}); rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+			continue
+		}
+
+		state := request.Request{Req: new(dns.Msg), W: &test.ResponseWriter{}}
+		state.Req.SetQuestion(tc.qname, tc.qtype)
+		answer, _, _, _ := z.Lookup(state, tc.qname)
+		if len(answer) != tc.answers {
+			t.Errorf("Test %d: expected %d answers for %s/%s, got %d", i, tc.answers, tc.qname, dns.TypeToString[tc.qtype], len(answer))
+		}
+
+		if tc.rcode != dns.RcodeSuccess && z.Apex.SOA.Serial != serial {
+			t.Errorf("Test %d: expected serial to stay at %d, got %d", i, serial, z.Apex.SOA.Serial)
+		}
+	}
+}
+
+func TestUpdateSerial(t *testing.T) {
+	z := newUpdateZone(t)
+	serial := z.Apex.SOA.Serial
+
+	m := new(dns.Msg)
+	m.SetUpdate("miek.nl.")
+	m.Ns = newRRs(t, []string{"b.miek.nl. 3600 IN A 127.0.0.1"})
+	serveUpdate(z, m, &test.ResponseWriter{})
+	if z.Apex.SOA.Serial != serial+1 {
+		t.Errorf("Expected serial %d, got %d", serial+1, z.Apex.SOA.Serial)
+	}
+
+	// No changes, no new serial.
+	serveUpdate(z, m, &test.ResponseWriter{})
+	if z.Apex.SOA.Serial != serial+1 {
+		t.Errorf("Expected serial %d, got %d", serial+1, z.Apex.SOA.Serial)
+	}
+
+	// An update with a newer SOA sets the serial.
+	m.Ns = newRRs(t, []string{"miek.nl. 1800 IN SOA linode.atoom.net. miek.miek.nl. 1282640000 14400 3600 604800 14400"})
+	serveUpdate(z, m, &test.ResponseWriter{})
+	if z.Apex.SOA.Serial != 1282640000 {
+		t.Errorf("Expected serial %d, got %d", 1282640000, z.Apex.SOA.Serial)
+	}
+
+	// An older one is ignored.
+	m.Ns = newRRs(t, []string{"miek.nl. 1800 IN SOA linode.atoom.net. miek.miek.nl. 1 14400 3600 604800 14400"})
+	serveUpdate(z, m, &test.ResponseWriter{})
+	if z.Apex.SOA.Serial != 1282640000 {
+		t.Errorf("Expected serial %d, got %d", 1282640000, z.Apex.SOA.Serial)
+	}
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	tests := []struct {
+		policy []string
+		tsig   string // Name of the key the update is signed with.
+		w      dns.ResponseWriter
+		rcode  int
+	}{
+		{[]string{"net", "10.240.0.0/16"}, "", &test.ResponseWriter{}, dns.RcodeSuccess},
+		{[]string{"net", "10.240.0.1"}, "", &test.ResponseWriter{}, dns.RcodeSuccess},
+		{[]string{"net", "192.168.0.0/16"}, "", &test.ResponseWriter{}, dns.RcodeRefused},
+		{[]string{"net", "10.240.0.0/16"}, "", &test.ResponseWriter6{}, dns.RcodeRefused},
+		{[]string{"key", "update.miek.nl."}, "", &test.ResponseWriter{}, dns.RcodeRefused},
+		{[]string{"key", "update.miek.nl."}, "update.miek.nl.", &test.ResponseWriter{}, dns.RcodeSuccess},
+		{[]string{"key", "other.miek.nl."}, "update.miek.nl.", &test.ResponseWriter{}, dns.RcodeRefused},
+		{[]string{"key", "update.miek.nl.", "net", "10.240.0.0/16"}, "update.miek.nl.", &test.ResponseWriter{}, dns.RcodeSuccess},
+		{[]string{"key", "update.miek.nl.", "net", "192.168.0.0/16"}, "update.miek.nl.", &test.ResponseWriter{}, dns.RcodeRefused},
+		{[]string{"key", "update.miek.nl."}, "update.miek.nl.", &badTsigWriter{}, dns.RcodeNotAuth},
+		{nil, "", &test.ResponseWriter{}, dns.RcodeRefused},
+		// Signed with a key the server has no secret for, so it wasn't verified.
+		{[]string{"key", "update.miek.nl."}, "forged.miek.nl.", &test.ResponseWriter{}, dns.RcodeNotAuth},
+		{[]string{"net", "10.240.0.0/16"}, "forged.miek.nl.", &test.ResponseWriter{}, dns.RcodeNotAuth},
+	}
+
+	keys := updateKeys(t)
+	for i, tc := range tests {
+		z := newUpdateZone(t)
+		z.Updates = nil
+		if tc.policy != nil {
+			p, err := ParseUpdatePolicy(tc.policy, keys)
+			if err != nil {
+				t.Fatalf("Test %d: failed to parse policy: %s", i, err)
+			}
+			z.Updates = p
+		}
+
+		m := new(dns.Msg)
+		m.SetUpdate("miek.nl.")
+		m.Ns = newRRs(t, []string{"b.miek.nl. 3600 IN A 127.0.0.1"})
+		if tc.tsig != "" {
+			m.SetTsig(tc.tsig, dns.HmacSHA256, 300, 0)
+		}
+
+		if rcode := serveUpdate(z, m, tc.w); rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+		}
+	}
+}
+
+func TestParseUpdatePolicy(t *testing.T) {
+	tests := []struct {
+		args      string
+		shouldErr bool
+	}{
+		{"key update.miek.nl.", false},
+		{"net 10.0.0.0/8 ::1", false},
+		{"key a.miek.nl b.miek.nl net 10.0.0.0/8", false},
+		{"", true},
+		{"key", true},
+		{"update.miek.nl.", true},
+		{"net 10.0.0.0/33", true},
+		{"net miek.nl", true},
+		{"key unknown.miek.nl.", true},
+	}
+	keys := updateKeys(t)
+	keys["a.miek.nl."] = keys["update.miek.nl."]
+	keys["b.miek.nl."] = keys["update.miek.nl."]
+	for i, tc := range tests {
+		_, err := ParseUpdatePolicy(strings.Fields(tc.args), keys)
+		if tc.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error for %q, got none", i, tc.args)
+		}
+		if !tc.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error for %q, got %s", i, tc.args, err)
+		}
+	}
+}
+
+func TestUpdateJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	z := newUpdateZone(t)
+	z.Journal = filepath.Join(dir, "db.miek.nl"+JournalExt)
+
+	m := new(dns.Msg)
+	m.SetUpdate("miek.nl.")
+	m.Ns = newRRs(t, []string{"b.miek.nl. 3600 IN A 127.0.0.1", "a.miek.nl. 0 ANY AAAA"})
+	serveUpdate(z, m, &test.ResponseWriter{})
+	m.Ns = newRRs(t, []string{`b.miek.nl. 3600 IN TXT "hello"`})
+	serveUpdate(z, m, &test.ResponseWriter{})
+
+	// Load the zone again and replay the journal.
+	z1 := newUpdateZone(t)
+	z1.Journal = z.Journal
+	if err := z1.ReplayJournal(); err != nil {
+		t.Fatalf("Failed to replay journal: %s", err)
+	}
+	if z1.Apex.SOA.Serial != z.Apex.SOA.Serial {
+		t.Errorf("Expected serial %d after replay, got %d", z.Apex.SOA.Serial, z1.Apex.SOA.Serial)
+	}
+	if a, b := len(z.All()), len(z1.All()); a != b {
+		t.Errorf("Expected %d records after replay, got %d", a, b)
+	}
+	if len(z1.rrset("a.miek.nl.", dns.TypeAAAA)) != 0 {
+		t.Errorf("Expected a.miek.nl./AAAA to be deleted after replay")
+	}
+
+	// Replaying again is a noop.
+	if err := z1.ReplayJournal(); err != nil {
+		t.Fatalf("Failed to replay journal: %s", err)
+	}
+	if z1.Apex.SOA.Serial != z.Apex.SOA.Serial {
+		t.Errorf("Expected serial %d after second replay, got %d", z.Apex.SOA.Serial, z1.Apex.SOA.Serial)
+	}
+}
+
+func TestCompactJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	z := newUpdateZone(t)
+	z.Journal = filepath.Join(dir, "db.miek.nl"+JournalExt)
+	serial := z.Apex.SOA.Serial
+
+	m := new(dns.Msg)
+	m.SetUpdate("miek.nl.")
+	m.Ns = newRRs(t, []string{"b.miek.nl. 3600 IN A 127.0.0.1"})
+	serveUpdate(z, m, &test.ResponseWriter{})
+	m.Ns = newRRs(t, []string{`b.miek.nl. 3600 IN TXT "hello"`})
+	serveUpdate(z, m, &test.ResponseWriter{})
+
+	// Only the second update is newer than the first.
+	if err := compactJournal(z.Journal, serial+1); err != nil {
+		t.Fatalf("Failed to compact journal: %s", err)
+	}
+	z1 := newUpdateZone(t)
+	z1.Journal = z.Journal
+	if err := z1.ReplayJournal(); err != nil {
+		t.Fatalf("Failed to replay journal: %s", err)
+	}
+	if len(z1.rrset("b.miek.nl.", dns.TypeA)) != 0 {
+		t.Errorf("Expected b.miek.nl./A not to be replayed")
+	}
+	if len(z1.rrset("b.miek.nl.", dns.TypeTXT)) != 1 {
+		t.Errorf("Expected b.miek.nl./TXT to be replayed")
+	}
+}
+
+func TestUpdatePersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "db.miek.nl")
+	if err := ioutil.WriteFile(name, []byte(dbMiekNL), 0644); err != nil {
+		t.Fatal(err)
+	}
+	z := newUpdateZone(t)
+	z.file = name
+	z.Persist = true
+	z.Journal = name + JournalExt
+
+	m := new(dns.Msg)
+	m.SetUpdate("miek.nl.")
+	m.Ns = newRRs(t, []string{"b.miek.nl. 3600 IN A 127.0.0.1"})
+	serveUpdate(z, m, &test.ResponseWriter{})
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	z1, err := Parse(f, "miek.nl.", name, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse written zone: %s", err)
+	}
+	if z1.Apex.SOA.Serial != z.Apex.SOA.Serial {
+		t.Errorf("Expected serial %d in written zone, got %d", z.Apex.SOA.Serial, z1.Apex.SOA.Serial)
+	}
+	if len(z1.rrset("b.miek.nl.", dns.TypeA)) != 1 {
+		t.Errorf("Expected b.miek.nl./A in written zone")
+	}
+	if fi, err := os.Stat(z.Journal); err != nil || fi.Size() != 0 {
+		t.Errorf("Expected empty journal after writing the zone")
+	}
+}
+
+func newUpdateZone(t *testing.T) *Zone {
+	z, err := Parse(strings.NewReader(dbMiekNL), "miek.nl.", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Failed to parse zone: %s", err)
+	}
+	z.Updates = &UpdatePolicy{Keys: nil, Nets: nil}
+	return z
+}
+
+// updateKeys returns the TSIG keys the server block is configured with in the tests.
+func updateKeys(t *testing.T) map[string]tsig.Key {
+	keys := map[string]tsig.Key{}
+	for _, name := range []string{"update.miek.nl.", "other.miek.nl."} {
+		k, err := tsig.New(name, "hmac-sha256", "c2VjcmV0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[k.Name] = k
+	}
+	return keys
+}
+
+func serveUpdate(z *Zone, m *dns.Msg, w dns.ResponseWriter) int {
+	rec := dnstest.NewRecorder(w)
+	z.ServeUpdate(context.TODO(), rec, m)
+	return rec.Msg.Rcode
+}
+
+func newRRs(t *testing.T, rrs []string) []dns.RR {
+	ret := []dns.RR{}
+	for _, s := range rrs {
+		// RRs without rdata, used in the prerequisite section and for deleting RRsets.
+		if f := strings.Fields(s); len(f) == 4 {
+			ttl, _ := strconv.Atoi(f[1])
+			ret = append(ret, &dns.ANY{Hdr: dns.RR_Header{Name: f[0], Ttl: uint32(ttl), Class: dns.StringToClass[f[2]], Rrtype: dns.StringToType[f[3]]}})
+			continue
+		}
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", s, err)
+		}
+		ret = append(ret, rr)
+	}
+	return ret
+}
+
+type badTsigWriter struct{ test.ResponseWriter }
+
+func (b *badTsigWriter) TsigStatus() error { return dns.ErrSig }
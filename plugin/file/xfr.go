@@ -5,12 +5,13 @@ import (
 	"fmt"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
 )
 
-// Xfr serves up an AXFR.
+// Xfr serves up an AXFR or IXFR.
 type Xfr struct {
 	*Zone
 }
@@ -25,19 +26,40 @@ func (x Xfr) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (in
 		return 0, plugin.Error(x.Name(), fmt.Errorf("xfr called with non transfer type: %d", state.QType()))
 	}
 
-	records := x.All()
-	if len(records) == 0 {
-		return dns.RcodeServerFailure, nil
+	var records []dns.RR
+	if state.QType() == dns.TypeIXFR {
+		records = x.incremental(state)
+		if len(records) == 1 || (records != nil && state.Proto() == "udp") {
+			// Up to date, or the difference sequences don't fit: reply with the current SOA, see RFC 1995, section 2.
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Authoritative = true
+			m.Answer = records[:1]
+			tsig.Sign(m, r)
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		}
+	}
+	if records == nil {
+		records = x.All()
+		if len(records) == 0 {
+			return dns.RcodeServerFailure, nil
+		}
+		records = append(records, records[0]) // add closing SOA to the end
+		log.Infof("Outgoing transfer of %d records of zone %s to %s started", len(records), x.origin, state.IP())
+	} else {
+		log.Infof("Outgoing incremental transfer of %d records of zone %s to %s started", len(records), x.origin, state.IP())
 	}
 
 	ch := make(chan *dns.Envelope)
-	defer close(ch)
+	done := make(chan struct{})
 	tr := new(dns.Transfer)
-	go tr.Out(w, r, ch)
+	go func() {
+		tr.Out(w, r, ch)
+		close(done)
+	}()
 
 	j, l := 0, 0
-	records = append(records, records[0]) // add closing SOA to the end
-	log.Infof("Outgoing transfer of %d records of zone %s to %s started", len(records), x.origin, state.IP())
 	for i, r := range records {
 		l += dns.Len(r)
 		if l > transferLength {
@@ -49,12 +71,26 @@ func (x Xfr) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (in
 	if j < len(records) {
 		ch <- &dns.Envelope{RR: records[j:]}
 	}
+	close(ch)
+	<-done // Wait until the last message is written.
 
 	w.Hijack()
 	// w.Close() // Client closes connection
 	return dns.RcodeSuccess, nil
 }
 
+// incremental returns the records for answering the IXFR in state. It returns nil when a full transfer is needed.
+func (x Xfr) incremental(state request.Request) []dns.RR {
+	if len(state.Req.Ns) == 0 {
+		return nil
+	}
+	soa, ok := state.Req.Ns[0].(*dns.SOA)
+	if !ok {
+		return nil
+	}
+	return x.ixfr(soa.Serial)
+}
+
 // Name implements the plugin.Handler interface.
 func (x Xfr) Name() string { return "xfr" }
 
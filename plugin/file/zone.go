@@ -6,8 +6,10 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/request"
 
@@ -25,12 +27,23 @@ type Zone struct {
 	TransferTo   []string
 	StartupOnce  sync.Once
 	TransferFrom []string
+	TransferKeys map[string]tsig.Key // TSIG keys for addresses in TransferTo and TransferFrom.
 	Expired      *bool
 
 	NoReload       bool
 	reloadMu       sync.RWMutex
 	reloadShutdown chan bool
 	Upstream       upstream.Upstream // Upstream for looking up names during the resolution process
+
+	Updates  *UpdatePolicy // Who may dynamically update the zone, nil if updates are not allowed.
+	Persist  bool          // Write dynamic updates back to the zone file.
+	Journal  string        // File dynamic updates are appended to, empty if there is no journal.
+	updateMu sync.Mutex    // Serializes dynamic updates.
+
+	history []*change // Recent changes of the zone, oldest first, used for IXFR.
+
+	Cache       string    // File a secondary zone is written to after each transfer, and loaded from at startup.
+	transferred time.Time // When the zone was last transferred, or found up to date.
 }
 
 // Apex contains the apex records of a zone: SOA, NS and their potential signatures.
@@ -61,6 +74,7 @@ func (z *Zone) Copy() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferTo = z.TransferTo
 	z1.TransferFrom = z.TransferFrom
+	z1.TransferKeys = z.TransferKeys
 	z1.Expired = z.Expired
 
 	z1.Apex = z.Apex
@@ -72,6 +86,7 @@ func (z *Zone) CopyWithoutApex() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferTo = z.TransferTo
 	z1.TransferFrom = z.TransferFrom
+	z1.TransferKeys = z.TransferKeys
 	z1.Expired = z.Expired
 
 	return z1
@@ -139,18 +154,21 @@ func (z *Zone) SetFile(path string) {
 }
 
 // TransferAllowed checks if incoming request for transferring the zone is allowed according to the ACLs.
+// If a TSIG key is configured for the matching address, the request must be signed with that key.
 func (z *Zone) TransferAllowed(state request.Request) bool {
 	for _, t := range z.TransferTo {
-		if t == "*" {
-			return true
-		}
-		// If remote IP matches we accept.
-		remote := state.IP()
-		to, _, err := net.SplitHostPort(t)
-		if err != nil {
-			continue
+		if t != "*" {
+			// If remote IP matches we accept.
+			remote := state.IP()
+			to, _, err := net.SplitHostPort(t)
+			if err != nil {
+				continue
+			}
+			if to != remote {
+				continue
+			}
 		}
-		if to == remote {
+		if z.signedBy(t, state) {
 			return true
 		}
 	}
@@ -158,14 +176,38 @@ func (z *Zone) TransferAllowed(state request.Request) bool {
 	return false
 }
 
+// signedBy returns true if no TSIG key is configured for addr or when state is signed with that key.
+func (z *Zone) signedBy(addr string, state request.Request) bool {
+	k, ok := z.TransferKeys[addr]
+	if !ok {
+		return true
+	}
+	return tsig.Verified(state.W, state.Req, k) == k.Name
+}
+
+// sign signs m with the TSIG key configured for addr and returns the secrets the client needs for
+// verifying the reply. If there is no such key, m is left untouched and nil is returned.
+func (z *Zone) sign(addr string, m *dns.Msg) map[string]string {
+	k, ok := z.TransferKeys[addr]
+	if !ok {
+		return nil
+	}
+	k.SetTsig(m)
+	return k.Secrets()
+}
+
 // All returns all records from the zone, the first record will be the SOA record,
 // otionally followed by all RRSIG(SOA)s.
 func (z *Zone) All() []dns.RR {
-	if !z.NoReload {
+	if z.mutable() {
 		z.reloadMu.RLock()
 		defer z.reloadMu.RUnlock()
 	}
+	return z.all()
+}
 
+// all returns all records from the zone, see All. The caller must hold reloadMu.
+func (z *Zone) all() []dns.RR {
 	records := []dns.RR{}
 	allNodes := z.Tree.All()
 	for _, a := range allNodes {
@@ -183,6 +225,10 @@ func (z *Zone) All() []dns.RR {
 	return append([]dns.RR{z.Apex.SOA}, records...)
 }
 
+// mutable returns true when the zone's contents may change while it is being served, either by
+// reloading the zone file or by dynamic updates.
+func (z *Zone) mutable() bool { return !z.NoReload || z.Updates != nil }
+
 // Print prints the zone's tree to stdout.
 func (z *Zone) Print() {
 	z.Tree.Print()
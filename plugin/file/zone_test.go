@@ -1,6 +1,14 @@
 package file
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/tsig"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
 
 func TestNameFromRight(t *testing.T) {
 	z := NewZone("example.org.", "stdin")
@@ -28,3 +36,41 @@ func TestNameFromRight(t *testing.T) {
 		}
 	}
 }
+
+func TestTransferAllowed(t *testing.T) {
+	k := tsig.Key{Name: "xfr.example.org.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"}
+
+	tests := []struct {
+		to       []string
+		keys     map[string]tsig.Key
+		signed   bool
+		expected bool
+	}{
+		{[]string{"*"}, nil, false, true},
+		{[]string{"10.240.0.1:53"}, nil, false, true},
+		{[]string{"10.240.0.2:53"}, nil, false, false},
+		{[]string{"*"}, map[string]tsig.Key{"*": k}, false, false},
+		{[]string{"*"}, map[string]tsig.Key{"*": k}, true, true},
+		{[]string{"10.240.0.1:53"}, map[string]tsig.Key{"10.240.0.1:53": k}, false, false},
+		{[]string{"10.240.0.1:53"}, map[string]tsig.Key{"10.240.0.1:53": k}, true, true},
+		{[]string{"10.240.0.2:53"}, map[string]tsig.Key{"10.240.0.2:53": k}, true, false},
+		// A signed request is allowed from an address without a key.
+		{[]string{"10.240.0.1:53", "*"}, map[string]tsig.Key{"*": k}, false, true},
+	}
+
+	for i, tc := range tests {
+		z := NewZone("example.org.", "stdin")
+		z.TransferTo = tc.to
+		z.TransferKeys = tc.keys
+
+		m := new(dns.Msg)
+		m.SetAxfr("example.org.")
+		if tc.signed {
+			k.SetTsig(m)
+		}
+		state := request.Request{W: &test.ResponseWriter{}, Req: m}
+		if got := z.TransferAllowed(state); got != tc.expected {
+			t.Errorf("Test %d: expected %t, got %t", i, tc.expected, got)
+		}
+	}
+}
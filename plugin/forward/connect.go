@@ -75,10 +75,15 @@ func (p *Proxy) readTimeout() time.Duration {
 
 func (p *Proxy) updateRtt(newRtt time.Duration) {
 	averageTimeout(&p.avgRtt, newRtt, cumulativeAvgWeight)
+	UpstreamRtt.WithLabelValues(p.addr).Set(time.Duration(atomic.LoadInt64(&p.avgRtt)).Seconds())
 }
 
 // Connect selects an upstream, sends the request and waits for a response.
 func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options) (*dns.Msg, error) {
+	if p.ex != nil {
+		return p.connectExchanger(ctx, state)
+	}
+
 	start := time.Now()
 
 	proto := ""
@@ -127,6 +132,13 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 
 	p.transport.Yield(conn)
 
+	p.countResponse(ret, start)
+
+	return ret, nil
+}
+
+// countResponse updates the metrics for a response from the upstream to a request sent at start.
+func (p *Proxy) countResponse(ret *dns.Msg, start time.Time) {
 	rc, ok := dns.RcodeToString[ret.Rcode]
 	if !ok {
 		rc = strconv.Itoa(ret.Rcode)
@@ -135,8 +147,6 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 	RequestCount.WithLabelValues(p.addr).Add(1)
 	RcodeCount.WithLabelValues(rc, p.addr).Add(1)
 	RequestDuration.WithLabelValues(p.addr).Observe(time.Since(start).Seconds())
-
-	return ret, nil
 }
 
 const cumulativeAvgWeight = 4
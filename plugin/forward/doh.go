@@ -0,0 +1,148 @@
+package forward
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/http2"
+)
+
+// dohTransport sends DNS messages to a DNS-over-HTTPS (RFC 8484) upstream. Connections are cached
+// by the HTTP client; with HTTP/2 all queries are multiplexed over a single connection.
+type dohTransport struct {
+	url       *url.URL
+	method    string
+	expire    time.Duration
+	tlsConfig *tls.Config
+
+	client *http.Client
+}
+
+func newDoHTransport(addr string) *dohTransport {
+	u, err := url.Parse(addr)
+	if err != nil { // Can't happen, the URL is checked when parsing the configuration.
+		u = &url.URL{Scheme: transport.HTTPS, Host: addr, Path: doh.Path}
+	}
+	t := &dohTransport{url: u, method: http.MethodPost, expire: defaultExpire, tlsConfig: new(tls.Config)}
+	t.configure()
+	return t
+}
+
+// configure (re)creates the HTTP client with the current settings.
+func (t *dohTransport) configure() {
+	tr := &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: maxDialTimeout}).DialContext,
+		TLSClientConfig:     t.tlsConfig.Clone(),
+		TLSHandshakeTimeout: maxTimeout,
+		IdleConnTimeout:     t.expire,
+		MaxIdleConnsPerHost: dohMaxIdleConns,
+	}
+	// A custom TLS config disables HTTP/2 in the HTTP client, enable it explicitly.
+	if err := http2.ConfigureTransport(tr); err != nil {
+		log.Warningf("Failed to enable HTTP/2 for %s: %s", t.url, err)
+	}
+	t.client = &http.Client{Transport: tr}
+}
+
+// SetTLSConfig sets the TLS config used for connecting to the upstream.
+func (t *dohTransport) SetTLSConfig(cfg *tls.Config) {
+	t.tlsConfig = cfg
+	t.configure()
+}
+
+// SetExpire sets the time after which idle connections are closed.
+func (t *dohTransport) SetExpire(expire time.Duration) {
+	t.expire = expire
+	t.configure()
+}
+
+// SetMethod sets the HTTP method, either GET or POST, used for sending queries.
+func (t *dohTransport) SetMethod(method string) { t.method = method }
+
+// Stop closes all idle connections.
+func (t *dohTransport) Stop() {
+	if tr, ok := t.client.Transport.(*http.Transport); ok {
+		tr.CloseIdleConnections()
+	}
+}
+
+// exchange sends m to the upstream and returns the reply.
+func (t *dohTransport) exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	// Use a message ID of 0 to make the requests cache friendly, see RFC 8484, section 4.1.
+	m1 := m.Copy()
+	m1.Id = 0
+	buf, err := m1.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var req *http.Request
+	switch t.method {
+	case http.MethodGet:
+		u := *t.url
+		q := u.Query()
+		q.Set("dns", base64.RawURLEncoding.EncodeToString(buf))
+		u.RawQuery = q.Encode()
+		req, err = http.NewRequest(http.MethodGet, u.String(), nil)
+	default:
+		req, err = http.NewRequest(http.MethodPost, t.url.String(), bytes.NewReader(buf))
+		if req != nil {
+			req.Header.Set("content-type", doh.MimeType)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("accept", doh.MimeType)
+
+	ctx, cancel := context.WithTimeout(ctx, maxTimeout)
+	defer cancel()
+
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status from %s: %s", t.url, resp.Status)
+	}
+
+	ret, err := doh.ResponseToMsg(resp)
+	if err != nil {
+		return nil, err
+	}
+	ret.Id = m.Id
+	return ret, nil
+}
+
+// dohURL checks if s is a valid URL for a DNS-over-HTTPS upstream and returns it normalized. When no
+// path is given it defaults to /dns-query.
+func dohURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != transport.HTTPS || u.Host == "" {
+		return "", fmt.Errorf("not a valid DNS-over-HTTPS URL: %q", s)
+	}
+	if u.Path == "" {
+		u.Path = doh.Path
+	}
+	return u.String(), nil
+}
+
+const dohMaxIdleConns = 10
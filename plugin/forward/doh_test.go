@@ -0,0 +1,137 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func newDoHServer(method *string) *httptest.Server {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*method = r.Method
+		m, err := doh.RequestToMsg(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ret := new(dns.Msg)
+		ret.SetReply(m)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		buf, _ := ret.Pack()
+		w.Header().Set("content-type", doh.MimeType)
+		w.Write(buf)
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	return s
+}
+
+func TestDoH(t *testing.T) {
+	for _, m := range []string{http.MethodPost, http.MethodGet} {
+		method := ""
+		s := newDoHServer(&method)
+
+		p := NewProxy(strings.TrimPrefix(s.URL, "https://")+doh.Path, transport.HTTPS)
+		p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+		p.ex.(*dohTransport).SetMethod(m)
+
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		state := request.Request{W: &test.ResponseWriter{}, Req: req}
+
+		ret, err := p.Connect(context.TODO(), state, options{})
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %s", m, err)
+		}
+		if ret.Id != req.Id {
+			t.Errorf("Expected ID %d, got %d", req.Id, ret.Id)
+		}
+		if len(ret.Answer) != 1 {
+			t.Errorf("Expected 1 answer for %s, got %d", m, len(ret.Answer))
+		}
+		if method != m {
+			t.Errorf("Expected method %s, got %s", m, method)
+		}
+		s.Close()
+	}
+}
+
+func TestDoHHealth(t *testing.T) {
+	fail := uint32(0)
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadUint32(&fail) == 1 {
+			http.Error(w, "broken", http.StatusInternalServerError)
+			return
+		}
+		m, _ := doh.RequestToMsg(r)
+		ret := new(dns.Msg)
+		ret.SetReply(m)
+		buf, _ := ret.Pack()
+		w.Header().Set("content-type", doh.MimeType)
+		w.Write(buf)
+	}))
+	defer s.Close()
+
+	p := NewProxy(strings.TrimPrefix(s.URL, "https://"), transport.HTTPS)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected healthy upstream, got %s", err)
+	}
+	atomic.StoreUint32(&fail, 1)
+	if err := p.health.Check(p); err == nil {
+		t.Errorf("Expected unhealthy upstream")
+	}
+	if atomic.LoadUint32(&p.fails) != 1 {
+		t.Errorf("Expected 1 failure, got %d", atomic.LoadUint32(&p.fails))
+	}
+}
+
+func TestSetupDoH(t *testing.T) {
+	tests := []struct {
+		input        string
+		shouldErr    bool
+		expectedAddr string
+		expectedMeth string
+	}{
+		{"forward . https://dns.example.org", false, "https://dns.example.org/dns-query", http.MethodPost},
+		{"forward . https://9.9.9.9/resolve", false, "https://9.9.9.9/resolve", http.MethodPost},
+		{"forward . https://dns.example.org {\ndoh_method get\n}\n", false, "https://dns.example.org/dns-query", http.MethodGet},
+		{"forward . https:///dns-query", true, "", ""},
+		{"forward . https://dns.example.org {\ndoh_method put\n}\n", true, "", ""},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		f, err := parseForward(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		p := f.proxies[0]
+		if p.addr != tc.expectedAddr {
+			t.Errorf("Test %d: expected address %s, got %s", i, tc.expectedAddr, p.addr)
+		}
+		if p.ex.(*dohTransport).method != tc.expectedMeth {
+			t.Errorf("Test %d: expected method %s, got %s", i, tc.expectedMeth, p.ex.(*dohTransport).method)
+		}
+	}
+}
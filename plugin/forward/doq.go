@@ -0,0 +1,119 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/doq"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// doqTransport sends DNS messages to a DNS-over-QUIC (RFC 9250) upstream. All queries share a single
+// connection, each query is sent on its own stream. The connection is redialed when it is closed.
+type doqTransport struct {
+	addr string
+
+	mu        sync.Mutex // protects the fields below.
+	tlsConfig *tls.Config
+	expire    time.Duration
+	conn      *quic.Conn
+}
+
+func newDoQTransport(addr string) *doqTransport {
+	t := &doqTransport{addr: addr, expire: defaultExpire}
+	t.SetTLSConfig(new(tls.Config))
+	return t
+}
+
+// SetTLSConfig sets the TLS config used for connecting to the upstream.
+func (t *doqTransport) SetTLSConfig(cfg *tls.Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tlsConfig = cfg.Clone()
+	t.tlsConfig.NextProtos = []string{doq.NextProto}
+	t.tlsConfig.MinVersion = tls.VersionTLS13
+	t.close()
+}
+
+// SetExpire sets the time after which an idle connection is closed.
+func (t *doqTransport) SetExpire(expire time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire = expire
+	t.close()
+}
+
+// Stop closes the connection.
+func (t *doqTransport) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.close()
+}
+
+// close closes the connection, t.mu must be held.
+func (t *doqTransport) close() {
+	if t.conn != nil {
+		t.conn.CloseWithError(doq.NoError, "")
+		t.conn = nil
+	}
+}
+
+// dial returns the connection to the upstream. A new connection is dialed when there is none, or
+// when the cached one is closed.
+func (t *doqTransport) dial(ctx context.Context) (*quic.Conn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn != nil {
+		select {
+		case <-t.conn.Context().Done():
+			t.conn = nil
+		default:
+			return t.conn, nil
+		}
+	}
+
+	conn, err := quic.DialAddr(ctx, t.addr, t.tlsConfig, &quic.Config{MaxIdleTimeout: t.expire})
+	if err != nil {
+		return nil, err
+	}
+	t.conn = conn
+	return conn, nil
+}
+
+// exchange sends m to the upstream and returns the reply.
+func (t *doqTransport) exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(ctx, maxTimeout)
+	defer cancel()
+
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+
+	if err := doq.Write(stream, m); err != nil {
+		stream.CancelRead(doq.InternalError)
+		stream.CancelWrite(doq.InternalError)
+		return nil, err
+	}
+	// Closing the send side tells the upstream there are no more queries on this stream.
+	stream.Close()
+
+	ret, err := doq.Read(stream)
+	if err != nil {
+		stream.CancelRead(doq.InternalError)
+		return nil, err
+	}
+	ret.Id = m.Id
+	return ret, nil
+}
@@ -0,0 +1,142 @@
+package forward
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/doq"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// newDoQServer starts a DNS-over-QUIC server that answers every query with an A record.
+func newDoQServer(t *testing.T) *quic.Listener {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "coredns"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{doq.NextProto},
+	}
+
+	l, err := quic.ListenAddr("127.0.0.1:0", cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					stream, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					m, err := doq.Read(stream)
+					if err != nil {
+						conn.CloseWithError(doq.ProtocolError, err.Error())
+						return
+					}
+					ret := new(dns.Msg)
+					ret.SetReply(m)
+					ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+					doq.Write(stream, ret)
+					stream.Close()
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestDoQ(t *testing.T) {
+	l := newDoQServer(t)
+	defer l.Close()
+
+	p := NewProxy(l.Addr().String(), transport.QUIC)
+	p.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	defer p.ex.Stop()
+
+	// Two queries, they share the connection.
+	for i := 0; i < 2; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		req.Id = 1234
+		state := request.Request{W: &test.ResponseWriter{}, Req: req}
+
+		ret, err := p.Connect(context.TODO(), state, options{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		if ret.Id != req.Id {
+			t.Errorf("Expected ID %d, got %d", req.Id, ret.Id)
+		}
+		if len(ret.Answer) != 1 {
+			t.Errorf("Expected 1 answer, got %d", len(ret.Answer))
+		}
+	}
+
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected healthy upstream, got %s", err)
+	}
+}
+
+func TestSetupDoQ(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedAddr   string
+		expectedServer string
+	}{
+		{"forward . quic://127.0.0.1", "quic://127.0.0.1:853", ""},
+		{"forward . quic://127.0.0.1:8853 {\ntls_servername dns.example.org\n}\n", "quic://127.0.0.1:8853", "dns.example.org"},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		f, err := parseForward(c)
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		p := f.proxies[0]
+		if p.addr != tc.expectedAddr {
+			t.Errorf("Test %d: expected address %s, got %s", i, tc.expectedAddr, p.addr)
+		}
+		cfg := p.ex.(*doqTransport).tlsConfig
+		if cfg.ServerName != tc.expectedServer {
+			t.Errorf("Test %d: expected server name %q, got %q", i, tc.expectedServer, cfg.ServerName)
+		}
+		if len(cfg.NextProtos) != 1 || cfg.NextProtos[0] != doq.NextProto {
+			t.Errorf("Test %d: expected ALPN %q, got %v", i, doq.NextProto, cfg.NextProtos)
+		}
+	}
+}
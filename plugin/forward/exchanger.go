@@ -0,0 +1,40 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// exchanger sends DNS messages to an upstream over a transport that has its own connection
+// handling, i.e. DNS-over-HTTPS, gRPC and DNS-over-QUIC.
+type exchanger interface {
+	// exchange sends m to the upstream and returns the reply, the reply has the ID of m.
+	exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error)
+	// SetTLSConfig sets the TLS config used for connecting to the upstream.
+	SetTLSConfig(cfg *tls.Config)
+	// SetExpire sets the time after which idle connections are closed.
+	SetExpire(expire time.Duration)
+	// Stop closes all connections.
+	Stop()
+}
+
+// connectExchanger sends the request to the upstream with p.ex and waits for a response.
+func (p *Proxy) connectExchanger(ctx context.Context, state request.Request) (*dns.Msg, error) {
+	start := time.Now()
+
+	ret, err := p.ex.exchange(ctx, state.Req)
+	if err != nil {
+		p.updateRtt(maxTimeout)
+		return nil, err
+	}
+	p.updateRtt(time.Since(start))
+
+	p.countResponse(ret, start)
+
+	return ret, nil
+}
@@ -8,10 +8,12 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"net/http"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/debug"
+	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -32,15 +34,18 @@ type Forward struct {
 	tlsServerName string
 	maxfails      uint32
 	expire        time.Duration
+	dohMethod     string
 
 	opts options // also here for testing
 
+	table *table // conditional forwarding table, may be nil.
+
 	Next plugin.Handler
 }
 
 // New returns a new Forward.
 func New() *Forward {
-	f := &Forward{maxfails: 2, tlsConfig: new(tls.Config), expire: defaultExpire, p: new(random), from: ".", hcInterval: hcInterval}
+	f := &Forward{maxfails: 2, tlsConfig: new(tls.Config), expire: defaultExpire, dohMethod: http.MethodPost, p: new(random), from: ".", hcInterval: hcInterval}
 	return f
 }
 
@@ -64,6 +69,19 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		return plugin.NextOrFailure(f.Name(), f.Next, ctx, w, r)
 	}
 
+	if f.table != nil {
+		if route := f.table.lookup(state.Name()); route != nil {
+			TableRequestCount.WithLabelValues(route.from).Add(1)
+			if !route.match(state) {
+				return plugin.NextOrFailure(f.Name(), f.Next, ctx, w, r)
+			}
+			return route.ServeDNS(ctx, w, r)
+		}
+	}
+	if len(f.proxies) == 0 {
+		return plugin.NextOrFailure(f.Name(), f.Next, ctx, w, r)
+	}
+
 	fails := 0
 	var span, child ot.Span
 	var upstreamErr error
@@ -148,6 +166,9 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			return 0, nil
 		}
 
+		addr := proxy.addr
+		metadata.SetValueFunc(ctx, "forward/upstream", func() string { return addr })
+
 		w.WriteMsg(ret)
 		return 0, nil
 	}
@@ -180,6 +201,12 @@ func (f *Forward) isAllowedDomain(name string) bool {
 	return true
 }
 
+// From returns the base domain of the queries that are forwarded.
+func (f *Forward) From() string { return f.from }
+
+// MaxFails returns the number of subsequent failed health checks after which an upstream is down.
+func (f *Forward) MaxFails() uint32 { return f.maxfails }
+
 // ForceTCP returns if TCP is forced to be used even when the request comes in over UDP.
 func (f *Forward) ForceTCP() bool { return f.opts.forceTCP }
 
@@ -189,6 +216,24 @@ func (f *Forward) PreferUDP() bool { return f.opts.preferUDP }
 // List returns a set of proxies to be used for this client depending on the policy in f.
 func (f *Forward) List() []*Proxy { return f.p.List(f.proxies) }
 
+// Ready implements the health.Readiness interface. Forward is ready when one of its upstreams passed
+// a health check, until then each call kicks off a health check of all upstreams. Without health
+// checking (max_fails 0) it is always ready.
+func (f *Forward) Ready() bool {
+	if f.maxfails == 0 || len(f.proxies) == 0 {
+		return true
+	}
+	for _, p := range f.proxies {
+		if p.Reached() {
+			return true
+		}
+	}
+	for _, p := range f.proxies {
+		p.Healthcheck()
+	}
+	return false
+}
+
 var (
 	// ErrNoHealthy means no healthy proxies left.
 	ErrNoHealthy = errors.New("no healthy proxies")
@@ -205,6 +250,7 @@ const (
 	randomPolicy policy = iota
 	roundRobinPolicy
 	sequentialPolicy
+	lowestLatencyPolicy
 )
 
 // options holds various options that can be set.
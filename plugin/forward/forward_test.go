@@ -1,8 +1,10 @@
 package forward
 
 import (
+	"context"
 	"testing"
 
+	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/test"
@@ -67,3 +69,33 @@ func TestForwardRefused(t *testing.T) {
 		t.Errorf("Expected rcode to be %d, got %d", dns.RcodeRefused, resp.Rcode)
 	}
 }
+
+func TestForwardMetadata(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	f := New()
+	f.SetProxy(NewProxy(s.Addr, transport.DNS))
+	defer f.Close()
+
+	upstream := ""
+	md := &metadata.Metadata{Zones: []string{"."}, Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		rcode, err := f.ServeDNS(ctx, w, r)
+		if fn := metadata.ValueFunc(ctx, "forward/upstream"); fn != nil {
+			upstream = fn()
+		}
+		return rcode, err
+	})}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	md.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+	if upstream != s.Addr {
+		t.Errorf("Expected upstream %s in the metadata, got %q", s.Addr, upstream)
+	}
+}
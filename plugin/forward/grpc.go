@@ -0,0 +1,100 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/pb"
+
+	"github.com/miekg/dns"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// grpcTransport sends DNS messages to a gRPC upstream using the DnsService from the pb package. All
+// queries are multiplexed over a single connection, which is managed (and reconnected) by gRPC.
+type grpcTransport struct {
+	addr      string
+	tlsConfig *tls.Config // nil means an insecure connection.
+
+	mu     sync.Mutex // protects the fields below.
+	conn   *grpc.ClientConn
+	client pb.DnsServiceClient
+}
+
+func newGRPCTransport(addr string) *grpcTransport { return &grpcTransport{addr: addr} }
+
+// SetTLSConfig sets the TLS config used for connecting to the upstream. Without it the connection
+// is insecure.
+func (t *grpcTransport) SetTLSConfig(cfg *tls.Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tlsConfig = cfg
+	t.close()
+}
+
+// SetExpire is a noop, gRPC keeps its connection open.
+func (t *grpcTransport) SetExpire(expire time.Duration) {}
+
+// Stop closes the connection.
+func (t *grpcTransport) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.close()
+}
+
+// close closes the connection, t.mu must be held.
+func (t *grpcTransport) close() {
+	if t.conn != nil {
+		t.conn.Close()
+	}
+	t.conn, t.client = nil, nil
+}
+
+// dial returns the client for the upstream, the connection is created on first use.
+func (t *grpcTransport) dial() (pb.DnsServiceClient, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	opt := grpc.WithInsecure()
+	if t.tlsConfig != nil {
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(t.tlsConfig.Clone()))
+	}
+	conn, err := grpc.Dial(t.addr, opt)
+	if err != nil {
+		return nil, err
+	}
+	t.conn, t.client = conn, pb.NewDnsServiceClient(conn)
+	return t.client, nil
+}
+
+// exchange sends m to the upstream and returns the reply.
+func (t *grpcTransport) exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	client, err := t.dial()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maxTimeout)
+	defer cancel()
+
+	reply, err := client.Query(ctx, &pb.DnsPacket{Msg: buf})
+	if err != nil {
+		return nil, err
+	}
+	ret := new(dns.Msg)
+	if err := ret.Unpack(reply.Msg); err != nil {
+		return nil, err
+	}
+	ret.Id = m.Id
+	return ret, nil
+}
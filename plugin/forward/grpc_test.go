@@ -0,0 +1,97 @@
+package forward
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/pb"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+	"google.golang.org/grpc"
+)
+
+type grpcServer struct{}
+
+func (s grpcServer) Query(ctx context.Context, in *pb.DnsPacket) (*pb.DnsPacket, error) {
+	m := new(dns.Msg)
+	if err := m.Unpack(in.Msg); err != nil {
+		return nil, err
+	}
+	ret := new(dns.Msg)
+	ret.SetReply(m)
+	ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+	buf, err := ret.Pack()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DnsPacket{Msg: buf}, nil
+}
+
+func (s grpcServer) Watch(pb.DnsService_WatchServer) error { return nil }
+
+func TestGRPC(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterDnsServiceServer(s, grpcServer{})
+	go s.Serve(l)
+	defer s.Stop()
+
+	p := NewProxy(l.Addr().String(), transport.GRPC)
+	defer p.ex.Stop()
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	state := request.Request{W: &test.ResponseWriter{}, Req: req}
+
+	ret, err := p.Connect(context.TODO(), state, options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if ret.Id != req.Id {
+		t.Errorf("Expected ID %d, got %d", req.Id, ret.Id)
+	}
+	if len(ret.Answer) != 1 {
+		t.Errorf("Expected 1 answer, got %d", len(ret.Answer))
+	}
+
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected healthy upstream, got %s", err)
+	}
+}
+
+func TestSetupGRPC(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedAddr string
+		expectedTLS  bool
+	}{
+		{"forward . grpc://127.0.0.1", "grpc://127.0.0.1:443", false},
+		{"forward . grpc://127.0.0.1:1443", "grpc://127.0.0.1:1443", false},
+		{"forward . grpc://127.0.0.1 {\ntls\n}\n", "grpc://127.0.0.1:443", true},
+		{"forward . grpc://127.0.0.1 {\ntls_servername dns.example.org\n}\n", "grpc://127.0.0.1:443", true},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		f, err := parseForward(c)
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		p := f.proxies[0]
+		if p.addr != tc.expectedAddr {
+			t.Errorf("Test %d: expected address %s, got %s", i, tc.expectedAddr, p.addr)
+		}
+		if tls := p.ex.(*grpcTransport).tlsConfig != nil; tls != tc.expectedTLS {
+			t.Errorf("Test %d: expected TLS %t, got %t", i, tc.expectedTLS, tls)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package forward
 
 import (
+	"context"
 	"crypto/tls"
 	"sync/atomic"
 	"time"
@@ -29,6 +30,9 @@ func NewHealthChecker(trans string) HealthChecker {
 		c.WriteTimeout = 1 * time.Second
 
 		return &dnsHc{c: c}
+
+	case transport.HTTPS, transport.GRPC, transport.QUIC:
+		return &exchangeHc{}
 	}
 
 	return nil
@@ -52,6 +56,7 @@ func (h *dnsHc) Check(p *Proxy) error {
 	}
 
 	atomic.StoreUint32(&p.fails, 0)
+	atomic.StoreUint32(&p.reached, 1)
 	return nil
 }
 
@@ -70,3 +75,29 @@ func (h *dnsHc) send(addr string) error {
 
 	return err
 }
+
+// exchangeHc is a health checker for DNS-over-HTTPS, gRPC and DNS-over-QUIC endpoints. It uses the
+// proxy's own exchanger, so it shares the TLS config and the connections with it.
+type exchangeHc struct{}
+
+// SetTLSConfig is a noop, the TLS config is set on the proxy's exchanger.
+func (h *exchangeHc) SetTLSConfig(cfg *tls.Config) {}
+
+// Check is used as the up.Func in the up.Probe. Any valid DNS reply constitutes a healthy upstream.
+func (h *exchangeHc) Check(p *Proxy) error {
+	ping := new(dns.Msg)
+	ping.SetQuestion(".", dns.TypeNS)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	if _, err := p.ex.exchange(ctx, ping); err != nil {
+		HealthcheckFailureCount.WithLabelValues(p.addr).Add(1)
+		atomic.AddUint32(&p.fails, 1)
+		return err
+	}
+
+	atomic.StoreUint32(&p.fails, 0)
+	atomic.StoreUint32(&p.reached, 1)
+	return nil
+}
@@ -181,3 +181,26 @@ func TestHealthNoMaxFails(t *testing.T) {
 		t.Errorf("Expected number of health checks to be %d, got %d", expected, i1)
 	}
 }
+
+func TestReady(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	p := NewProxy(s.Addr, transport.DNS)
+	f := New()
+	f.SetProxy(p)
+	defer f.Close()
+
+	// The first call starts the health check.
+	if f.Ready() {
+		t.Errorf("Expected forward not to be ready before a health check")
+	}
+	time.Sleep(1 * time.Second)
+	if !f.Ready() {
+		t.Errorf("Expected forward to be ready after a health check")
+	}
+}
@@ -45,4 +45,16 @@ var (
 		Name:      "sockets_open",
 		Help:      "Gauge of open sockets per upstream.",
 	}, []string{"to"})
+	TableRequestCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "table_request_count_total",
+		Help:      "Counter of requests sent to each route of the forwarding table.",
+	}, []string{"zone"})
+	UpstreamRtt = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "forward",
+		Name:      "upstream_rtt_seconds",
+		Help:      "Gauge of the smoothed round trip time per upstream.",
+	}, []string{"to"})
 )
@@ -2,6 +2,7 @@ package forward
 
 import (
 	"math/rand"
+	"sort"
 	"sync/atomic"
 )
 
@@ -62,3 +63,37 @@ func (r *sequential) String() string { return "sequential" }
 func (r *sequential) List(p []*Proxy) []*Proxy {
 	return p
 }
+
+// lowestLatency is a policy that orders hosts by their smoothed round trip time, fastest first. To keep
+// the measurements of the slower hosts current, one in every exploreRatio calls puts a random other host
+// first.
+type lowestLatency struct {
+	count uint32
+}
+
+func (r *lowestLatency) String() string { return "lowest_latency" }
+
+func (r *lowestLatency) List(p []*Proxy) []*Proxy {
+	if len(p) <= 1 {
+		return p
+	}
+
+	rtts := make(map[*Proxy]int64, len(p))
+	for _, p1 := range p {
+		rtts[p1] = atomic.LoadInt64(&p1.avgRtt)
+	}
+	fastest := make([]*Proxy, len(p))
+	copy(fastest, p)
+	sort.SliceStable(fastest, func(i, j int) bool { return rtts[fastest[i]] < rtts[fastest[j]] })
+
+	if atomic.AddUint32(&r.count, 1)%exploreRatio == 0 {
+		i := 1 + rand.Intn(len(fastest)-1)
+		explore := fastest[i]
+		copy(fastest[1:i+1], fastest[:i])
+		fastest[0] = explore
+	}
+	return fastest
+}
+
+// exploreRatio is how often the lowestLatency policy tries a slower host.
+const exploreRatio = 20
@@ -0,0 +1,78 @@
+package forward
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/transport"
+)
+
+func TestLowestLatency(t *testing.T) {
+	p1 := NewProxy("10.0.0.1:53", transport.DNS)
+	p2 := NewProxy("10.0.0.2:53", transport.DNS)
+	p3 := NewProxy("10.0.0.3:53", transport.DNS)
+	p1.avgRtt = int64(300 * time.Millisecond)
+	p2.avgRtt = int64(10 * time.Millisecond)
+	p3.avgRtt = int64(100 * time.Millisecond)
+	proxies := []*Proxy{p1, p2, p3}
+
+	ll := &lowestLatency{}
+	explored := 0
+	for i := 1; i <= 2*exploreRatio; i++ {
+		list := ll.List(proxies)
+		if len(list) != 3 {
+			t.Fatalf("Expected 3 proxies, got %d", len(list))
+		}
+		if i%exploreRatio != 0 {
+			if list[0] != p2 || list[1] != p3 || list[2] != p1 {
+				t.Errorf("Call %d: expected proxies ordered by RTT, got %s %s %s", i, list[0].addr, list[1].addr, list[2].addr)
+			}
+			continue
+		}
+		if list[0] == p2 {
+			t.Errorf("Call %d: expected a slower proxy to be explored, got %s", i, list[0].addr)
+		}
+		explored++
+	}
+	if explored != 2 {
+		t.Errorf("Expected 2 explorations, got %d", explored)
+	}
+
+	// The original list must be left untouched.
+	if proxies[0] != p1 || proxies[1] != p2 || proxies[2] != p3 {
+		t.Errorf("Expected the proxy list to be unmodified")
+	}
+
+	// Empty and single proxy lists are returned as is, also when exploring.
+	for i := 1; i <= exploreRatio; i++ {
+		if list := ll.List(nil); len(list) != 0 {
+			t.Errorf("Expected no proxies, got %d", len(list))
+		}
+		if list := ll.List([]*Proxy{p1}); len(list) != 1 || list[0] != p1 {
+			t.Errorf("Expected only %s, got %v", p1.addr, list)
+		}
+	}
+}
+
+func TestLowestLatencyUpdate(t *testing.T) {
+	p1 := NewProxy("10.0.0.1:53", transport.DNS)
+	p2 := NewProxy("10.0.0.2:53", transport.DNS)
+
+	// Make p2 fast, and p1 slow, after a few exchanges p2 should be preferred.
+	for i := 0; i < 10; i++ {
+		p1.updateRtt(500 * time.Millisecond)
+		p2.updateRtt(20 * time.Millisecond)
+	}
+	ll := &lowestLatency{}
+	if list := ll.List([]*Proxy{p1, p2}); list[0] != p2 {
+		t.Errorf("Expected %s to be first, got %s", p2.addr, list[0].addr)
+	}
+
+	// p2 starts failing, its RTT decays towards maxTimeout and p1 takes over.
+	for i := 0; i < 10; i++ {
+		p2.updateRtt(maxTimeout)
+	}
+	if list := ll.List([]*Proxy{p1, p2}); list[0] != p1 {
+		t.Errorf("Expected %s to be first, got %s", p1.addr, list[0].addr)
+	}
+}
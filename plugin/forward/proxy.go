@@ -6,13 +6,15 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/pkg/up"
 )
 
 // Proxy defines an upstream host.
 type Proxy struct {
-	avgRtt int64
-	fails  uint32
+	avgRtt  int64
+	fails   uint32
+	reached uint32 // set to 1 after the first successful health check.
 
 	addr string
 
@@ -20,6 +22,9 @@ type Proxy struct {
 	expire    time.Duration
 	transport *Transport
 
+	// DNS-over-HTTPS, gRPC and DNS-over-QUIC upstreams have their own connection handling.
+	ex exchanger
+
 	// health checking
 	probe  *up.Probe
 	health HealthChecker
@@ -34,6 +39,17 @@ func NewProxy(addr, trans string) *Proxy {
 		transport: newTransport(addr),
 		avgRtt:    int64(maxTimeout / 2),
 	}
+	switch trans {
+	case transport.HTTPS:
+		p.addr = transport.HTTPS + "://" + addr
+		p.ex = newDoHTransport(p.addr)
+	case transport.GRPC:
+		p.addr = transport.GRPC + "://" + addr
+		p.ex = newGRPCTransport(addr)
+	case transport.QUIC:
+		p.addr = transport.QUIC + "://" + addr
+		p.ex = newDoQTransport(addr)
+	}
 	p.health = NewHealthChecker(trans)
 	runtime.SetFinalizer(p, (*Proxy).finalizer)
 	return p
@@ -41,12 +57,21 @@ func NewProxy(addr, trans string) *Proxy {
 
 // SetTLSConfig sets the TLS config in the lower p.transport and in the healthchecking client.
 func (p *Proxy) SetTLSConfig(cfg *tls.Config) {
+	if p.ex != nil {
+		p.ex.SetTLSConfig(cfg)
+		return
+	}
 	p.transport.SetTLSConfig(cfg)
 	p.health.SetTLSConfig(cfg)
 }
 
 // SetExpire sets the expire duration in the lower p.transport.
-func (p *Proxy) SetExpire(expire time.Duration) { p.transport.SetExpire(expire) }
+func (p *Proxy) SetExpire(expire time.Duration) {
+	p.transport.SetExpire(expire)
+	if p.ex != nil {
+		p.ex.SetExpire(expire)
+	}
+}
 
 // Healthcheck kicks of a round of health checks for this proxy.
 func (p *Proxy) Healthcheck() {
@@ -65,9 +90,23 @@ func (p *Proxy) Down(maxfails uint32) bool {
 	return fails > maxfails
 }
 
+// Addr returns the address of this proxy, prefixed with the transport for other transports than DNS.
+func (p *Proxy) Addr() string { return p.addr }
+
+// Fails returns the number of subsequent failed health checks of this proxy.
+func (p *Proxy) Fails() uint32 { return atomic.LoadUint32(&p.fails) }
+
+// Reached returns true if this proxy has passed a health check at least once.
+func (p *Proxy) Reached() bool { return atomic.LoadUint32(&p.reached) == 1 }
+
 // close stops the health checking goroutine.
-func (p *Proxy) close()     { p.probe.Stop() }
-func (p *Proxy) finalizer() { p.transport.Stop() }
+func (p *Proxy) close() { p.probe.Stop() }
+func (p *Proxy) finalizer() {
+	p.transport.Stop()
+	if p.ex != nil {
+		p.ex.Stop()
+	}
+}
 
 // start starts the proxy's healthchecking.
 func (p *Proxy) start(duration time.Duration) {
@@ -2,12 +2,16 @@ package forward
 
 import (
 	"fmt"
+	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/parse"
 	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/transport"
@@ -16,6 +20,8 @@ import (
 	"github.com/mholt/caddy/caddyfile"
 )
 
+var log = clog.NewWithPlugin("forward")
+
 func init() {
 	caddy.RegisterPlugin("forward", caddy.Plugin{
 		ServerType: "dns",
@@ -38,7 +44,7 @@ func setup(c *caddy.Controller) error {
 	})
 
 	c.OnStartup(func() error {
-		metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, HealthcheckFailureCount, SocketGauge)
+		metrics.MustRegister(c, RequestCount, RcodeCount, RequestDuration, HealthcheckFailureCount, SocketGauge, UpstreamRtt, TableRequestCount)
 		return f.OnStartup()
 	})
 
@@ -54,6 +60,9 @@ func (f *Forward) OnStartup() (err error) {
 	for _, p := range f.proxies {
 		p.start(f.hcInterval)
 	}
+	if f.table != nil {
+		f.table.start()
+	}
 	return nil
 }
 
@@ -62,6 +71,9 @@ func (f *Forward) OnShutdown() error {
 	for _, p := range f.proxies {
 		p.close()
 	}
+	if f.table != nil {
+		f.table.close()
+	}
 	return nil
 }
 
@@ -83,6 +95,15 @@ func parseForward(c *caddy.Controller) (*Forward, error) {
 		if err != nil {
 			return nil, err
 		}
+		if f.table != nil {
+			config := dnsserver.GetConfig(c)
+			if f.table.path != "" && !filepath.IsAbs(f.table.path) && config.Root != "" {
+				f.table.path = filepath.Join(config.Root, f.table.path)
+			}
+			if err := f.table.load(); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return f, nil
 }
@@ -97,13 +118,22 @@ func ParseForwardStanza(c *caddyfile.Dispenser) (*Forward, error) {
 	f.from = plugin.Host(f.from).Normalize()
 
 	to := c.RemainingArgs()
-	if len(to) == 0 {
-		return f, c.ArgErr()
-	}
 
-	toHosts, err := parse.HostPortOrFile(to...)
-	if err != nil {
-		return f, err
+	toHosts := []string{}
+	for _, host := range to {
+		if trans, _ := parse.Transport(host); trans == transport.HTTPS {
+			u, err := dohURL(host)
+			if err != nil {
+				return f, err
+			}
+			toHosts = append(toHosts, u)
+			continue
+		}
+		hosts, err := parse.HostPortOrFile(host)
+		if err != nil {
+			return f, err
+		}
+		toHosts = append(toHosts, hosts...)
 	}
 
 	transports := make([]string, len(toHosts))
@@ -114,19 +144,33 @@ func ParseForwardStanza(c *caddyfile.Dispenser) (*Forward, error) {
 		transports[i] = trans
 	}
 
+	defaultTLSConfig := f.tlsConfig
 	for c.NextBlock() {
 		if err := parseBlock(c, f); err != nil {
 			return f, err
 		}
 	}
+	if len(f.proxies) == 0 && f.table == nil {
+		return f, c.ArgErr()
+	}
 
 	if f.tlsServerName != "" {
 		f.tlsConfig.ServerName = f.tlsServerName
 	}
+	// gRPC upstreams only use TLS when it is configured.
+	grpcTLS := f.tlsConfig != defaultTLSConfig || f.tlsServerName != ""
 	for i := range f.proxies {
 		// Only set this for proxies that need it.
-		if transports[i] == transport.TLS {
+		switch transports[i] {
+		case transport.TLS, transport.HTTPS, transport.QUIC:
 			f.proxies[i].SetTLSConfig(f.tlsConfig)
+		case transport.GRPC:
+			if grpcTLS {
+				f.proxies[i].SetTLSConfig(f.tlsConfig)
+			}
+		}
+		if doh, ok := f.proxies[i].ex.(*dohTransport); ok {
+			doh.SetMethod(f.dohMethod)
 		}
 		f.proxies[i].SetExpire(f.expire)
 	}
@@ -206,6 +250,48 @@ func parseBlock(c *caddyfile.Dispenser, f *Forward) error {
 			return fmt.Errorf("expire can't be negative: %s", dur)
 		}
 		f.expire = dur
+	case "doh_method":
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		switch x := strings.ToUpper(c.Val()); x {
+		case http.MethodGet, http.MethodPost:
+			f.dohMethod = x
+		default:
+			return c.Errf("unknown DNS-over-HTTPS method '%s'", c.Val())
+		}
+	case "table":
+		if f.table != nil {
+			return c.Err("table already defined")
+		}
+		f.table = &table{}
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			f.table.path = args[0]
+		default:
+			return c.ArgErr()
+		}
+		// Inline routes, we collect the tokens here and parse them when loading the table.
+		if !c.NextArg() {
+			if f.table.path == "" {
+				return c.ArgErr()
+			}
+			return nil
+		}
+		for depth := 1; c.Next(); {
+			switch c.Val() {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+			f.table.inline = append(f.table.inline, caddyfile.Token{File: c.File(), Line: c.Line(), Text: c.Val()})
+		}
 	case "policy":
 		if !c.NextArg() {
 			return c.ArgErr()
@@ -217,6 +303,8 @@ func parseBlock(c *caddyfile.Dispenser, f *Forward) error {
 			f.p = &roundRobin{}
 		case "sequential":
 			f.p = &sequential{}
+		case "lowest_latency":
+			f.p = &lowestLatency{}
 		default:
 			return c.Errf("unknown policy '%s'", x)
 		}
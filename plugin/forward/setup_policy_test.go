@@ -18,6 +18,7 @@ func TestSetupPolicy(t *testing.T) {
 		{"forward . 127.0.0.1 {\npolicy random\n}\n", false, "random", ""},
 		{"forward . 127.0.0.1 {\npolicy round_robin\n}\n", false, "round_robin", ""},
 		{"forward . 127.0.0.1 {\npolicy sequential\n}\n", false, "sequential", ""},
+		{"forward . 127.0.0.1 {\npolicy lowest_latency\n}\n", false, "lowest_latency", ""},
 		// negative
 		{"forward . 127.0.0.1 {\npolicy random2\n}\n", true, "random", "unknown policy"},
 	}
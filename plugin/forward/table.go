@@ -0,0 +1,216 @@
+package forward
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mholt/caddy/caddyfile"
+	"github.com/miekg/dns"
+)
+
+// table is a conditional forwarding table. It maps zones to routes, each route is a Forward with its own
+// upstreams, policy, TLS settings and health checking. Queries are sent to the route with the longest
+// matching zone.
+type table struct {
+	path   string            // File with routes, re-read when it changes. May be empty.
+	inline []caddyfile.Token // Routes defined in the Corefile.
+
+	sync.RWMutex
+	routes map[string]*Forward
+	mtime  time.Time
+	size   int64
+
+	stop chan bool
+}
+
+// lookup returns the route with the longest zone that matches qname, or nil if there is none.
+func (t *table) lookup(qname string) *Forward {
+	t.RLock()
+	defer t.RUnlock()
+
+	for off, end := 0, false; !end; off, end = dns.NextLabel(qname, off) {
+		if r, ok := t.routes[qname[off:]]; ok {
+			return r
+		}
+	}
+	return t.routes["."]
+}
+
+// load parses the inline routes and the routes from the file. It should only be called when the table
+// is being setup, use reload to update an active table.
+func (t *table) load() error {
+	routes, err := t.parse()
+	if err != nil {
+		return err
+	}
+	t.Lock()
+	t.routes = routes
+	t.Unlock()
+	return nil
+}
+
+// reload re-reads the file when it has been modified. The new routes are started and the old ones stopped.
+func (t *table) reload() error {
+	if t.path == "" {
+		return nil
+	}
+	s, err := os.Stat(t.path)
+	if err != nil {
+		return err
+	}
+	t.RLock()
+	modified := !s.ModTime().Equal(t.mtime) || s.Size() != t.size
+	t.RUnlock()
+	if !modified {
+		return nil
+	}
+
+	routes, err := t.parse()
+	if err != nil {
+		return err
+	}
+	for _, r := range routes {
+		r.OnStartup()
+	}
+
+	t.Lock()
+	old := t.routes
+	t.routes = routes
+	t.Unlock()
+
+	for _, r := range old {
+		r.OnShutdown()
+	}
+	log.Infof("Reloaded forwarding table %s with %d routes", t.path, len(routes))
+	return nil
+}
+
+// parse parses the inline routes and the routes in the file. Routes from the file take precedence.
+func (t *table) parse() (map[string]*Forward, error) {
+	routes, err := parseRoutes(t.inline)
+	if err != nil {
+		return nil, err
+	}
+	if t.path == "" {
+		return routes, nil
+	}
+
+	s, err := os.Stat(t.path)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return nil, err
+	}
+	d := caddyfile.NewDispenser(t.path, bytes.NewReader(buf))
+	tokens := []caddyfile.Token{}
+	for d.Next() {
+		tokens = append(tokens, caddyfile.Token{File: d.File(), Line: d.Line(), Text: d.Val()})
+	}
+	fileRoutes, err := parseRoutes(tokens)
+	if err != nil {
+		return nil, err
+	}
+	for zone, r := range fileRoutes {
+		routes[zone] = r
+	}
+
+	t.Lock()
+	t.mtime = s.ModTime()
+	t.size = s.Size()
+	t.Unlock()
+
+	return routes, nil
+}
+
+// parseRoutes parses the routes in tokens. A route uses the syntax of a forward stanza, without the
+// directive's name: ZONE TO... [{ OPTIONS }].
+func parseRoutes(tokens []caddyfile.Token) (map[string]*Forward, error) {
+	// Prefix each route with a directive name, so we can use ParseForwardStanza.
+	stanzas := []caddyfile.Token{}
+	depth := 0
+	for i, tok := range tokens {
+		if depth == 0 && (i == 0 || tok.File != tokens[i-1].File || tok.Line > tokens[i-1].Line) {
+			stanzas = append(stanzas, caddyfile.Token{File: tok.File, Line: tok.Line, Text: "forward"})
+		}
+		switch tok.Text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+		stanzas = append(stanzas, tok)
+	}
+
+	routes := map[string]*Forward{}
+	d := caddyfile.NewDispenserTokens("", stanzas)
+	for d.Next() {
+		r, err := ParseForwardStanza(&d)
+		if err != nil {
+			return nil, err
+		}
+		if r.table != nil {
+			return nil, d.Err("a table can not be used in a route")
+		}
+		if r.Len() == 0 {
+			return nil, d.Errf("no upstreams defined for route '%s'", r.from)
+		}
+		if r.Len() > max {
+			return nil, fmt.Errorf("more than %d TOs configured for route %s: %d", max, r.from, r.Len())
+		}
+		if _, ok := routes[r.from]; ok {
+			return nil, fmt.Errorf("duplicate route for %s", r.from)
+		}
+		routes[r.from] = r
+	}
+	return routes, nil
+}
+
+// start starts the health checking of all routes and the checking of the file for changes.
+func (t *table) start() {
+	t.RLock()
+	for _, r := range t.routes {
+		r.OnStartup()
+	}
+	t.RUnlock()
+
+	if t.path == "" {
+		return
+	}
+	t.stop = make(chan bool)
+	go func() {
+		ticker := time.NewTicker(reloadInterval)
+		for {
+			select {
+			case <-t.stop:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				if err := t.reload(); err != nil {
+					log.Warningf("Failed to reload forwarding table: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// close stops all routes and the checking of the file.
+func (t *table) close() {
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+	t.RLock()
+	for _, r := range t.routes {
+		r.OnShutdown()
+	}
+	t.RUnlock()
+}
+
+// reloadInterval is how often the table's file is checked for changes.
+var reloadInterval = 5 * time.Second
@@ -0,0 +1,217 @@
+package forward
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func TestSetupTable(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		routes    int
+	}{
+		{`forward . 127.0.0.1 {
+			table {
+				example.org 127.0.0.2
+				a.example.org 127.0.0.3 127.0.0.4 {
+					policy sequential
+					max_fails 5
+				}
+			}
+		}`, false, 2},
+		{`forward . {
+			table {
+				example.org 127.0.0.2
+			}
+		}`, false, 1},
+		// negative
+		{`forward .`, true, 0},
+		{`forward . {
+			table
+		}`, true, 0},
+		{`forward . {
+			table {
+				example.org
+			}
+		}`, true, 0},
+		{`forward . {
+			table {
+				example.org 127.0.0.2
+				example.org 127.0.0.3
+			}
+		}`, true, 0},
+		{`forward . {
+			table {
+				example.org 127.0.0.2 {
+					table {
+						a.example.org 127.0.0.3
+					}
+				}
+			}
+		}`, true, 0},
+		{`forward . {
+			table /does/not/exist
+		}`, true, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		f, err := parseForward(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(f.table.routes) != tc.routes {
+			t.Errorf("Test %d: expected %d routes, got %d", i, tc.routes, len(f.table.routes))
+		}
+	}
+}
+
+func TestTableLookup(t *testing.T) {
+	c := caddy.NewTestController("dns", `forward . 127.0.0.1 {
+		table {
+			example.org 127.0.0.2
+			a.example.org 127.0.0.3 {
+				policy sequential
+			}
+		}
+	}`)
+	f, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	tests := []struct {
+		qname string
+		route string
+	}{
+		{"example.org.", "example.org."},
+		{"www.example.org.", "example.org."},
+		{"a.example.org.", "a.example.org."},
+		{"www.a.example.org.", "a.example.org."},
+		{"b.example.org.", "example.org."},
+		{"example.net.", ""},
+		{".", ""},
+	}
+	for i, tc := range tests {
+		r := f.table.lookup(tc.qname)
+		if tc.route == "" {
+			if r != nil {
+				t.Errorf("Test %d: expected no route for %s, got %s", i, tc.qname, r.from)
+			}
+			continue
+		}
+		if r == nil || r.from != tc.route {
+			t.Errorf("Test %d: expected route %s for %s, got %v", i, tc.route, tc.qname, r)
+		}
+	}
+	if p := f.table.lookup("a.example.org.").p.String(); p != "sequential" {
+		t.Errorf("Expected policy sequential for a.example.org., got %s", p)
+	}
+}
+
+func TestTableForward(t *testing.T) {
+	// dnstest.NewServer registers a global handler, so both servers use this one, which returns the
+	// port it received the query on.
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		_, port, _ := net.SplitHostPort(w.LocalAddr().String())
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.TXT(r.Question[0].Name+" IN TXT "+port))
+		w.WriteMsg(ret)
+	}
+	s1 := dnstest.NewServer(handler)
+	defer s1.Close()
+	s2 := dnstest.NewServer(handler)
+	defer s2.Close()
+	_, port1, _ := net.SplitHostPort(s1.Addr)
+	_, port2, _ := net.SplitHostPort(s2.Addr)
+
+	c := caddy.NewTestController("dns", "forward . "+s1.Addr+" {\ntable {\nexample.org "+s2.Addr+"\n}\n}\n")
+	f, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	f.OnStartup()
+	defer f.OnShutdown()
+
+	tests := []struct {
+		qname    string
+		expected string
+	}{
+		{"example.org.", port2},
+		{"www.example.org.", port2},
+		{"example.net.", port1},
+	}
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeTXT)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if x := rec.Msg.Answer[0].(*dns.TXT).Txt[0]; x != tc.expected {
+			t.Errorf("Test %d: expected port %s for %s, got %s", i, tc.expected, tc.qname, x)
+		}
+	}
+}
+
+func TestTableReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forward-table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "table")
+
+	if err := ioutil.WriteFile(name, []byte("# routes\nexample.org 127.0.0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := caddy.NewTestController("dns", "forward . {\ntable "+name+"\n}\n")
+	f, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if r := f.table.lookup("example.net."); r != nil {
+		t.Fatalf("Expected no route for example.net., got %s", r.from)
+	}
+
+	if err := ioutil.WriteFile(name, []byte("example.org 127.0.0.2\nexample.net 127.0.0.3 {\nforce_tcp\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time changes.
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(name, later, later)
+
+	if err := f.table.reload(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	defer f.OnShutdown()
+
+	r := f.table.lookup("www.example.net.")
+	if r == nil {
+		t.Fatalf("Expected route for example.net. after reload")
+	}
+	if !r.opts.forceTCP {
+		t.Errorf("Expected force_tcp to be set for example.net.")
+	}
+}
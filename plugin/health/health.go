@@ -2,6 +2,7 @@
 package health
 
 import (
+	"encoding/json"
 	"io"
 	"net"
 	"net/http"
@@ -24,6 +25,8 @@ type health struct {
 
 	// A slice of Healthers that the health plugin will poll every second for their health status.
 	h []Healther
+	// The plugins of all server blocks that implement Readiness, polled on each request to /ready.
+	r []*readyPlugin
 	sync.RWMutex
 	ok bool // ok is the global boolean indicating an all healthy plugin stack
 
@@ -58,6 +61,16 @@ func (h *health) OnStartup() error {
 		}
 		w.WriteHeader(http.StatusServiceUnavailable)
 	})
+	h.mux.HandleFunc(readyPath, func(w http.ResponseWriter, r *http.Request) {
+		notReady := h.notReady()
+		w.Header().Set("Content-Type", "application/json")
+		if len(notReady) == 0 {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(readyStatus{Ready: len(notReady) == 0, NotReady: notReady})
+	})
 
 	go func() { http.Serve(h.ln, h.mux) }()
 	go func() { h.overloaded() }()
@@ -90,7 +103,8 @@ func (h *health) OnFinalShutdown() error {
 }
 
 const (
-	ok      = "OK"
-	defAddr = ":8080"
-	path    = "/health"
+	ok        = "OK"
+	defAddr   = ":8080"
+	path      = "/health"
+	readyPath = "/ready"
 )
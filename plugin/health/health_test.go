@@ -1,6 +1,7 @@
 package health
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -75,3 +76,61 @@ func TestHealthLameduck(t *testing.T) {
 
 	h.OnFinalShutdown()
 }
+
+type testReady struct{ ready bool }
+
+func (r *testReady) Ready() bool { return r.ready }
+
+func TestReady(t *testing.T) {
+	h := newHealth(":0")
+	r := &testReady{}
+	h.r = append(h.r, &readyPlugin{name: "kubernetes", r: r}, &readyPlugin{name: "erratic", r: &testReady{ready: true}})
+
+	if err := h.OnStartup(); err != nil {
+		t.Fatalf("Unable to startup the health server: %v", err)
+	}
+	defer h.OnFinalShutdown()
+
+	go func() {
+		<-h.pollstop
+		return
+	}()
+
+	address := fmt.Sprintf("http://%s%s", h.ln.Addr().String(), readyPath)
+
+	get := func() (int, readyStatus) {
+		response, err := http.Get(address)
+		if err != nil {
+			t.Fatalf("Unable to query %s: %v", address, err)
+		}
+		defer response.Body.Close()
+		st := readyStatus{}
+		if err := json.NewDecoder(response.Body).Decode(&st); err != nil {
+			t.Fatalf("Unable to decode response body from %s: %v", address, err)
+		}
+		return response.StatusCode, st
+	}
+
+	code, st := get()
+	if code != 503 {
+		t.Errorf("Invalid status code: expecting '503', got '%d'", code)
+	}
+	if st.Ready || len(st.NotReady) != 1 || st.NotReady[0] != "kubernetes" {
+		t.Errorf("Expected kubernetes not to be ready, got %+v", st)
+	}
+
+	r.ready = true
+	code, st = get()
+	if code != 200 {
+		t.Errorf("Invalid status code: expecting '200', got '%d'", code)
+	}
+	if !st.Ready || len(st.NotReady) != 0 {
+		t.Errorf("Expected all plugins to be ready, got %+v", st)
+	}
+
+	// Once ready a plugin stays ready.
+	r.ready = false
+	if code, _ = get(); code != 200 {
+		t.Errorf("Invalid status code: expecting '200', got '%d'", code)
+	}
+}
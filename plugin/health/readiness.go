@@ -0,0 +1,51 @@
+package health
+
+import (
+	"sort"
+)
+
+// Readiness interface needs to be implemented by each plugin that needs time before it can serve
+// queries, i.e. because it loads data from somewhere. Ready is called for each request to /ready,
+// until it returns true for the first time, after that the plugin is considered ready for the
+// lifetime of the server. Like Health it should return quickly.
+type Readiness interface {
+	// Ready returns true when the plugin is ready to serve queries.
+	Ready() bool
+}
+
+// readyPlugin is a plugin that implements Readiness.
+type readyPlugin struct {
+	name  string
+	r     Readiness
+	ready bool // latched once r reported ready.
+}
+
+// readyStatus is the JSON body of a response to /ready.
+type readyStatus struct {
+	Ready    bool     `json:"ready"`
+	NotReady []string `json:"not_ready"`
+}
+
+// notReady returns the sorted names of the plugins that are not ready yet.
+func (h *health) notReady() []string {
+	h.Lock()
+	defer h.Unlock()
+
+	seen := map[string]bool{}
+	names := []string{}
+	for _, p := range h.r {
+		if p.ready {
+			continue
+		}
+		if p.r.Ready() {
+			p.ready = true
+			continue
+		}
+		if !seen[p.name] {
+			seen[p.name] = true
+			names = append(names, p.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -35,6 +35,14 @@ func setup(c *caddy.Controller) error {
 				h.h = append(h.h, x)
 			}
 		}
+		// Readiness is checked for the plugins of all server blocks.
+		for _, conf := range dnsserver.GetConfigs(c) {
+			for _, p := range conf.Handlers() {
+				if x, ok := p.(Readiness); ok {
+					h.r = append(h.r, &readyPlugin{name: p.Name(), r: x})
+				}
+			}
+		}
 		return nil
 	})
 
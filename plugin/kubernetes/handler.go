@@ -55,7 +55,9 @@ func (k Kubernetes) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.M
 		}
 		fallthrough
 	case dns.TypeAXFR, dns.TypeIXFR:
-		k.Transfer(ctx, state)
+		if rcode, err := k.Transfer(ctx, state); rcode == dns.RcodeRefused {
+			return rcode, err
+		}
 	default:
 		// Do a fake A lookup, so we can distinguish between NODATA and NXDOMAIN
 		_, err = plugin.A(&k, zone, state, nil, opt)
@@ -2,3 +2,6 @@ package kubernetes
 
 // Health implements the health.Healther interface.
 func (k *Kubernetes) Health() bool { return k.APIConn.HasSynced() }
+
+// Ready implements the health.Readiness interface.
+func (k *Kubernetes) Ready() bool { return k.APIConn.HasSynced() }
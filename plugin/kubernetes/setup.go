@@ -237,13 +237,20 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 			}
 			k8s.ttl = uint32(t)
 		case "transfer":
-			tos, froms, err := parse.Transfer(c, false)
+			tos, froms, key, err := parse.Transfer(c, false)
 			if err != nil {
 				return nil, err
 			}
 			if len(froms) != 0 {
 				return nil, c.Errf("transfer from is not supported with this plugin")
 			}
+			if key != "" {
+				k, ok := dnsserver.GetConfig(c).TsigKeys[key]
+				if !ok {
+					return nil, c.Errf("unknown TSIG key '%s'", key)
+				}
+				k8s.TransferKey = &k
+			}
 			k8s.TransferTo = tos
 		case "noendpoints":
 			if len(c.RemainingArgs()) != 0 {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -24,6 +25,9 @@ func (k *Kubernetes) MinTTL(state request.Request) uint32 { return 30 }
 
 // Transfer implements the Transferer interface.
 func (k *Kubernetes) Transfer(ctx context.Context, state request.Request) (int, error) {
+	if k.TransferKey != nil && tsig.Verified(state.W, state.Req, *k.TransferKey) != k.TransferKey.Name {
+		return dns.RcodeRefused, nil
+	}
 
 	// Get all services.
 	rrs := make(chan dns.RR)
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/test"
 	api "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -201,3 +202,32 @@ func TestEndpointsEquivalent(t *testing.T) {
 		}
 	}
 }
+
+func TestKubernetesXFRTsig(t *testing.T) {
+	k := New([]string{"cluster.local."})
+	k.APIConn = &APIConnServeTest{}
+	k.TransferTo = []string{"*"}
+	k.TransferKey = &tsig.Key{Name: "xfr.example.org.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"}
+	k.Namespaces = map[string]bool{"testns": true}
+
+	ctx := context.TODO()
+	w := dnstest.NewMultiRecorder(&test.ResponseWriter{})
+	dnsmsg := &dns.Msg{}
+	dnsmsg.SetAxfr(k.Zones[0])
+
+	rcode, _ := k.ServeDNS(ctx, w, dnsmsg)
+	if rcode != dns.RcodeRefused {
+		t.Errorf("Expected unsigned transfer to be refused, got %s", dns.RcodeToString[rcode])
+	}
+	if len(w.Msgs) != 0 {
+		t.Errorf("Expected no zone response, got %d messages", len(w.Msgs))
+	}
+
+	dnsmsg.SetTsig("xfr.example.org.", dns.HmacSHA256, 300, 0)
+	if _, err := k.ServeDNS(ctx, w, dnsmsg); err != nil {
+		t.Error(err)
+	}
+	if len(w.Msgs) == 0 {
+		t.Error("Did not get back a zone response")
+	}
+}
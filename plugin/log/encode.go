@@ -0,0 +1,197 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/metrics/vars"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// encoding is how a log entry is written.
+type encoding int
+
+const (
+	// encodingText writes the entry with the rule's format, see replacer.
+	encodingText encoding = iota
+	// encodingJSON writes the entry as a JSON object.
+	encodingJSON
+	// encodingLogfmt writes the entry as key=value pairs.
+	encodingLogfmt
+)
+
+func encodingFromString(s string) (encoding, bool) {
+	switch s {
+	case "text":
+		return encodingText, true
+	case "json":
+		return encodingJSON, true
+	case "logfmt":
+		return encodingLogfmt, true
+	}
+	return encodingText, false
+}
+
+// field is a key and a typed value in a structured log entry.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// upstreamLabel is the metadata label holding the upstream the query was sent to.
+const upstreamLabel = "forward/upstream"
+
+// fields returns the fields of the log entry for the query r and its response in rr.
+func fields(ctx context.Context, r *dns.Msg, rr *dnstest.Recorder, class response.Class) []field {
+	state := request.Request{W: rr, Req: r}
+	port, _ := strconv.Atoi(state.Port())
+
+	f := []field{
+		{"time", time.Now().Format(time.RFC3339Nano)},
+		{"remote", state.IP()},
+		{"port", port},
+		{"id", r.Id},
+		{"opcode", dns.OpcodeToString[r.Opcode]},
+		{"type", state.Type()},
+		{"class", state.Class()},
+		{"name", state.Name()},
+		{"proto", state.Proto()},
+		{"size", state.Len()},
+		{"do", state.Do()},
+		{"bufsize", state.Size()},
+		{"flags", flags(r.MsgHdr)},
+	}
+
+	rcode := dns.RcodeToString[rr.Rcode]
+	if rcode == "" {
+		rcode = strconv.Itoa(rr.Rcode)
+	}
+	f = append(f,
+		field{"rcode", rcode},
+		field{"rclass", class.String()},
+	)
+	if rr.Msg != nil {
+		f = append(f, field{"rflags", flags(rr.Msg.MsgHdr)})
+	}
+	f = append(f,
+		field{"rsize", rr.Len},
+		field{"duration", time.Since(rr.Start).Seconds()},
+	)
+
+	if server := vars.WithServer(ctx); server != "" {
+		f = append(f, field{"server", server})
+	}
+	if up := metadata.ValueFunc(ctx, upstreamLabel); up != nil {
+		f = append(f, field{"upstream", up()})
+	}
+
+	labels := metadata.Labels(ctx)
+	if len(labels) > 0 {
+		sort.Strings(labels)
+		md := make([]field, 0, len(labels))
+		for _, l := range labels {
+			if fn := metadata.ValueFunc(ctx, l); fn != nil {
+				md = append(md, field{l, fn()})
+			}
+		}
+		f = append(f, field{"metadata", md})
+	}
+	return f
+}
+
+// encodeJSON encodes the fields as a JSON object, nested fields are encoded as an object.
+func encodeJSON(b *bytes.Buffer, fields []field) {
+	b.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(f.key)
+		b.Write(k)
+		b.WriteByte(':')
+		if nested, ok := f.value.([]field); ok {
+			encodeJSON(b, nested)
+			continue
+		}
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			v = []byte("null")
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+}
+
+// encodeLogfmt encodes the fields as key=value pairs, nested fields are written with their own keys.
+func encodeLogfmt(b *bytes.Buffer, fields []field) {
+	for _, f := range fields {
+		if nested, ok := f.value.([]field); ok {
+			encodeLogfmt(b, nested)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(f.key)
+		b.WriteByte('=')
+
+		var v string
+		switch x := f.value.(type) {
+		case string:
+			v = x
+		case []string:
+			v = strings.Join(x, ",")
+		case float64:
+			v = strconv.FormatFloat(x, 'f', -1, 64)
+		default:
+			j, _ := json.Marshal(x)
+			v = string(j)
+		}
+		if v == "" || strings.ContainsAny(v, " =\"\\") || strings.IndexFunc(v, isControl) >= 0 {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(v)
+	}
+}
+
+func isControl(r rune) bool { return r < ' ' || r == 0x7f }
+
+// flags returns the flags set in h.
+func flags(h dns.MsgHdr) []string {
+	f := []string{}
+	if h.Response {
+		f = append(f, "qr")
+	}
+	if h.Authoritative {
+		f = append(f, "aa")
+	}
+	if h.Truncated {
+		f = append(f, "tc")
+	}
+	if h.RecursionDesired {
+		f = append(f, "rd")
+	}
+	if h.RecursionAvailable {
+		f = append(f, "ra")
+	}
+	if h.Zero {
+		f = append(f, "z")
+	}
+	if h.AuthenticatedData {
+		f = append(f, "ad")
+	}
+	if h.CheckingDisabled {
+		f = append(f, "cd")
+	}
+	return f
+}
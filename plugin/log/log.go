@@ -2,13 +2,16 @@
 package log
 
 import (
+	"bytes"
 	"context"
 	"log"
+	"math/rand"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics/vars"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/output"
 	"github.com/coredns/coredns/plugin/pkg/rcode"
 	"github.com/coredns/coredns/plugin/pkg/replacer"
 	"github.com/coredns/coredns/plugin/pkg/response"
@@ -56,9 +59,20 @@ func (l Logger) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		class := response.Classify(tpe)
 		// If we don't set up a class in config, the default "all" will be added
 		// and we shouldn't have an empty rule.Class.
-		if rule.Class[response.All] || rule.Class[class] {
-			rep := replacer.New(r, rrw, CommonLogEmptyValue)
-			rule.Log.Println(rep.Replace(rule.Format))
+		if (rule.Class[response.All] || rule.Class[class]) && rule.sampled(class) {
+			switch rule.Encoding {
+			case encodingJSON:
+				var b bytes.Buffer
+				encodeJSON(&b, fields(ctx, r, rrw, class))
+				rule.Log.Println(b.String())
+			case encodingLogfmt:
+				var b bytes.Buffer
+				encodeLogfmt(&b, fields(ctx, r, rrw, class))
+				rule.Log.Println(b.String())
+			default:
+				rep := replacer.New(r, rrw, CommonLogEmptyValue)
+				rule.Log.Println(rep.Replace(rule.Format))
+			}
 		}
 
 		return rc, err
@@ -75,9 +89,23 @@ type Rule struct {
 	NameScope string
 	Class     map[response.Class]bool
 	Format    string
+	Encoding  encoding
+	Sample    map[response.Class]float64 // fraction of the entries that is logged, by class; nil logs all.
+	Output    *output.Config             // where the entries are written; nil is standard output.
 	Log       *log.Logger
 }
 
+// sampled returns true if an entry of class should be logged according to the sample rates of the rule.
+func (r Rule) sampled(class response.Class) bool {
+	rate, ok := r.Sample[class]
+	if !ok {
+		if rate, ok = r.Sample[response.All]; !ok {
+			return true
+		}
+	}
+	return rate >= 1 || rand.Float64() < rate
+}
+
 const (
 	// CommonLogFormat is the common log format.
 	CommonLogFormat = `{remote}:{port} ` + CommonLogEmptyValue + ` [{when}] {>id} "{type} {class} {name} {proto} {size} {>do} {>bufsize}" {rcode} {>rflags} {rsize} {duration}`
@@ -3,12 +3,18 @@ package log
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/output"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/test"
 
@@ -103,3 +109,171 @@ func TestLoggedClassError(t *testing.T) {
 		t.Errorf("Expected it to be logged. Logged string: %s", logged)
 	}
 }
+
+// upstreamHandler answers the query and records the upstream in the metadata, like forward does.
+func upstreamHandler() test.Handler {
+	return test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		metadata.SetValueFunc(ctx, "forward/upstream", func() string { return "192.0.2.53:53" })
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.RecursionAvailable = true
+		m.Answer = []dns.RR{test.A("example.org. 300 IN A 192.0.2.1")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func TestLoggedJSON(t *testing.T) {
+	var f bytes.Buffer
+	rule := Rule{
+		NameScope: ".",
+		Log:       log.New(&f, "", 0),
+		Class:     map[response.Class]bool{response.All: true},
+		Encoding:  encodingJSON,
+	}
+	logger := Logger{Rules: []Rule{rule}, Next: upstreamHandler()}
+	md := &metadata.Metadata{Zones: []string{"."}, Next: logger}
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	md.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(f.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON object, got %q: %s", f.String(), err)
+	}
+	expected := map[string]interface{}{
+		"remote":   "10.240.0.1",
+		"port":     float64(40212),
+		"type":     "A",
+		"name":     "example.org.",
+		"proto":    "udp",
+		"do":       false,
+		"rcode":    "NOERROR",
+		"rclass":   "success",
+		"upstream": "192.0.2.53:53",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("Expected %s to be %v, got %v", k, v, entry[k])
+		}
+	}
+	if flags, ok := entry["rflags"].([]interface{}); !ok || len(flags) != 3 {
+		t.Errorf("Expected rflags to be a list of 3 flags, got %v", entry["rflags"])
+	}
+	if _, ok := entry["duration"].(float64); !ok {
+		t.Errorf("Expected duration to be a number, got %v", entry["duration"])
+	}
+	md1, ok := entry["metadata"].(map[string]interface{})
+	if !ok || md1["forward/upstream"] != "192.0.2.53:53" {
+		t.Errorf("Expected metadata with the upstream, got %v", entry["metadata"])
+	}
+}
+
+func TestLoggedLogfmt(t *testing.T) {
+	var f bytes.Buffer
+	rule := Rule{
+		NameScope: ".",
+		Log:       log.New(&f, "", 0),
+		Class:     map[response.Class]bool{response.All: true},
+		Encoding:  encodingLogfmt,
+	}
+	logger := Logger{Rules: []Rule{rule}, Next: upstreamHandler()}
+	md := &metadata.Metadata{Zones: []string{"."}, Next: logger}
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	md.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r)
+
+	logged := f.String()
+	for _, s := range []string{
+		" remote=10.240.0.1 port=40212 ",
+		" type=A class=IN name=example.org. proto=udp ",
+		" rcode=NOERROR rclass=success rflags=qr,rd,ra ",
+		" upstream=192.0.2.53:53 ",
+		" forward/upstream=192.0.2.53:53\n",
+	} {
+		if !strings.Contains(logged, s) {
+			t.Errorf("Expected %q to be logged, got %q", s, logged)
+		}
+	}
+}
+
+func TestEncodeLogfmtQuote(t *testing.T) {
+	var b bytes.Buffer
+	encodeLogfmt(&b, []field{{"a", "x y"}, {"b", ""}, {"c", `"q"`}, {"d", 1.5}, {"e", true}})
+	if expected := `a="x y" b="" c="\"q\"" d=1.5 e=true`; b.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, b.String())
+	}
+}
+
+func TestLoggedSample(t *testing.T) {
+	tests := []struct {
+		sample   map[response.Class]float64
+		expected int
+	}{
+		{nil, 100},
+		{map[response.Class]float64{response.All: 1}, 100},
+		{map[response.Class]float64{response.Error: 0.000001}, 100}, // successes are all logged.
+		{map[response.Class]float64{response.Success: 0.000001}, 0},
+		{map[response.Class]float64{response.All: 0.000001, response.Success: 1}, 100},
+	}
+
+	for i, tc := range tests {
+		var f bytes.Buffer
+		rule := Rule{
+			NameScope: ".",
+			Format:    DefaultLogFormat,
+			Log:       log.New(&f, "", 0),
+			Class:     map[response.Class]bool{response.All: true},
+			Sample:    tc.sample,
+		}
+		logger := Logger{Rules: []Rule{rule}, Next: upstreamHandler()}
+
+		r := new(dns.Msg)
+		r.SetQuestion("example.org.", dns.TypeA)
+		for j := 0; j < 100; j++ {
+			logger.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r)
+		}
+		if n := strings.Count(f.String(), "\n"); n != tc.expected {
+			t.Errorf("Test %d: expected %d entries, got %d", i, tc.expected, n)
+		}
+	}
+}
+
+func TestLoggedOutputFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "query.log")
+
+	w, err := output.Open(output.Config{Kind: output.File, Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := Logger{
+		Rules: []Rule{{
+			NameScope: ".",
+			Format:    DefaultLogFormat,
+			Log:       log.New(w, "", 0),
+			Class:     map[response.Class]bool{response.All: true},
+		}},
+		Next: test.ErrorHandler(),
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	logger.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r)
+	// Close writes the queued entries.
+	w.Close()
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), "A IN example.org. udp 29 false 512") {
+		t.Errorf("Expected the entry in %s, got %q", path, buf)
+	}
+}
@@ -3,9 +3,12 @@ package log
 import (
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/output"
 	"github.com/coredns/coredns/plugin/pkg/response"
 
 	"github.com/mholt/caddy"
@@ -26,14 +29,41 @@ func setup(c *caddy.Controller) error {
 	}
 
 	// Open the log files for writing when the server starts
+	var writers []*output.Writer
 	c.OnStartup(func() error {
 		for i := 0; i < len(rules); i++ {
-			rules[i].Log = log.New(os.Stdout, "", 0)
+			if rules[i].Output == nil {
+				rules[i].Log = log.New(os.Stdout, "", 0)
+				continue
+			}
+			cfg := *rules[i].Output
+			cfg.Severity = output.SeverityInfo
+			w, err := output.Open(cfg)
+			if err != nil {
+				for _, w := range writers {
+					w.Close()
+				}
+				writers = nil
+				return plugin.Error("log", err)
+			}
+			writers = append(writers, w)
+			rules[i].Log = log.New(w, "", 0)
+		}
+		if len(writers) > 0 {
+			metrics.MustRegister(c, output.DroppedCount)
 		}
 
 		return nil
 	})
 
+	c.OnShutdown(func() error {
+		for _, w := range writers {
+			w.Close()
+		}
+		writers = nil
+		return nil
+	})
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		return Logger{Next: next, Rules: rules, ErrorFunc: dnsserver.DefaultErrorFunc}
 	})
@@ -82,6 +112,7 @@ func logParse(c *caddy.Controller) ([]Rule, error) {
 
 		// Class refinements in an extra block.
 		for c.NextBlock() {
+			rule := &rules[len(rules)-1]
 			switch c.Val() {
 			// class followed by combinations of all, denial, error and success.
 			case "class":
@@ -94,7 +125,53 @@ func logParse(c *caddy.Controller) ([]Rule, error) {
 					if err != nil {
 						return nil, err
 					}
-					rules[len(rules)-1].Class[cls] = true
+					rule.Class[cls] = true
+				}
+			// format followed by text, json or logfmt.
+			case "format":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				enc, ok := encodingFromString(c.Val())
+				if !ok {
+					return nil, c.Errf("unknown format '%s'", c.Val())
+				}
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				if enc != encodingText && len(args) > 1 {
+					return nil, c.Errf("can't use format '%s' with a log format", c.Val())
+				}
+				rule.Encoding = enc
+			// output followed by stdout, file or syslog and their options.
+			case "output":
+				cfg, err := output.Parse(c, dnsserver.GetConfig(c).Root)
+				if err != nil {
+					return nil, err
+				}
+				rule.Output = &cfg
+			// sample followed by a rate and optionally the classes it applies to.
+			case "sample":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rate, err := strconv.ParseFloat(c.Val(), 64)
+				if err != nil || rate <= 0 || rate > 1 {
+					return nil, c.Errf("sample rate must be larger than 0 and at most 1: '%s'", c.Val())
+				}
+				if rule.Sample == nil {
+					rule.Sample = make(map[response.Class]float64)
+				}
+				classes := c.RemainingArgs()
+				if len(classes) == 0 {
+					classes = []string{response.All.String()}
+				}
+				for _, c := range classes {
+					cls, err := response.ClassFromString(c)
+					if err != nil {
+						return nil, err
+					}
+					rule.Sample[cls] = rate
 				}
 			default:
 				return nil, c.ArgErr()
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/coredns/coredns/plugin/pkg/output"
 	"github.com/coredns/coredns/plugin/pkg/response"
 
 	"github.com/mholt/caddy"
@@ -92,6 +93,66 @@ func TestLogParse(t *testing.T) {
 			Format:    CommonLogFormat,
 			Class:     map[response.Class]bool{response.Denial: true, response.Error: true},
 		}}},
+		{`log {
+			format json
+		}`, false, []Rule{{
+			NameScope: ".",
+			Format:    CommonLogFormat,
+			Class:     map[response.Class]bool{response.All: true},
+			Encoding:  encodingJSON,
+		}}},
+		{`log example.org {
+			class denial error
+			format logfmt
+			sample 0.5
+			sample 0.1 error
+		}`, false, []Rule{{
+			NameScope: "example.org.",
+			Format:    CommonLogFormat,
+			Class:     map[response.Class]bool{response.Denial: true, response.Error: true},
+			Encoding:  encodingLogfmt,
+			Sample:    map[response.Class]float64{response.All: 0.5, response.Error: 0.1},
+		}}},
+		{`log {
+			output file /var/log/query.log size 10M keep 3
+		}`, false, []Rule{{
+			NameScope: ".",
+			Format:    CommonLogFormat,
+			Class:     map[response.Class]bool{response.All: true},
+			Output:    &output.Config{Kind: output.File, Buffer: output.DefaultBuffer, Path: "/var/log/query.log", MaxSize: 10 << 20, Keep: 3},
+		}}},
+		{`log {
+			output syslog udp://127.0.0.1:514 facility local0
+		}`, false, []Rule{{
+			NameScope: ".",
+			Format:    CommonLogFormat,
+			Class:     map[response.Class]bool{response.All: true},
+			Output:    &output.Config{Kind: output.Syslog, Buffer: output.DefaultBuffer, Network: "udp", Address: "127.0.0.1:514", Facility: 16, Tag: "coredns"},
+		}}},
+		{`log {
+			output
+		}`, true, []Rule{}},
+		{`log {
+			output file
+		}`, true, []Rule{}},
+		{`log {
+			format yaml
+		}`, true, []Rule{}},
+		{`log {
+			format
+		}`, true, []Rule{}},
+		{`log . {combined} {
+			format json
+		}`, true, []Rule{}},
+		{`log {
+			sample 0
+		}`, true, []Rule{}},
+		{`log {
+			sample 1.5
+		}`, true, []Rule{}},
+		{`log {
+			sample 0.1 abracadabra
+		}`, true, []Rule{}},
 		{`log {
 			class abracadabra
 		}`, true, []Rule{}},
@@ -132,6 +193,21 @@ func TestLogParse(t *testing.T) {
 				t.Errorf("Test %d expected %dth LogRule Class to be  %v  , but got %v",
 					i, j, test.expectedLogRules[j].Class, actualLogRule.Class)
 			}
+
+			if actualLogRule.Encoding != test.expectedLogRules[j].Encoding {
+				t.Errorf("Test %d expected %dth LogRule Encoding to be %d, but got %d",
+					i, j, test.expectedLogRules[j].Encoding, actualLogRule.Encoding)
+			}
+
+			if !reflect.DeepEqual(actualLogRule.Sample, test.expectedLogRules[j].Sample) {
+				t.Errorf("Test %d expected %dth LogRule Sample to be %v, but got %v",
+					i, j, test.expectedLogRules[j].Sample, actualLogRule.Sample)
+			}
+
+			if !reflect.DeepEqual(actualLogRule.Output, test.expectedLogRules[j].Output) {
+				t.Errorf("Test %d expected %dth LogRule Output to be %v, but got %v",
+					i, j, test.expectedLogRules[j].Output, actualLogRule.Output)
+			}
 		}
 	}
 
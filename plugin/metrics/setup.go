@@ -36,6 +36,12 @@ func setup(c *caddy.Controller) error {
 		return m
 	})
 
+	// Only claim the address on startup: the reload plugin's dry run executes this setup too, but never
+	// starts the instance.
+	c.OnStartup(func() error {
+		uniqAddr.Set(m.Addr, m.OnStartup)
+		return nil
+	})
 	c.OncePerServerBlock(func() error {
 		c.OnStartup(func() error {
 			return uniqAddr.ForEach()
@@ -55,10 +61,6 @@ func setup(c *caddy.Controller) error {
 func prometheusParse(c *caddy.Controller) (*Metrics, error) {
 	var met = New(defaultAddr)
 
-	defer func() {
-		uniqAddr.Set(met.Addr, met.OnStartup)
-	}()
-
 	i := 0
 	for c.Next() {
 		if i > 0 {
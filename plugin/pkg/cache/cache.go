@@ -71,6 +71,17 @@ func (c *Cache) Len() int {
 	return l
 }
 
+// Walk walks each shard in the cache, and calls f for each element, while holding the lock of the shard.
+// The items map is passed, so f can delete the element with key from it. When f returns false, Walk
+// stops.
+func (c *Cache) Walk(f func(items map[uint64]interface{}, key uint64) bool) {
+	for _, s := range c.shards {
+		if !s.Walk(f) {
+			return
+		}
+	}
+}
+
 // newShard returns a new shard with size.
 func newShard(size int) *shard { return &shard{items: make(map[uint64]interface{}), size: size} }
 
@@ -123,6 +134,18 @@ func (s *shard) Get(key uint64) (interface{}, bool) {
 	return el, found
 }
 
+// Walk calls f for each element in the shard, see Cache.Walk. It returns false when f did.
+func (s *shard) Walk(f func(items map[uint64]interface{}, key uint64) bool) bool {
+	s.Lock()
+	defer s.Unlock()
+	for k := range s.items {
+		if !f(s.items, k) {
+			return false
+		}
+	}
+	return true
+}
+
 // Len returns the current length of the cache.
 func (s *shard) Len() int {
 	s.RLock()
@@ -30,6 +30,37 @@ func TestCacheLen(t *testing.T) {
 	}
 }
 
+func TestCacheWalk(t *testing.T) {
+	c := New(4)
+	for i := uint64(0); i < 10; i++ {
+		c.Add(i, i)
+	}
+
+	n := 0
+	c.Walk(func(items map[uint64]interface{}, key uint64) bool {
+		if items[key].(uint64)%2 == 0 {
+			delete(items, key)
+		}
+		n++
+		return true
+	})
+	if n != 10 {
+		t.Errorf("Expected to walk 10 elements, got %d", n)
+	}
+	if l := c.Len(); l != 5 {
+		t.Errorf("Cache size should %d, got %d", 5, l)
+	}
+
+	n = 0
+	c.Walk(func(items map[uint64]interface{}, key uint64) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("Expected to stop walking after 1 element, got %d", n)
+	}
+}
+
 func BenchmarkCache(b *testing.B) {
 	b.ReportAllocs()
 
@@ -0,0 +1,72 @@
+// Package doq contains functions for DNS-over-QUIC, RFC 9250. Each query and its response are sent
+// on their own bidirectional QUIC stream, prefixed with a two byte length field, like DNS over TCP.
+package doq
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/miekg/dns"
+)
+
+// NextProto is the ALPN token of DNS-over-QUIC.
+const NextProto = "doq"
+
+// Error codes used when closing a connection or resetting a stream, see RFC 9250, section 4.3.
+const (
+	NoError          = 0x0
+	InternalError    = 0x1
+	ProtocolError    = 0x2
+	RequestCancelled = 0x3
+	ExcessiveLoad    = 0x4
+)
+
+// ErrProtocol is returned by Read when the message violates RFC 9250.
+var ErrProtocol = errors.New("doq: protocol error")
+
+// Write writes m with a length prefix to w. The message ID is set to 0, as required by RFC 9250.
+func Write(w io.Writer, m *dns.Msg) error {
+	id := m.Id
+	m.Id = 0
+	buf, err := m.Pack()
+	m.Id = id
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 2+len(buf))
+	binary.BigEndian.PutUint16(b, uint16(len(buf)))
+	copy(b[2:], buf)
+	_, err = w.Write(b)
+	return err
+}
+
+// Read reads a length prefixed message from r. If the message ID isn't 0, or the message carries the
+// edns-tcp-keepalive option, ErrProtocol is returned.
+func Read(r io.Reader) (*dns.Msg, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	m := new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		return nil, err
+	}
+	if m.Id != 0 {
+		return nil, ErrProtocol
+	}
+	if opt := m.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if o.Option() == dns.EDNS0TCPKEEPALIVE {
+				return nil, ErrProtocol
+			}
+		}
+	}
+	return m, nil
+}
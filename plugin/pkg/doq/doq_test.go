@@ -0,0 +1,54 @@
+package doq
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestWriteRead(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.Id = 1234
+
+	var b bytes.Buffer
+	if err := Write(&b, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Id != 1234 {
+		t.Errorf("Expected the message ID not to be changed, got %d", m.Id)
+	}
+	if l := int(b.Bytes()[0])<<8 | int(b.Bytes()[1]); l != b.Len()-2 {
+		t.Errorf("Expected length prefix %d, got %d", b.Len()-2, l)
+	}
+
+	r, err := Read(&b)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if r.Id != 0 || r.Question[0].Name != "example.org." {
+		t.Errorf("Expected the query with ID 0, got %s", r)
+	}
+}
+
+func TestReadProtocolError(t *testing.T) {
+	id := new(dns.Msg)
+	id.SetQuestion("example.org.", dns.TypeA)
+	id.Id = 1
+
+	keepalive := new(dns.Msg)
+	keepalive.SetQuestion("example.org.", dns.TypeA)
+	keepalive.Id = 0
+	keepalive.SetEdns0(4096, false)
+	keepalive.IsEdns0().Option = append(keepalive.IsEdns0().Option, &dns.EDNS0_TCP_KEEPALIVE{Code: dns.EDNS0TCPKEEPALIVE})
+
+	for i, m := range []*dns.Msg{id, keepalive} {
+		buf, _ := m.Pack()
+		b := bytes.NewBuffer([]byte{byte(len(buf) >> 8), byte(len(buf))})
+		b.Write(buf)
+		if _, err := Read(b); err != ErrProtocol {
+			t.Errorf("Test %d: expected ErrProtocol, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package output
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// rotatingFile is a file that is rotated when it grows too large or too old. The rotated files get the
+// time of the rotation as suffix, i.e. query.log.20181001T120000.000, and may be compressed.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	every    time.Duration
+	keep     int
+	compress bool
+
+	f      *os.File
+	size   int64
+	opened time.Time
+
+	wg sync.WaitGroup // compression and clean up of rotated files.
+	mu sync.Mutex     // serializes compression and clean up.
+
+	now func() time.Time
+}
+
+// rotateFormat is the suffix of rotated files, it sorts in time order.
+const rotateFormat = "20060102T150405.000"
+
+func openFile(c Config) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:     c.Path,
+		maxSize:  c.MaxSize,
+		every:    c.Every,
+		keep:     c.Keep,
+		compress: c.Compress,
+		now:      time.Now,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = fi.Size()
+	r.opened = r.now()
+	return nil
+}
+
+// Write implements io.Writer. The file is rotated first, if writing p would make it too large or if
+// it is too old.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.f == nil {
+		// A previous rotation failed to open the new file.
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	tooLarge := r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize
+	tooOld := r.every > 0 && r.now().Sub(r.opened) >= r.every
+	if tooLarge || tooOld {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current file aside and opens a new one.
+func (r *rotatingFile) rotate() error {
+	r.f.Close()
+	r.f = nil
+	rotated := r.path + "." + r.now().Format(rotateFormat)
+	if err := os.Rename(r.path, rotated); err != nil {
+		return err
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.compress {
+			if err := compress(rotated); err != nil {
+				clog.Errorf("Failed to compress %s: %s", rotated, err)
+			}
+		}
+		r.cleanup()
+	}()
+
+	return r.open()
+}
+
+// cleanup removes the oldest rotated files, when there are more than keep.
+func (r *rotatingFile) cleanup() {
+	if r.keep <= 0 {
+		return
+	}
+	rotated, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return
+	}
+	var files []string
+	for _, f := range rotated {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(f, r.path+"."), ".gz")
+		if _, err := time.Parse(rotateFormat, suffix); err == nil {
+			files = append(files, f)
+		}
+	}
+	if len(files) <= r.keep {
+		return
+	}
+	sort.Strings(files)
+	for _, f := range files[:len(files)-r.keep] {
+		if err := os.Remove(f); err != nil {
+			clog.Errorf("Failed to remove %s: %s", f, err)
+		}
+	}
+}
+
+// Close implements io.Closer, it waits for the compression of rotated files.
+func (r *rotatingFile) Close() error {
+	r.wg.Wait()
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
+
+// compress gzips file to file.gz and removes file.
+func compress(file string) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(file+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(file + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(file + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(file + ".gz")
+		return err
+	}
+	return os.Remove(file)
+}
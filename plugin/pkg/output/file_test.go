@@ -0,0 +1,118 @@
+package output
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "query.log")
+
+	r, err := openFile(Config{Path: path, MaxSize: 10, Keep: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	for _, l := range []string{"line one\n", "line two\n", "line three\n", "line four\n"} {
+		if _, err := r.Write([]byte(l)); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+	}
+	r.Close()
+
+	buf, _ := ioutil.ReadFile(path)
+	if string(buf) != "line four\n" {
+		t.Errorf("Expected the last line in %s, got %q", path, buf)
+	}
+
+	rotated, _ := filepath.Glob(path + ".*")
+	if len(rotated) != 2 {
+		t.Fatalf("Expected 2 rotated files to be kept, got %v", rotated)
+	}
+	sort.Strings(rotated)
+	buf, _ = ioutil.ReadFile(rotated[0])
+	if string(buf) != "line two\n" {
+		t.Errorf("Expected the oldest file to be removed, got %q in %s", buf, rotated[0])
+	}
+}
+
+func TestRotatingFileEvery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "query.log")
+
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	r := &rotatingFile{path: path, every: time.Hour, now: func() time.Time { return now }}
+	if err := r.open(); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Write([]byte("one\n"))
+	now = now.Add(30 * time.Minute)
+	r.Write([]byte("two\n"))
+	now = now.Add(30 * time.Minute)
+	r.Write([]byte("three\n"))
+	r.Close()
+
+	rotated, _ := filepath.Glob(path + ".*")
+	if len(rotated) != 1 {
+		t.Fatalf("Expected 1 rotated file, got %v", rotated)
+	}
+	if expected := path + ".20181001T130000.000"; rotated[0] != expected {
+		t.Errorf("Expected rotated file %s, got %s", expected, rotated[0])
+	}
+	buf, _ := ioutil.ReadFile(rotated[0])
+	if string(buf) != "one\ntwo\n" {
+		t.Errorf("Expected the first two lines in the rotated file, got %q", buf)
+	}
+}
+
+func TestRotatingFileCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "query.log")
+
+	r, err := openFile(Config{Path: path, MaxSize: 5, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("first\n"))
+	r.Write([]byte("second\n"))
+	r.Close()
+
+	rotated, _ := filepath.Glob(path + ".*")
+	if len(rotated) != 1 || filepath.Ext(rotated[0]) != ".gz" {
+		t.Fatalf("Expected 1 compressed file, got %v", rotated)
+	}
+	f, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Expected a gzip file, got %s", err)
+	}
+	buf, _ := ioutil.ReadAll(gz)
+	if string(buf) != "first\n" {
+		t.Errorf("Expected the first line in the compressed file, got %q", buf)
+	}
+}
@@ -0,0 +1,15 @@
+package output
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DroppedCount is the number of lines that were dropped, because the queue was full or writing failed.
+var DroppedCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "output",
+	Name:      "dropped_lines_total",
+	Help:      "Counter of log lines that were dropped, by output.",
+}, []string{"output"})
@@ -0,0 +1,195 @@
+// Package output implements the destinations the log and errors plugins write to: standard output,
+// files that are rotated by size or time, and syslog.
+//
+// Lines are written asynchronously: Write queues the line and returns, a goroutine writes the queued
+// lines to the destination. When the queue is full the line is dropped and counted in DroppedCount,
+// so a slow disk or syslog server never blocks the handling of queries.
+//
+// Writers for the same destination are shared, they are reference counted and the destination is
+// closed when the last one is closed. This allows a new server instance to take over the destination
+// of the old one on reload.
+package output
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// Kinds of outputs.
+const (
+	Stdout = "stdout"
+	File   = "file"
+	Syslog = "syslog"
+)
+
+// Config is the configuration of an output, see Parse.
+type Config struct {
+	Kind   string
+	Buffer int // number of lines that can be queued.
+
+	// File outputs.
+	Path     string
+	MaxSize  int64         // rotate when the file would grow beyond this many bytes, 0 disables.
+	Every    time.Duration // rotate when the file is older than this, 0 disables.
+	Keep     int           // number of rotated files to keep, 0 keeps all.
+	Compress bool          // gzip rotated files.
+
+	// Syslog outputs.
+	Network  string // "unix", "udp" or "tcp"; empty for the local syslog socket.
+	Address  string
+	Facility int
+	Severity int // set by the plugin, not parsed.
+	Tag      string
+}
+
+// Key returns the key that identifies the destination of c.
+func (c Config) Key() string {
+	switch c.Kind {
+	case File:
+		return File + ":" + c.Path
+	case Syslog:
+		addr := c.Address
+		if c.Network != "" {
+			addr = c.Network + "://" + c.Address
+		}
+		return Syslog + ":" + addr
+	}
+	return Stdout
+}
+
+// Writer writes lines asynchronously to an output.
+type Writer struct {
+	key   string // key of the writer in writers.
+	label string // destination in DroppedCount.
+	refs  int    // guarded by mu.
+
+	sink  io.WriteCloser
+	lines chan []byte
+	done  chan struct{}
+
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+var (
+	mu      sync.Mutex
+	writers = make(map[string]*Writer)
+)
+
+// Open returns the Writer for the destination of c. If there is one already, it is shared.
+func Open(c Config) (*Writer, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := c.Key()
+	if c.Kind == Syslog {
+		// Messages are sent with the severity of the writer.
+		key += "/" + strconv.Itoa(c.Severity)
+	}
+	if w, ok := writers[key]; ok {
+		w.refs++
+		return w, nil
+	}
+
+	var (
+		sink io.WriteCloser
+		err  error
+	)
+	switch c.Kind {
+	case File:
+		sink, err = openFile(c)
+	case Syslog:
+		sink, err = openSyslog(c)
+	default:
+		sink = stdout{}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := c.Buffer
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	w := &Writer{
+		key:   key,
+		label: c.Key(),
+		refs:  1,
+		sink:  sink,
+		lines: make(chan []byte, buffer),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	writers[key] = w
+	return w, nil
+}
+
+// Write implements io.Writer. It queues p and never blocks, if the queue is full p is dropped.
+func (w *Writer) Write(p []byte) (int, error) {
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+	if w.closed {
+		DroppedCount.WithLabelValues(w.label).Inc()
+		return len(p), nil
+	}
+	select {
+	case w.lines <- b:
+	default:
+		DroppedCount.WithLabelValues(w.label).Inc()
+	}
+	return len(p), nil
+}
+
+// Close closes w. When this was the last user of the destination, the queued lines are written and
+// the destination is closed.
+func (w *Writer) Close() error {
+	mu.Lock()
+	w.refs--
+	if w.refs > 0 {
+		mu.Unlock()
+		return nil
+	}
+	delete(writers, w.key)
+	mu.Unlock()
+
+	w.closeMu.Lock()
+	w.closed = true
+	close(w.lines)
+	w.closeMu.Unlock()
+
+	<-w.done
+	return w.sink.Close()
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	failing := false
+	for b := range w.lines {
+		if _, err := w.sink.Write(b); err != nil {
+			DroppedCount.WithLabelValues(w.label).Inc()
+			if !failing {
+				clog.Errorf("Failed to write to %s: %s", w.label, err)
+			}
+			failing = true
+			continue
+		}
+		failing = false
+	}
+}
+
+// stdout is the standard output, it isn't closed.
+type stdout struct{}
+
+func (stdout) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdout) Close() error                { return nil }
+
+// DefaultBuffer is the default number of lines that can be queued.
+const DefaultBuffer = 10000
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingSink blocks writes until release is closed.
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	lines   []string
+}
+
+func (b *blockingSink) Write(p []byte) (int, error) {
+	<-b.release
+	b.mu.Lock()
+	b.lines = append(b.lines, string(p))
+	b.mu.Unlock()
+	return len(p), nil
+}
+
+func (b *blockingSink) Close() error { return nil }
+
+func TestWriterDrops(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	w := &Writer{key: "test", label: "test", refs: 1, sink: sink, lines: make(chan []byte, 2), done: make(chan struct{})}
+	go w.run()
+
+	done := make(chan struct{})
+	go func() {
+		// The first line is taken by run and blocks in the sink, two are queued, the rest is dropped.
+		for i := 0; i < 10; i++ {
+			w.Write([]byte("line\n"))
+			time.Sleep(time.Millisecond)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Write not to block")
+	}
+
+	close(sink.release)
+	mu.Lock()
+	writers["test"] = w
+	mu.Unlock()
+	w.Close()
+
+	if written := len(sink.lines); written < 2 || written == 10 {
+		t.Errorf("Expected some of the 10 lines to be dropped, got %d written", written)
+	}
+}
+
+func TestOpenShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := Config{Kind: File, Path: filepath.Join(dir, "query.log")}
+
+	w1, err := Open(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	w2, err := Open(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if w1 != w2 {
+		t.Fatalf("Expected the writer for the same file to be shared")
+	}
+
+	w1.Write([]byte("one\n"))
+	w1.Close()
+	// The second reference keeps the writer open.
+	w2.Write([]byte("two\n"))
+	w2.Close()
+
+	buf, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "one\ntwo\n" {
+		t.Errorf("Expected both lines to be written, got %q", buf)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := writers[c.Key()]; ok {
+		t.Errorf("Expected the writer to be removed after the last close")
+	}
+}
@@ -0,0 +1,140 @@
+package output
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+// Parse parses the arguments of an output directive, the controller must be at the directive:
+//
+//	output stdout [buffer N]
+//	output file PATH [size SIZE] [every DURATION] [keep N] [compress] [buffer N]
+//	output syslog [ADDRESS] [facility FACILITY] [tag TAG] [buffer N]
+//
+// A relative PATH is relative to root. SIZE is a number of bytes with an optional K, M or G
+// suffix. ADDRESS is unix:///path, udp://host:port or tcp://host:port.
+func Parse(c *caddy.Controller, root string) (Config, error) {
+	cfg := Config{Buffer: DefaultBuffer}
+	if !c.NextArg() {
+		return cfg, c.ArgErr()
+	}
+	cfg.Kind = c.Val()
+
+	args := c.RemainingArgs()
+	switch cfg.Kind {
+	case Stdout:
+	case File:
+		if len(args) == 0 {
+			return cfg, c.ArgErr()
+		}
+		cfg.Path = args[0]
+		if !filepath.IsAbs(cfg.Path) && root != "" {
+			cfg.Path = filepath.Join(root, cfg.Path)
+		}
+		args = args[1:]
+	case Syslog:
+		cfg.Facility = defaultFacility
+		cfg.Tag = defaultTag
+		if len(args) > 0 && strings.Contains(args[0], "://") {
+			u, err := url.Parse(args[0])
+			if err != nil {
+				return cfg, c.Errf("invalid syslog address %q: %s", args[0], err)
+			}
+			switch u.Scheme {
+			case "unix":
+				cfg.Network, cfg.Address = "unix", u.Path
+			case "udp", "tcp":
+				cfg.Network, cfg.Address = u.Scheme, u.Host
+			default:
+				return cfg, c.Errf("unsupported syslog address %q", args[0])
+			}
+			if cfg.Address == "" {
+				return cfg, c.Errf("invalid syslog address %q", args[0])
+			}
+			args = args[1:]
+		}
+	default:
+		return cfg, c.Errf("unknown output '%s'", cfg.Kind)
+	}
+
+	for len(args) > 0 {
+		opt := args[0]
+		args = args[1:]
+
+		if opt == "compress" && cfg.Kind == File {
+			cfg.Compress = true
+			continue
+		}
+		if len(args) == 0 {
+			return cfg, c.ArgErr()
+		}
+		value := args[0]
+		args = args[1:]
+
+		switch {
+		case opt == "buffer":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return cfg, c.Errf("invalid buffer '%s'", value)
+			}
+			cfg.Buffer = n
+		case opt == "size" && cfg.Kind == File:
+			n, err := parseSize(value)
+			if err != nil {
+				return cfg, c.Err(err.Error())
+			}
+			cfg.MaxSize = n
+		case opt == "every" && cfg.Kind == File:
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return cfg, c.Errf("invalid duration '%s'", value)
+			}
+			cfg.Every = d
+		case opt == "keep" && cfg.Kind == File:
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return cfg, c.Errf("invalid keep '%s'", value)
+			}
+			cfg.Keep = n
+		case opt == "facility" && cfg.Kind == Syslog:
+			f, ok := facilities[strings.ToLower(value)]
+			if !ok {
+				return cfg, c.Errf("unknown syslog facility '%s'", value)
+			}
+			cfg.Facility = f
+		case opt == "tag" && cfg.Kind == Syslog:
+			cfg.Tag = value
+		default:
+			return cfg, c.Errf("unknown property '%s' for %s output", opt, cfg.Kind)
+		}
+	}
+	return cfg, nil
+}
+
+// parseSize parses a number of bytes with an optional K, M or G suffix.
+func parseSize(s string) (int64, error) {
+	mult := int64(1)
+	n := strings.ToUpper(s)
+	switch {
+	case strings.HasSuffix(n, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(n, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(n, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		n = n[:len(n)-1]
+	}
+	i, err := strconv.ParseInt(n, 10, 64)
+	if err != nil || i <= 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return i * mult, nil
+}
@@ -0,0 +1,62 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  Config
+	}{
+		{`output stdout`, false, Config{Kind: Stdout, Buffer: DefaultBuffer}},
+		{`output stdout buffer 10`, false, Config{Kind: Stdout, Buffer: 10}},
+		{`output file query.log`, false, Config{Kind: File, Buffer: DefaultBuffer, Path: "/etc/coredns/query.log"}},
+		{`output file /var/log/query.log size 100M every 24h keep 7 compress`, false,
+			Config{Kind: File, Buffer: DefaultBuffer, Path: "/var/log/query.log", MaxSize: 100 << 20, Every: 24 * time.Hour, Keep: 7, Compress: true}},
+		{`output file /var/log/query.log size 512`, false, Config{Kind: File, Buffer: DefaultBuffer, Path: "/var/log/query.log", MaxSize: 512}},
+		{`output syslog`, false, Config{Kind: Syslog, Buffer: DefaultBuffer, Facility: 3, Tag: "coredns"}},
+		{`output syslog udp://127.0.0.1:514 facility local3 tag dns`, false,
+			Config{Kind: Syslog, Buffer: DefaultBuffer, Network: "udp", Address: "127.0.0.1:514", Facility: 19, Tag: "dns"}},
+		{`output syslog unix:///dev/log`, false, Config{Kind: Syslog, Buffer: DefaultBuffer, Network: "unix", Address: "/dev/log", Facility: 3, Tag: "coredns"}},
+		{`output syslog tcp://syslog.example.org:601`, false,
+			Config{Kind: Syslog, Buffer: DefaultBuffer, Network: "tcp", Address: "syslog.example.org:601", Facility: 3, Tag: "coredns"}},
+		// fails
+		{`output`, true, Config{}},
+		{`output kafka`, true, Config{}},
+		{`output file`, true, Config{}},
+		{`output file query.log size`, true, Config{}},
+		{`output file query.log size 10T`, true, Config{}},
+		{`output file query.log every -1h`, true, Config{}},
+		{`output file query.log keep x`, true, Config{}},
+		{`output file query.log facility local0`, true, Config{}},
+		{`output syslog http://127.0.0.1`, true, Config{}},
+		{`output syslog udp://`, true, Config{}},
+		{`output syslog facility local9`, true, Config{}},
+		{`output syslog compress`, true, Config{}},
+		{`output stdout buffer 0`, true, Config{}},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		c.Next()
+		cfg, err := Parse(c, "/etc/coredns")
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %q, got none", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %q, got %s", i, tc.input, err)
+			continue
+		}
+		if cfg != tc.expected {
+			t.Errorf("Test %d: expected %+v, got %+v", i, tc.expected, cfg)
+		}
+	}
+}
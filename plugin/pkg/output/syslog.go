@@ -0,0 +1,150 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Syslog severities used by the plugins.
+const (
+	SeverityErr  = 3
+	SeverityInfo = 6
+)
+
+// facilities maps the syslog facility names to their numbers.
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// defaultFacility is the facility when none is configured.
+const defaultFacility = 3 // daemon
+
+// defaultTag is the APP-NAME when no tag is configured.
+const defaultTag = "coredns"
+
+// localSockets are the usual paths of the local syslog socket.
+var localSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogWriter sends each line as a RFC 5424 message.
+type syslogWriter struct {
+	network string
+	address string
+	pri     int
+	tag     string
+
+	hostname string
+	pid      int
+
+	conn   net.Conn
+	framed bool // stream connections need octet counting, see RFC 6587.
+
+	now func() time.Time
+}
+
+func openSyslog(c Config) (*syslogWriter, error) {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	s := &syslogWriter{
+		network:  c.Network,
+		address:  c.Address,
+		pri:      c.Facility*8 + c.Severity,
+		tag:      c.Tag,
+		hostname: hostname,
+		pid:      os.Getpid(),
+		now:      time.Now,
+	}
+	if s.tag == "" {
+		s.tag = defaultTag
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *syslogWriter) connect() error {
+	if s.network != "" {
+		conn, err := net.Dial(s.network, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+		s.framed = s.network == "tcp" || s.network == "unix"
+		return nil
+	}
+
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range localSockets {
+			if conn, err := net.Dial(network, path); err == nil {
+				s.conn = conn
+				s.framed = network == "unix"
+				return nil
+			}
+		}
+	}
+	return errors.New("no local syslog socket found")
+}
+
+// format returns p as RFC 5424 message.
+func (s *syslogWriter) format(p []byte) []byte {
+	msg := bytes.TrimRight(p, "\n")
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "<%d>1 %s %s %s %d - - ", s.pri, s.now().Format("2006-01-02T15:04:05.000000Z07:00"), s.hostname, s.tag, s.pid)
+	b.Write(msg)
+	if !s.framed {
+		return b.Bytes()
+	}
+	return append([]byte(strconv.Itoa(b.Len())+" "), b.Bytes()...)
+}
+
+// Write implements io.Writer. If sending fails, it reconnects and tries once more.
+func (s *syslogWriter) Write(p []byte) (int, error) {
+	if s.conn != nil {
+		if _, err := s.conn.Write(s.format(p)); err == nil {
+			return len(p), nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	if err := s.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := s.conn.Write(s.format(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close implements io.Closer.
+func (s *syslogWriter) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	s, err := openSyslog(Config{Network: "udp", Address: pc.LocalAddr().String(), Facility: facilities["local0"], Severity: SeverityInfo, Tag: "dns"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Write([]byte("10.0.0.1 - example.org. A\n")); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// <local0*8+info>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	re := regexp.MustCompile(`^<134>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) \S+ dns ` + strconv.Itoa(os.Getpid()) + ` - - 10\.0\.0\.1 - example\.org\. A$`)
+	if !re.Match(buf[:n]) {
+		t.Errorf("Expected a RFC 5424 message, got %q", buf[:n])
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := openSyslog(Config{Network: "tcp", Address: l.Addr().String(), Facility: defaultFacility, Severity: SeverityErr})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	defer s.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s.Write([]byte("[ERROR] failed\n"))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	length, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		t.Fatalf("Expected an octet count, got %q", length)
+	}
+	msg := make([]byte, n)
+	if _, err := r.Read(msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(msg), "<27>1 ") || !strings.HasSuffix(string(msg), " coredns "+strconv.Itoa(os.Getpid())+" - - [ERROR] failed") {
+		t.Errorf("Expected a RFC 5424 message, got %q", msg)
+	}
+}
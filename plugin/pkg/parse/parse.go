@@ -3,26 +3,37 @@ package parse
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/coredns/coredns/plugin/pkg/transport"
 
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
-// Transfer parses transfer statements: 'transfer [to|from] [address...]'.
-func Transfer(c *caddy.Controller, secondary bool) (tos, froms []string, err error) {
+// Transfer parses transfer statements: 'transfer [to|from] [address...] [key KEY]'. The name of the TSIG
+// key is returned in key, it is empty when no key is given.
+func Transfer(c *caddy.Controller, secondary bool) (tos, froms []string, key string, err error) {
 	if !c.NextArg() {
-		return nil, nil, c.ArgErr()
+		return nil, nil, "", c.ArgErr()
 	}
 	value := c.Val()
+	args := c.RemainingArgs()
+	if l := len(args); l >= 2 && args[l-2] == "key" {
+		key = strings.ToLower(dns.Fqdn(args[l-1]))
+		args = args[:l-2]
+		if len(args) == 0 {
+			return nil, nil, "", fmt.Errorf("no addresses for transfer %s with key %s", value, key)
+		}
+	}
 	switch value {
 	case "to":
-		tos = c.RemainingArgs()
+		tos = args
 		for i := range tos {
 			if tos[i] != "*" {
 				normalized, err := HostPort(tos[i], transport.Port)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, "", err
 				}
 				tos[i] = normalized
 			}
@@ -30,18 +41,18 @@ func Transfer(c *caddy.Controller, secondary bool) (tos, froms []string, err err
 
 	case "from":
 		if !secondary {
-			return nil, nil, fmt.Errorf("can't use `transfer from` when not being a secondary")
+			return nil, nil, "", fmt.Errorf("can't use `transfer from` when not being a secondary")
 		}
-		froms = c.RemainingArgs()
+		froms = args
 		for i := range froms {
 			if froms[i] != "*" {
 				normalized, err := HostPort(froms[i], transport.Port)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, "", err
 				}
 				froms[i] = normalized
 			} else {
-				return nil, nil, fmt.Errorf("can't use '*' in transfer from")
+				return nil, nil, "", fmt.Errorf("can't use '*' in transfer from")
 			}
 		}
 	}
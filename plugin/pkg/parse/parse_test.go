@@ -13,58 +13,73 @@ func TestTransfer(t *testing.T) {
 		secondary      bool
 		expectedTo     []string
 		expectedFrom   []string
+		expectedKey    string
 	}{
 		// OK transfer to
 		{
 			`to 127.0.0.1`,
-			false, false, []string{"127.0.0.1:53"}, []string{},
+			false, false, []string{"127.0.0.1:53"}, []string{}, "",
 		},
 		// OK transfer tos
 		{
 			`to 127.0.0.1 127.0.0.2`,
-			false, false, []string{"127.0.0.1:53", "127.0.0.2:53"}, []string{},
+			false, false, []string{"127.0.0.1:53", "127.0.0.2:53"}, []string{}, "",
 		},
 		// OK transfer from
 		{
 			`from 127.0.0.1`,
-			false, true, []string{}, []string{"127.0.0.1:53"},
+			false, true, []string{}, []string{"127.0.0.1:53"}, "",
 		},
 		// OK transfer froms
 		{
 			`from 127.0.0.1 127.0.0.2`,
-			false, true, []string{}, []string{"127.0.0.1:53", "127.0.0.2:53"},
+			false, true, []string{}, []string{"127.0.0.1:53", "127.0.0.2:53"}, "",
 		},
 		// OK transfer tos/froms
 		{
 			`to 127.0.0.1 127.0.0.2
 			from 127.0.0.1 127.0.0.2`,
-			false, true, []string{"127.0.0.1:53", "127.0.0.2:53"}, []string{"127.0.0.1:53", "127.0.0.2:53"},
+			false, true, []string{"127.0.0.1:53", "127.0.0.2:53"}, []string{"127.0.0.1:53", "127.0.0.2:53"}, "",
+		},
+		// OK transfer with key
+		{
+			`to 127.0.0.1 key Xfr.Example.org`,
+			false, false, []string{"127.0.0.1:53"}, []string{}, "xfr.example.org.",
+		},
+		{
+			`from 127.0.0.1 key xfr.example.org.`,
+			false, true, []string{}, []string{"127.0.0.1:53"}, "xfr.example.org.",
+		},
+		// Bad transfer with key, but no addresses
+		{
+			`to key xfr.example.org.`,
+			true, false, []string{}, []string{}, "",
 		},
 		// Bad transfer from, secondary false
 		{
 			`from 127.0.0.1`,
-			true, false, []string{}, []string{},
+			true, false, []string{}, []string{}, "",
 		},
 		// Bad transfer from garbage
 		{
 			`from !@#$%^&*()`,
-			true, true, []string{}, []string{},
+			true, true, []string{}, []string{}, "",
 		},
 		// Bad transfer from no args
 		{
 			`from`,
-			true, false, []string{}, []string{},
+			true, false, []string{}, []string{}, "",
 		},
 		// Bad transfer from *
 		{
 			`from *`,
-			true, true, []string{}, []string{},
+			true, true, []string{}, []string{}, "",
 		},
 	}
 
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.inputFileRules)
-		tos, froms, err := Transfer(c, test.secondary)
+		tos, froms, key, err := Transfer(c, test.secondary)
 
 		if err == nil && test.shouldErr {
 			t.Fatalf("Test %d expected errors, but got no error %+v %+v", i, err, test)
@@ -72,6 +87,10 @@ func TestTransfer(t *testing.T) {
 			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
 		}
 
+		if key != test.expectedKey {
+			t.Fatalf("Test %d expected key %q, got %q", i, test.expectedKey, key)
+		}
+
 		if test.expectedTo != nil {
 			for j, got := range tos {
 				if got != test.expectedTo[j] {
@@ -0,0 +1,79 @@
+// Package tsig contains the TSIG (RFC 2845) keys that are shared between the server and plugins.
+package tsig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Key is a TSIG key.
+type Key struct {
+	Name      string // Name of the key, fully qualified and lowercase.
+	Algorithm string // Algorithm, i.e. dns.HmacSHA256.
+	Secret    string // Base64 encoded secret.
+}
+
+// New returns a new key. The algorithm may be given without the closing dot, i.e. "hmac-sha256".
+func New(name, algorithm, secret string) (Key, error) {
+	alg, ok := algorithms[strings.ToLower(dns.Fqdn(algorithm))]
+	if !ok {
+		return Key{}, fmt.Errorf("unsupported TSIG algorithm: %q", algorithm)
+	}
+	if _, err := base64.StdEncoding.DecodeString(secret); err != nil {
+		return Key{}, fmt.Errorf("invalid secret for TSIG key %q: %s", name, err)
+	}
+	return Key{Name: strings.ToLower(dns.Fqdn(name)), Algorithm: alg, Secret: secret}, nil
+}
+
+// Verified returns the name of the key r is signed with. If r is not signed, not signed with one of keys,
+// or the signature did not verify, the empty string is returned. The server only verifies messages signed
+// with the keys it knows about, so keys must be keys configured for it with the tsig plugin.
+func Verified(w dns.ResponseWriter, r *dns.Msg, keys ...Key) string {
+	t := r.IsTsig()
+	if t == nil {
+		return ""
+	}
+	name := strings.ToLower(t.Hdr.Name)
+	for _, k := range keys {
+		if k.Name != name || k.Secret == "" {
+			continue
+		}
+		if w.TsigStatus() != nil {
+			return ""
+		}
+		return name
+	}
+	return ""
+}
+
+// Sign adds a TSIG RR to the reply m, when the request r is signed. The server signs the reply
+// when it is written.
+func Sign(m, r *dns.Msg) {
+	t := r.IsTsig()
+	if t == nil {
+		return
+	}
+	m.SetTsig(t.Hdr.Name, t.Algorithm, fudge, time.Now().Unix())
+}
+
+// SetTsig signs the request m with key k.
+func (k Key) SetTsig(m *dns.Msg) { m.SetTsig(k.Name, k.Algorithm, fudge, time.Now().Unix()) }
+
+// Secrets returns k's secret as used in dns.Client and dns.Transfer.
+func (k Key) Secrets() map[string]string { return map[string]string{k.Name: k.Secret} }
+
+// fudge is the permitted error in the signing time, see RFC 2845, section 4.5.
+const fudge = 300
+
+// algorithms maps the supported algorithms and their aliases to the algorithm's name.
+var algorithms = map[string]string{
+	"hmac-md5.":    dns.HmacMD5,
+	dns.HmacMD5:    dns.HmacMD5,
+	dns.HmacSHA1:   dns.HmacSHA1,
+	dns.HmacSHA256: dns.HmacSHA256,
+	dns.HmacSHA512: dns.HmacSHA512,
+}
@@ -0,0 +1,72 @@
+package tsig
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name, algorithm, secret string
+		shouldErr               bool
+		expected                Key
+	}{
+		{"Key.Example.org", "hmac-sha256", "c2VjcmV0", false, Key{"key.example.org.", dns.HmacSHA256, "c2VjcmV0"}},
+		{"key.example.org.", "HMAC-MD5", "c2VjcmV0", false, Key{"key.example.org.", dns.HmacMD5, "c2VjcmV0"}},
+		{"key.example.org.", "hmac-md5.sig-alg.reg.int.", "c2VjcmV0", false, Key{"key.example.org.", dns.HmacMD5, "c2VjcmV0"}},
+		{"key.example.org.", "hmac-sha384", "c2VjcmV0", true, Key{}},
+		{"key.example.org.", "hmac-sha256", "c2VjcmV0!", true, Key{}},
+	}
+	for i, tc := range tests {
+		k, err := New(tc.name, tc.algorithm, tc.secret)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if k != tc.expected {
+			t.Errorf("Test %d: expected key %v, got %v", i, tc.expected, k)
+		}
+	}
+}
+
+func TestVerified(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeSOA)
+	if k := Verified(&test.ResponseWriter{}, m); k != "" {
+		t.Errorf("Expected no key for unsigned message, got %s", k)
+	}
+
+	key, err := New("key.example.org.", "hmac-sha256", "c2VjcmV0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetTsig("Key.Example.org.", dns.HmacSHA256, 300, 0)
+	if k := Verified(&test.ResponseWriter{}, m, key); k != "key.example.org." {
+		t.Errorf("Expected key %s, got %s", "key.example.org.", k)
+	}
+	if k := Verified(&badWriter{}, m, key); k != "" {
+		t.Errorf("Expected no key for message that didn't verify, got %s", k)
+	}
+
+	// Without a secret for the key the server didn't verify the message, it must not be trusted.
+	if k := Verified(&test.ResponseWriter{}, m); k != "" {
+		t.Errorf("Expected no key for message signed with an unknown key, got %s", k)
+	}
+	other, _ := New("other.example.org.", "hmac-sha256", "c2VjcmV0")
+	if k := Verified(&test.ResponseWriter{}, m, other); k != "" {
+		t.Errorf("Expected no key for message signed with an unknown key, got %s", k)
+	}
+}
+
+type badWriter struct{ test.ResponseWriter }
+
+func (b *badWriter) TsigStatus() error { return dns.ErrSig }
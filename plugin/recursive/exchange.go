@@ -0,0 +1,63 @@
+package recursive
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// udpSize is the EDNS0 buffer size advertised to name servers.
+const udpSize = 1232
+
+var errMismatch = errors.New("question in response does not match query")
+
+// exchange sends the query for qname and qtype to the name server with address ip. Truncated
+// responses are retried over TCP. When case randomisation is enabled the response must echo the
+// exact qname.
+func (r *Recursive) exchange(ctx context.Context, ip, qname string, qtype uint16, do bool) (*dns.Msg, error) {
+	name := qname
+	if r.caseRand {
+		name = randomCase(qname)
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(udpSize, do)
+
+	addr := net.JoinHostPort(ip, r.port)
+	c := &dns.Client{Net: "udp", Timeout: r.timeout}
+	start := time.Now()
+	res, _, err := c.ExchangeContext(ctx, m, addr)
+	if err == nil && res.Truncated {
+		c.Net = "tcp"
+		res, _, err = c.ExchangeContext(ctx, m, addr)
+	}
+	r.updateRTT(ip, time.Since(start), err != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Question) != 1 || res.Question[0].Name != name || res.Question[0].Qtype != qtype {
+		return nil, errMismatch
+	}
+	return res, nil
+}
+
+// randomCase randomises the case of the letters in name, see draft-vixie-dnsext-dns0x20.
+func randomCase(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			if rand.Intn(2) == 0 {
+				b[i] = c | 0x20
+			} else {
+				b[i] = c &^ 0x20
+			}
+		}
+	}
+	return string(b)
+}
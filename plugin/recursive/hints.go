@@ -0,0 +1,68 @@
+package recursive
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// hints are the root name servers and their addresses, see https://www.iana.org/domains/root/servers.
+var hints = []string{
+	"a.root-servers.net. 198.41.0.4 2001:503:ba3e::2:30",
+	"b.root-servers.net. 170.247.170.2 2801:1b8:10::b",
+	"c.root-servers.net. 192.33.4.12 2001:500:2::c",
+	"d.root-servers.net. 199.7.91.13 2001:500:2d::d",
+	"e.root-servers.net. 192.203.230.10 2001:500:a8::e",
+	"f.root-servers.net. 192.5.5.241 2001:500:2f::f",
+	"g.root-servers.net. 192.112.36.4 2001:500:12::d0d",
+	"h.root-servers.net. 198.97.190.53 2001:500:1::53",
+	"i.root-servers.net. 192.36.148.17 2001:7fe::53",
+	"j.root-servers.net. 192.58.128.30 2001:503:c27::2:30",
+	"k.root-servers.net. 193.0.14.129 2001:7fd::1",
+	"l.root-servers.net. 199.7.83.42 2001:500:9f::42",
+	"m.root-servers.net. 202.12.27.33 2001:dc3::35",
+}
+
+// rootHints returns the delegation for the root zone from the built-in hints.
+func rootHints() *delegation {
+	d := &delegation{zone: "."}
+	for _, h := range hints {
+		fields := strings.Fields(h)
+		d.ns = append(d.ns, fields[0])
+		d.addrs = append(d.addrs, fields[1:]...)
+	}
+	return d
+}
+
+// readHints reads the root hints from file, a zone file with the NS records of the root zone and the
+// A and AAAA records of those name servers, i.e. the named.root file.
+func readHints(file string) (*delegation, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := &delegation{zone: "."}
+	var addrs []dns.RR
+	for x := range dns.ParseZone(f, ".", file) {
+		if x.Error != nil {
+			return nil, x.Error
+		}
+		switch rr := x.RR.(type) {
+		case *dns.NS:
+			if rr.Hdr.Name == "." {
+				d.ns = append(d.ns, strings.ToLower(rr.Ns))
+			}
+		case *dns.A, *dns.AAAA:
+			addrs = append(addrs, rr)
+		}
+	}
+	d.addrs = glue(d.ns, addrs)
+	if len(d.addrs) == 0 {
+		return nil, fmt.Errorf("%s: no root name server addresses", file)
+	}
+	return d, nil
+}
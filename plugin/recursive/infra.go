@@ -0,0 +1,147 @@
+package recursive
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// delegation is a zone and the name servers it is delegated to.
+type delegation struct {
+	zone    string
+	ns      []string // names of the name servers
+	addrs   []string // addresses of the name servers
+	expires time.Time
+}
+
+// closest returns the delegation closest to name from the infrastructure cache, or the root hints.
+func (r *Recursive) closest(name string, now time.Time) *delegation {
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		if d := r.delegation(name[off:], now); d != nil {
+			return d
+		}
+	}
+	if d := r.delegation(".", now); d != nil {
+		return d
+	}
+	return r.hints
+}
+
+// delegation returns the cached delegation for zone, or nil if there is none.
+func (r *Recursive) delegation(zone string, now time.Time) *delegation {
+	i, ok := r.infra.Get(hash(zone, dns.TypeNS, false))
+	if !ok {
+		return nil
+	}
+	d := i.(*delegation)
+	if now.After(d.expires) || len(d.addrs) == 0 {
+		return nil
+	}
+	return d
+}
+
+// addDelegation adds d to the infrastructure cache.
+func (r *Recursive) addDelegation(d *delegation) {
+	r.infra.Add(hash(d.zone, dns.TypeNS, false), d)
+}
+
+// rtt is the smoothed round trip time of a name server.
+type rtt struct {
+	sync.Mutex
+	srtt time.Duration
+}
+
+const (
+	initialRTT = 376 * time.Millisecond // RTT of name servers we haven't queried yet.
+	maxRTT     = 2 * time.Minute
+)
+
+// rtt returns the smoothed round trip time to the name server with address ip.
+func (r *Recursive) rtt(ip string) time.Duration {
+	i, ok := r.rtts.Get(hash(ip, 0, false))
+	if !ok {
+		return initialRTT
+	}
+	t := i.(*rtt)
+	t.Lock()
+	defer t.Unlock()
+	return t.srtt
+}
+
+// updateRTT records the round trip time d of a query to the name server with address ip. A failed
+// query doubles the RTT, which makes other name servers of the zone more likely to be picked.
+func (r *Recursive) updateRTT(ip string, d time.Duration, failed bool) {
+	k := hash(ip, 0, false)
+	i, ok := r.rtts.Get(k)
+	if !ok {
+		i = &rtt{srtt: initialRTT}
+		r.rtts.Add(k, i)
+	}
+	t := i.(*rtt)
+	t.Lock()
+	defer t.Unlock()
+	if failed {
+		t.srtt *= 2
+		if t.srtt > maxRTT {
+			t.srtt = maxRTT
+		}
+		return
+	}
+	t.srtt = (7*t.srtt + 3*d) / 10
+}
+
+// byRTT returns the addresses sorted by their round trip time, fastest first.
+func (r *Recursive) byRTT(addrs []string) []string {
+	rtts := make(map[string]time.Duration, len(addrs))
+	for _, a := range addrs {
+		rtts[a] = r.rtt(a)
+	}
+	sorted := make([]string, len(addrs))
+	copy(sorted, addrs)
+	sort.SliceStable(sorted, func(i, j int) bool { return rtts[sorted[i]] < rtts[sorted[j]] })
+	return sorted
+}
+
+// glue returns the addresses of the name servers ns in rrs.
+func glue(ns []string, rrs []dns.RR) []string {
+	var addrs []string
+	for _, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		found := false
+		for _, n := range ns {
+			if n == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		switch x := rr.(type) {
+		case *dns.A:
+			addrs = append(addrs, x.A.String())
+		case *dns.AAAA:
+			addrs = append(addrs, x.AAAA.String())
+		}
+	}
+	return addrs
+}
+
+func hash(name string, qtype uint16, do bool) uint64 {
+	h := fnv.New64()
+	if do {
+		h.Write([]byte("1"))
+	} else {
+		h.Write([]byte("0"))
+	}
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, qtype)
+	h.Write(b)
+	h.Write([]byte(strings.ToLower(name)))
+	return h.Sum64()
+}
@@ -0,0 +1,25 @@
+package recursive
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	// QueryCount is the number of queries sent to authoritative name servers.
+	QueryCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "recursive",
+		Name:      "queries_total",
+		Help:      "Counter of queries sent to authoritative name servers.",
+	}, []string{"server"})
+	// FailureCount is the number of queries that could not be resolved.
+	FailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "recursive",
+		Name:      "failures_total",
+		Help:      "Counter of queries that could not be resolved.",
+	}, []string{"server"})
+)
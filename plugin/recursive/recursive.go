@@ -0,0 +1,96 @@
+// Package recursive implements a plugin that resolves queries iteratively, starting at the root
+// name servers.
+package recursive
+
+import (
+	"context"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/singleflight"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Recursive is a recursive resolver.
+type Recursive struct {
+	Next  plugin.Handler
+	Zones []string
+
+	hints *delegation // the root hints.
+
+	minimise bool // QNAME minimisation, RFC 7816.
+	caseRand bool // 0x20 case randomisation of outgoing queries.
+	timeout  time.Duration
+	port     string // port of the name servers, only changed for testing.
+
+	infra *cache.Cache // delegations by zone
+	rtts  *cache.Cache // round trip times by name server address
+	group *singleflight.Group
+}
+
+// New returns a new Recursive that uses the built-in root hints.
+func New() *Recursive {
+	return &Recursive{
+		hints:    rootHints(),
+		minimise: true,
+		caseRand: true,
+		timeout:  defaultTimeout,
+		port:     "53",
+		infra:    cache.New(defaultCap),
+		rtts:     cache.New(defaultCap),
+		group:    new(singleflight.Group),
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (r *Recursive) ServeDNS(ctx context.Context, w dns.ResponseWriter, req *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: req}
+	if plugin.Zones(r.Zones).Matches(state.Name()) == "" || !req.RecursionDesired {
+		return plugin.NextOrFailure(r.Name(), r.Next, ctx, w, req)
+	}
+
+	server := metrics.WithServer(ctx)
+	ctx, cancel := context.WithTimeout(ctx, maxResolveTime)
+	defer cancel()
+
+	do := state.Do()
+	key := hash(state.Name(), state.QType(), do)
+	i, err := r.group.Do(key, func() (interface{}, error) {
+		return r.resolve(ctx, server, state.Name(), state.QType(), do, 0)
+	})
+	if err != nil {
+		FailureCount.WithLabelValues(server).Inc()
+		log.Debugf("Failed to resolve %s/%s: %s", state.Name(), state.Type(), err)
+		return dns.RcodeServerFailure, err
+	}
+	res := i.(*dns.Msg)
+
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.RecursionAvailable = true
+	m.Rcode = res.Rcode
+	// The result may be shared with other queries, copy the records.
+	for _, rr := range res.Answer {
+		m.Answer = append(m.Answer, dns.Copy(rr))
+	}
+	for _, rr := range res.Ns {
+		m.Ns = append(m.Ns, dns.Copy(rr))
+	}
+	state.SizeAndDo(m)
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the Handler interface.
+func (r *Recursive) Name() string { return "recursive" }
+
+const (
+	defaultTimeout = 2 * time.Second
+	maxResolveTime = 10 * time.Second // maximum time spent resolving a single query.
+	defaultCap     = 10000            // capacity of the infrastructure caches.
+)
@@ -0,0 +1,236 @@
+package recursive
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const rootZone = `.	3600	IN	SOA	ns.root. hostmaster.root. 1 7200 3600 1209600 3600
+.	3600	IN	NS	ns.root.
+ns.root.	3600	IN	A	127.0.0.1
+org.	3600	IN	NS	ns.org.
+ns.org.	3600	IN	A	127.0.0.2
+`
+
+const orgZone = `org.	3600	IN	SOA	ns.org. hostmaster.org. 1 7200 3600 1209600 3600
+org.	3600	IN	NS	ns.org.
+ns.org.	3600	IN	A	127.0.0.2
+example.org.	3600	IN	NS	ns1.example.org.
+ns1.example.org.	3600	IN	A	127.0.0.3
+other.org.	3600	IN	NS	ns2.example.org.
+`
+
+const exampleZone = `example.org.	3600	IN	SOA	ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600
+example.org.	3600	IN	NS	ns1.example.org.
+ns1.example.org.	3600	IN	A	127.0.0.3
+ns2.example.org.	3600	IN	A	127.0.0.3
+www.example.org.	3600	IN	A	127.0.1.1
+alias.example.org.	3600	IN	CNAME	www.other.org.
+deep.a.b.example.org.	3600	IN	A	127.0.1.2
+`
+
+const otherZone = `other.org.	3600	IN	SOA	ns.example.org. hostmaster.other.org. 1 7200 3600 1209600 3600
+other.org.	3600	IN	NS	ns2.example.org.
+www.other.org.	3600	IN	A	127.0.1.3
+`
+
+// authServer is an authoritative name server serving zones with the file plugin. It records the
+// questions it receives.
+type authServer struct {
+	*dns.Server
+	sync.Mutex
+	questions []dns.Question
+}
+
+func (a *authServer) seen() []dns.Question {
+	a.Lock()
+	defer a.Unlock()
+	return append([]dns.Question{}, a.questions...)
+}
+
+func newAuthServer(t *testing.T, addr string, zones map[string]string) *authServer {
+	f := file.File{Zones: file.Zones{Z: map[string]*file.Zone{}}}
+	for origin, z := range zones {
+		zone, err := file.Parse(strings.NewReader(z), origin, "stdin", 0)
+		if err != nil {
+			t.Fatalf("Failed to parse zone %s: %s", origin, err)
+		}
+		f.Zones.Z[origin] = zone
+		f.Zones.Names = append(f.Zones.Names, origin)
+	}
+
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %s", addr, err)
+	}
+	a := &authServer{}
+	a.Server = &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		a.Lock()
+		a.questions = append(a.questions, r.Question[0])
+		a.Unlock()
+		f.ServeDNS(context.TODO(), w, r)
+	})}
+	go a.ActivateAndServe()
+	return a
+}
+
+// newTestRecursive starts the name servers for the test zones on 127.0.0.1 to 127.0.0.3, all on the
+// same port.
+func newTestRecursive(t *testing.T) (*Recursive, []*authServer) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(pc.LocalAddr().String())
+	pc.Close()
+
+	servers := []*authServer{
+		newAuthServer(t, "127.0.0.1:"+port, map[string]string{".": rootZone}),
+		newAuthServer(t, "127.0.0.2:"+port, map[string]string{"org.": orgZone}),
+		newAuthServer(t, "127.0.0.3:"+port, map[string]string{"example.org.": exampleZone, "other.org.": otherZone}),
+	}
+
+	r := New()
+	r.Zones = []string{"."}
+	r.hints = &delegation{zone: ".", ns: []string{"ns.root."}, addrs: []string{"127.0.0.1"}}
+	r.port = port
+	return r, servers
+}
+
+func shutdown(servers []*authServer) {
+	for _, s := range servers {
+		s.Shutdown()
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	r, servers := newTestRecursive(t)
+	defer shutdown(servers)
+
+	tests := []test.Case{
+		{
+			Qname: "www.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example.org. 3600 IN A 127.0.1.1")},
+		},
+		{
+			// CNAME into another zone, which has a delegation without glue.
+			Qname: "alias.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("alias.example.org. 3600 IN CNAME www.other.org."),
+				test.A("www.other.org. 3600 IN A 127.0.1.3"),
+			},
+		},
+		{
+			// Empty non-terminals on the way.
+			Qname: "deep.a.b.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("deep.a.b.example.org. 3600 IN A 127.0.1.2")},
+		},
+		{
+			Qname: "nope.example.org.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600"),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		m := tc.Msg()
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Errorf("Expected no error for %s, got %s", tc.Qname, err)
+			continue
+		}
+		if !rec.Msg.RecursionAvailable {
+			t.Errorf("Expected RA bit to be set for %s", tc.Qname)
+		}
+		test.SortAndCheck(t, rec.Msg, tc)
+	}
+}
+
+func TestRecursiveMinimise(t *testing.T) {
+	r, servers := newTestRecursive(t)
+	defer shutdown(servers)
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	r.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+
+	// The root name servers only get to see org.
+	for _, q := range servers[0].seen() {
+		if !strings.EqualFold(q.Name, "org.") || q.Qtype != dns.TypeNS {
+			t.Errorf("Expected root name server to only see org./NS, got %s/%s", q.Name, dns.TypeToString[q.Qtype])
+		}
+	}
+
+	// The delegations are cached, the next query goes straight to the name server of example.org.
+	before := len(servers[0].seen()) + len(servers[1].seen())
+	m.SetQuestion("deep.a.b.example.org.", dns.TypeA)
+	r.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+	if after := len(servers[0].seen()) + len(servers[1].seen()); after != before {
+		t.Errorf("Expected no queries to the root and org. name servers, got %d", after-before)
+	}
+}
+
+func TestRecursiveNoMinimise(t *testing.T) {
+	r, servers := newTestRecursive(t)
+	defer shutdown(servers)
+	r.minimise = false
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	r.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+
+	seen := servers[0].seen()
+	if len(seen) != 1 || !strings.EqualFold(seen[0].Name, "www.example.org.") {
+		t.Errorf("Expected root name server to see the full name, got %v", seen)
+	}
+}
+
+func TestRecursiveFailover(t *testing.T) {
+	r, servers := newTestRecursive(t)
+	defer shutdown(servers)
+
+	// 127.0.0.4 doesn't run a name server, the query must still be answered.
+	r.hints.addrs = []string{"127.0.0.4", "127.0.0.1"}
+	r.rtts.Add(hash("127.0.0.1", 0, false), &rtt{srtt: 2 * initialRTT})
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected 1 answer, got %v", rec.Msg.Answer)
+	}
+	if r.rtt("127.0.0.4") <= r.rtt("127.0.0.1") {
+		t.Errorf("Expected the failing name server to have a higher RTT")
+	}
+}
+
+func TestRandomCase(t *testing.T) {
+	name := "www.example.org."
+	mixed := false
+	for i := 0; i < 10; i++ {
+		r := randomCase(name)
+		if !strings.EqualFold(r, name) {
+			t.Fatalf("Expected %s to equal %s", r, name)
+		}
+		if r != name {
+			mixed = true
+		}
+	}
+	if !mixed {
+		t.Errorf("Expected the case of %s to be randomised", name)
+	}
+}
@@ -0,0 +1,263 @@
+package recursive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	maxReferrals = 30             // maximum number of queries to resolve a single name.
+	maxDepth     = 6              // maximum nesting of name server address lookups.
+	maxCNAME     = 8              // maximum length of a CNAME chain.
+	maxTries     = 4              // maximum number of name servers tried for a query.
+	maxInfraTTL  = 24 * time.Hour // maximum time a delegation is cached.
+)
+
+var (
+	errMaxDepth  = errors.New("maximum resolution depth exceeded")
+	errReferrals = errors.New("too many referrals")
+	errNoServers = errors.New("no reachable name servers")
+)
+
+// resolve resolves qname and qtype, CNAMEs that point into other zones are followed.
+func (r *Recursive) resolve(ctx context.Context, server, qname string, qtype uint16, do bool, depth int) (*dns.Msg, error) {
+	if depth > maxDepth {
+		return nil, errMaxDepth
+	}
+
+	res := new(dns.Msg)
+	name := strings.ToLower(qname)
+	seen := map[string]bool{name: true}
+	for i := 0; i < maxCNAME; i++ {
+		m, err := r.iterate(ctx, server, name, qtype, do, depth)
+		if err != nil {
+			return nil, err
+		}
+		res.Rcode = m.Rcode
+		res.Answer = append(res.Answer, m.Answer...)
+		res.Ns = m.Ns
+
+		target := cnameTarget(name, qtype, m.Answer)
+		if target == "" || seen[target] {
+			break
+		}
+		seen[target] = true
+		name = target
+	}
+	if len(res.Answer) > 0 {
+		// Only negative responses need the authority section.
+		res.Ns = nil
+	}
+	return res, nil
+}
+
+// iterate resolves name and qtype by following the delegations from the closest known zone down.
+func (r *Recursive) iterate(ctx context.Context, server, name string, qtype uint16, do bool, depth int) (*dns.Msg, error) {
+	start := name
+	if qtype == dns.TypeDS {
+		// The DS records are in the parent zone.
+		start = parent(name)
+	}
+	d := r.closest(start, time.Now())
+	minimise := r.minimise
+	labels := dns.CountLabel(d.zone) + 1
+
+	for i := 0; i < maxReferrals; i++ {
+		qname, qt := name, qtype
+		if minimise && labels < dns.CountLabel(name) {
+			// RFC 7816: only send the labels the name servers of the zone need to see.
+			qname, qt = lastLabels(name, labels), dns.TypeNS
+		}
+
+		m, err := r.query(ctx, server, d, qname, qt, do)
+		if err != nil {
+			return nil, err
+		}
+
+		if child := referral(m, d.zone, qname); child != nil {
+			child.addrs = glue(child.ns, inBailiwick(m.Extra, d.zone))
+			if len(child.addrs) == 0 {
+				child.addrs = r.lookupAddrs(ctx, server, child, depth+1)
+			}
+			if len(child.addrs) == 0 {
+				return nil, fmt.Errorf("no addresses for the name servers of %s", child.zone)
+			}
+			r.addDelegation(child)
+			d = child
+			labels = dns.CountLabel(d.zone) + 1
+			continue
+		}
+
+		if qname == name {
+			m.Answer = inBailiwick(m.Answer, d.zone)
+			m.Ns = inBailiwick(m.Ns, d.zone)
+			return m, nil
+		}
+		if m.Rcode != dns.RcodeSuccess {
+			// Some name servers get this wrong for empty non-terminals, continue with the full name.
+			minimise = false
+			continue
+		}
+		labels++
+	}
+	return nil, errReferrals
+}
+
+// query sends the query for qname and qtype to the name servers of d, the fastest name server is
+// tried first.
+func (r *Recursive) query(ctx context.Context, server string, d *delegation, qname string, qtype uint16, do bool) (*dns.Msg, error) {
+	addrs := r.byRTT(d.addrs)
+	if len(addrs) > maxTries {
+		addrs = addrs[:maxTries]
+	}
+
+	var lastErr error
+	for _, a := range addrs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		QueryCount.WithLabelValues(server).Inc()
+		m, err := r.exchange(ctx, a, qname, qtype, do)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if m.Rcode == dns.RcodeSuccess || m.Rcode == dns.RcodeNameError {
+			return m, nil
+		}
+		// A lame or broken name server, try the next one.
+		lastErr = fmt.Errorf("%s for %s/%s from %s", dns.RcodeToString[m.Rcode], qname, dns.TypeToString[qtype], a)
+	}
+	if lastErr == nil {
+		lastErr = errNoServers
+	}
+	return nil, lastErr
+}
+
+// lookupAddrs resolves the addresses of the name servers of d, for delegations without glue. It stops
+// at the first name server that has addresses.
+func (r *Recursive) lookupAddrs(ctx context.Context, server string, d *delegation, depth int) []string {
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		for _, ns := range d.ns {
+			if dns.IsSubDomain(d.zone, ns) {
+				// Needs glue, which wasn't there.
+				continue
+			}
+			m, err := r.resolve(ctx, server, ns, qtype, false, depth)
+			if err != nil {
+				log.Debugf("Failed to resolve name server %s of %s: %s", ns, d.zone, err)
+				continue
+			}
+			var addrs []string
+			for _, rr := range m.Answer {
+				switch x := rr.(type) {
+				case *dns.A:
+					addrs = append(addrs, x.A.String())
+				case *dns.AAAA:
+					addrs = append(addrs, x.AAAA.String())
+				}
+			}
+			if len(addrs) > 0 {
+				return addrs
+			}
+		}
+	}
+	return nil
+}
+
+// referral returns the delegation in m, if m is a referral from zone to a zone closer to qname.
+func referral(m *dns.Msg, zone, qname string) *delegation {
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) > 0 {
+		return nil
+	}
+
+	d := &delegation{}
+	ttl := uint32(maxInfraTTL.Seconds())
+	for _, rr := range m.Ns {
+		switch x := rr.(type) {
+		case *dns.SOA:
+			return nil
+		case *dns.NS:
+			owner := strings.ToLower(x.Hdr.Name)
+			if owner == zone || !dns.IsSubDomain(zone, owner) || !dns.IsSubDomain(owner, qname) {
+				continue
+			}
+			if d.zone != "" && d.zone != owner {
+				continue
+			}
+			d.zone = owner
+			d.ns = append(d.ns, strings.ToLower(x.Ns))
+			if x.Hdr.Ttl < ttl {
+				ttl = x.Hdr.Ttl
+			}
+		}
+	}
+	if d.zone == "" {
+		return nil
+	}
+	d.expires = time.Now().Add(time.Duration(ttl) * time.Second)
+	return d
+}
+
+// cnameTarget follows the CNAMEs in answer from name. If the chain ends at a name without data of
+// type qtype, that name is returned.
+func cnameTarget(name string, qtype uint16, answer []dns.RR) string {
+	if qtype == dns.TypeCNAME || qtype == dns.TypeANY {
+		return ""
+	}
+	target := name
+	for i := 0; i < maxCNAME; i++ {
+		next := ""
+		for _, rr := range answer {
+			hdr := rr.Header()
+			if !strings.EqualFold(hdr.Name, target) {
+				continue
+			}
+			if hdr.Rrtype == qtype {
+				return ""
+			}
+			if c, ok := rr.(*dns.CNAME); ok {
+				next = strings.ToLower(c.Target)
+			}
+		}
+		if next == "" {
+			break
+		}
+		target = next
+	}
+	if target == name {
+		return ""
+	}
+	return target
+}
+
+// inBailiwick returns the records in rrs that belong to zone.
+func inBailiwick(rrs []dns.RR, zone string) []dns.RR {
+	out := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeOPT || dns.IsSubDomain(zone, rr.Header().Name) {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+// parent returns the parent of name, the parent of the root zone is the root zone.
+func parent(name string) string {
+	off, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[off:]
+}
+
+// lastLabels returns the last n labels of name.
+func lastLabels(name string, n int) string {
+	idx := dns.Split(name)
+	return name[idx[len(idx)-n]:]
+}
@@ -0,0 +1,107 @@
+package recursive
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/mholt/caddy"
+)
+
+var log = clog.NewWithPlugin("recursive")
+
+func init() {
+	caddy.RegisterPlugin("recursive", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	r, err := recursiveParse(c)
+	if err != nil {
+		return plugin.Error("recursive", err)
+	}
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, QueryCount, FailureCount)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		r.Next = next
+		return r
+	})
+
+	return nil
+}
+
+func recursiveParse(c *caddy.Controller) (*Recursive, error) {
+	r := New()
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		r.Zones = make([]string, len(c.ServerBlockKeys))
+		copy(r.Zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			r.Zones = args
+		}
+		for i := range r.Zones {
+			r.Zones[i] = plugin.Host(r.Zones[i]).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "root_hints":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				file := args[0]
+				if !filepath.IsAbs(file) && config.Root != "" {
+					file = filepath.Join(config.Root, file)
+				}
+				d, err := readHints(file)
+				if err != nil {
+					return nil, err
+				}
+				r.hints = d
+			case "no_qname_minimisation":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				r.minimise = false
+			case "no_case_randomisation":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				r.caseRand = false
+			case "timeout":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return nil, err
+				}
+				if d <= 0 {
+					return nil, c.Errf("timeout can't be negative or zero: %s", d)
+				}
+				r.timeout = d
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return r, nil
+}
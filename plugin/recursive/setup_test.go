@@ -0,0 +1,97 @@
+package recursive
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recursive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	hintsFile := filepath.Join(dir, "named.root")
+	if err := ioutil.WriteFile(hintsFile, []byte(`.	3600000	NS	A.ROOT-SERVERS.NET.
+A.ROOT-SERVERS.NET.	3600000	A	198.41.0.4
+A.ROOT-SERVERS.NET.	3600000	AAAA	2001:503:ba3e::2:30
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := ioutil.WriteFile(emptyFile, []byte(`.	3600000	NS	A.ROOT-SERVERS.NET.
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     []string
+		minimise  bool
+		caseRand  bool
+		timeout   time.Duration
+		hints     int
+	}{
+		{`recursive`, false, []string{"."}, true, true, defaultTimeout, 26},
+		{`recursive example.org`, false, []string{"example.org."}, true, true, defaultTimeout, 26},
+		{`recursive {
+			no_qname_minimisation
+			no_case_randomisation
+			timeout 1s
+		}`, false, []string{"."}, false, false, time.Second, 26},
+		{`recursive {
+			root_hints ` + hintsFile + `
+		}`, false, []string{"."}, true, true, defaultTimeout, 2},
+		// fails
+		{`recursive {
+			root_hints ` + emptyFile + `
+		}`, true, nil, false, false, 0, 0},
+		{`recursive {
+			root_hints /does/not/exist
+		}`, true, nil, false, false, 0, 0},
+		{`recursive {
+			timeout -1s
+		}`, true, nil, false, false, 0, 0},
+		{`recursive {
+			no_qname_minimisation yes
+		}`, true, nil, false, false, 0, 0},
+		{`recursive {
+			blah
+		}`, true, nil, false, false, 0, 0},
+		{"recursive\nrecursive", true, nil, false, false, 0, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		c.ServerBlockKeys = []string{"."}
+		r, err := recursiveParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(r.Zones) != 1 || r.Zones[0] != tc.zones[0] {
+			t.Errorf("Test %d: expected zones %v, got %v", i, tc.zones, r.Zones)
+		}
+		if r.minimise != tc.minimise || r.caseRand != tc.caseRand {
+			t.Errorf("Test %d: expected minimise %t and case randomisation %t", i, tc.minimise, tc.caseRand)
+		}
+		if r.timeout != tc.timeout {
+			t.Errorf("Test %d: expected timeout %s, got %s", i, tc.timeout, r.timeout)
+		}
+		if len(r.hints.addrs) != tc.hints {
+			t.Errorf("Test %d: expected %d root hint addresses, got %d", i, tc.hints, len(r.hints.addrs))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package reload
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status is the state of the running Corefile and the result of the last reload.
+type Status struct {
+	Hash       string    `json:"hash"` // MD5 of the running Corefile.
+	LastReload time.Time `json:"last_reload"`
+	Result     string    `json:"result,omitempty"` // success, invalid or failed.
+	Error      string    `json:"error,omitempty"`
+}
+
+var (
+	statusMu sync.RWMutex
+	status   Status
+)
+
+const (
+	statusSuccess = "success"
+	statusInvalid = "invalid"
+	statusFailed  = "failed"
+)
+
+// setRunning records the MD5 of the running Corefile.
+func setRunning(md5sum [md5.Size]byte) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	status.Hash = hash(md5sum)
+
+	ConfigInfo.Reset()
+	ConfigInfo.WithLabelValues(status.Hash).Set(1)
+}
+
+// setStatus records the result of a reload.
+func setStatus(result string, err error) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	status.LastReload = time.Now().UTC()
+	status.Result = result
+	status.Error = ""
+	if err != nil {
+		status.Error = err.Error()
+	}
+}
+
+// getStatus returns a copy of the current status.
+func getStatus() Status {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	return status
+}
+
+// server serves the reload status and on demand reloads over HTTP.
+type server struct {
+	addr string
+	ln   net.Listener
+}
+
+func (s *server) OnStartup() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, serveHTTP)
+	go func() { http.Serve(s.ln, mux) }()
+	return nil
+}
+
+func (s *server) OnShutdown() error {
+	if s.ln == nil {
+		return nil
+	}
+	err := s.ln.Close()
+	s.ln = nil
+	return err
+}
+
+// serveHTTP returns the status with a GET and triggers a reload with a POST. The reload happens after
+// the response is sent.
+func serveHTTP(w http.ResponseWriter, r *http.Request) {
+	code := http.StatusOK
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		trigger()
+		code = http.StatusAccepted
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(getStatus())
+}
+
+const path = "/reload"
@@ -0,0 +1,55 @@
+package reload
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHTTP(t *testing.T) {
+	setRunning(md5.Sum([]byte(". {\n reload\n}\n")))
+	setStatus(statusInvalid, errors.New("unknown directive 'foo'"))
+	// Drain any pending reload.
+	select {
+	case <-r.trigger:
+	default:
+	}
+
+	rec := httptest.NewRecorder()
+	serveHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+	st := Status{}
+	if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Hash != hash(md5.Sum([]byte(". {\n reload\n}\n"))) || st.Result != statusInvalid || st.Error != "unknown directive 'foo'" {
+		t.Errorf("Unexpected status: %+v", st)
+	}
+	select {
+	case <-r.trigger:
+		t.Errorf("Expected no reload to be triggered by a GET")
+	default:
+	}
+
+	rec = httptest.NewRecorder()
+	serveHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected status code %d, got %d", http.StatusAccepted, rec.Code)
+	}
+	select {
+	case <-r.trigger:
+	default:
+		t.Errorf("Expected a reload to be triggered by a POST")
+	}
+
+	rec = httptest.NewRecorder()
+	serveHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
@@ -0,0 +1,33 @@
+package reload
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics for the reload plugin.
+var (
+	// ReloadCount is the number of reloads per result: success, invalid (the dry run failed) or
+	// failed (the restart failed).
+	ReloadCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "reload",
+		Name:      "count_total",
+		Help:      "Counter of Corefile reloads per result.",
+	}, []string{"result"})
+	// RollbackCount is the number of rollbacks to the last good Corefile after a failed reload.
+	RollbackCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "reload",
+		Name:      "rollback_count_total",
+		Help:      "Counter of rollbacks to the last good Corefile.",
+	})
+	// ConfigInfo has the MD5 of the running Corefile as its label, the value is always 1.
+	ConfigInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "reload",
+		Name:      "config_info",
+		Help:      "Info metric with the MD5 of the running Corefile.",
+	}, []string{"hash"})
+)
@@ -2,12 +2,17 @@ package reload
 
 import (
 	"crypto/md5"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/mholt/caddy"
 )
 
 // reload periodically checks if the Corefile has changed, and reloads if so
+
 const (
 	unused    = 0
 	maybeUsed = 1
@@ -18,6 +23,8 @@ type reload struct {
 	interval time.Duration
 	usage    int
 	quit     chan bool
+	trigger  chan bool      // on demand reloads, from SIGHUP or the HTTP endpoint.
+	failed   [md5.Size]byte // MD5 of the last Corefile that failed to load, it isn't tried again.
 }
 
 func hook(event caddy.EventName, info interface{}) error {
@@ -36,40 +43,117 @@ func hook(event caddy.EventName, info interface{}) error {
 	instance := info.(*caddy.Instance)
 	md5sum := md5.Sum(instance.Caddyfile().Body())
 	log.Infof("Running configuration MD5 = %x\n", md5sum)
+	setRunning(md5sum)
 
 	go func() {
 		tick := time.NewTicker(r.interval)
+		defer tick.Stop()
 
 		for {
+			force := false
 			select {
 			case <-tick.C:
-				corefile, err := caddy.LoadCaddyfile(instance.Caddyfile().ServerType())
-				if err != nil {
-					continue
-				}
-				s := md5.Sum(corefile.Body())
-				if s != md5sum {
-					// Let not try to restart with the same file, even though it is wrong.
-					md5sum = s
-					// now lets consider that plugin will not be reload, unless appear in next config file
-					// change status iof usage will be reset in setup if the plugin appears in config file
-					r.usage = maybeUsed
-					_, err := instance.Restart(corefile)
-					if err != nil {
-						log.Errorf("Corefile changed but reload failed: %s\n", err)
-						continue
-					}
-					// we are done, if the plugin was not set used, then it is not.
-					if r.usage == maybeUsed {
-						r.usage = unused
-					}
-					return
-				}
+			case <-r.trigger:
+				force = true
 			case <-r.quit:
 				return
 			}
+			if reloadCorefile(instance, md5sum, force) {
+				// instance is replaced, the hook is called for the new one.
+				return
+			}
 		}
 	}()
 
 	return nil
 }
+
+// reloadCorefile loads the Corefile and restarts instance with it when it differs from the running one
+// (with MD5 md5sum) or when force is true. It returns true when instance was replaced.
+func reloadCorefile(instance *caddy.Instance, md5sum [md5.Size]byte, force bool) bool {
+	corefile, err := caddy.LoadCaddyfile(instance.Caddyfile().ServerType())
+	if err != nil {
+		if force {
+			log.Errorf("Failed to load Corefile: %s", err)
+			setStatus(statusFailed, err)
+			ReloadCount.WithLabelValues(statusFailed).Inc()
+		}
+		return false
+	}
+	s := md5.Sum(corefile.Body())
+	// Let not try to restart with the same file, even though it is wrong.
+	if !force && (s == md5sum || s == r.failed) {
+		return false
+	}
+
+	if err := validate(corefile); err != nil {
+		log.Errorf("Corefile changed but is invalid, not reloading: %s", err)
+		r.failed = s
+		setStatus(statusInvalid, err)
+		ReloadCount.WithLabelValues(statusInvalid).Inc()
+		return false
+	}
+
+	// now lets consider that plugin will not be reload, unless appear in next config file
+	// change status iof usage will be reset in setup if the plugin appears in config file
+	r.usage = maybeUsed
+	if _, err := instance.Restart(corefile); err != nil {
+		log.Errorf("Corefile changed but reload failed: %s", err)
+		r.failed = s
+		setStatus(statusFailed, err)
+		ReloadCount.WithLabelValues(statusFailed).Inc()
+		return rollback(instance)
+	}
+	// we are done, if the plugin was not set used, then it is not.
+	if r.usage == maybeUsed {
+		r.usage = unused
+	}
+	setStatus(statusSuccess, nil)
+	ReloadCount.WithLabelValues(statusSuccess).Inc()
+	return true
+}
+
+// validate does a dry run of the setup of all plugins in corefile, without starting any servers.
+func validate(corefile caddy.Input) error {
+	// The dry run also runs our own setup, which must not change the running configuration.
+	interval, usage := r.interval, r.usage
+	defer func() { r.interval, r.usage = interval, usage }()
+
+	return caddy.ValidateAndExecuteDirectives(corefile, nil, true)
+}
+
+// rollback restarts instance with its own, last good, Corefile. A failed restart may already have
+// stopped parts of the running instance, i.e. the listeners of the health and prometheus plugins, the
+// restart brings them back. It returns true when instance was replaced.
+func rollback(instance *caddy.Instance) bool {
+	r.usage = maybeUsed
+	if _, err := instance.Restart(instance.Caddyfile()); err != nil {
+		log.Errorf("Failed to roll back to the last good Corefile: %s", err)
+		r.usage = used
+		return false
+	}
+	log.Infof("Rolled back to the last good Corefile")
+	RollbackCount.Inc()
+	return true
+}
+
+// trigger requests a reload, even when the Corefile didn't change. It doesn't block.
+func trigger() {
+	select {
+	case r.trigger <- true:
+	default:
+	}
+}
+
+// sighup triggers a reload for each SIGHUP that is received.
+func sighup() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		log.Info("SIGHUP: Reloading")
+		trigger()
+	}
+}
+
+// hash returns md5sum as a string.
+func hash(md5sum [md5.Size]byte) string { return fmt.Sprintf("%x", md5sum) }
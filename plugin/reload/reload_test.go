@@ -0,0 +1,69 @@
+package reload
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestValidate(t *testing.T) {
+	r.interval, r.usage = 10*time.Second, used
+
+	tests := []struct {
+		corefile  string
+		shouldErr bool
+	}{
+		{". {\n reload 2s\n}\n", false},
+		{". {\n reload foo\n}\n", true},
+		{". {\n reload\n", true},
+		{"example.org {\n reload 10s 2s 1s\n}\n", true},
+	}
+
+	for i, tc := range tests {
+		err := validate(caddy.CaddyfileInput{Contents: []byte(tc.corefile), ServerTypeName: "dns"})
+		if tc.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error, got none", i)
+		}
+		if !tc.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+		}
+		if r.interval != 10*time.Second || r.usage != used {
+			t.Errorf("Test %d: expected the running configuration not to be changed, got %s and %d", i, r.interval, r.usage)
+		}
+	}
+}
+
+func TestValidateMetricsAddress(t *testing.T) {
+	corefile := ".:0 {\n reload\n prometheus 127.0.0.1:53188\n}\n"
+	i, err := caddy.Start(caddy.CaddyfileInput{Contents: []byte(corefile), ServerTypeName: "dns"})
+	if err != nil {
+		t.Fatalf("Could not start instance: %s", err)
+	}
+
+	// Change the prometheus address, the dry run must not claim the new address.
+	corefile = ".:0 {\n reload\n prometheus 127.0.0.1:53189\n}\n"
+	input := caddy.CaddyfileInput{Contents: []byte(corefile), ServerTypeName: "dns"}
+	if err := validate(input); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	i, err = i.Restart(input)
+	if err != nil {
+		t.Fatalf("Could not restart instance: %s", err)
+	}
+	defer i.Stop()
+
+	resp, err := http.Get("http://127.0.0.1:53189/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	// Only the metrics plugin of the running instance has the metrics of the reload plugin.
+	if !strings.Contains(string(body), "coredns_reload_rollback_count_total") {
+		t.Errorf("Expected the reload metrics to be exported on the new address, got %s", body)
+	}
+}
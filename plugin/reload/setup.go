@@ -3,10 +3,12 @@ package reload
 import (
 	"fmt"
 	"math/rand"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 
 	"github.com/mholt/caddy"
@@ -25,7 +27,7 @@ func init() {
 // it is used to transmit data between Setup and start of the hook called 'onInstanceStartup'
 // channel for QUIT is never changed in purpose.
 // WARNING: this data may be unsync after an invalid attempt of reload Corefile.
-var r = reload{interval: defaultInterval, usage: unused, quit: make(chan bool)}
+var r = reload{interval: defaultInterval, usage: unused, quit: make(chan bool), trigger: make(chan bool, 1)}
 var once sync.Once
 var shutOnce sync.Once
 
@@ -65,6 +67,23 @@ func setup(c *caddy.Controller) error {
 		j = i / 2
 	}
 
+	addr := ""
+	for c.NextBlock() {
+		switch c.Val() {
+		case "listen":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return plugin.Error("reload", c.ArgErr())
+			}
+			if _, _, err := net.SplitHostPort(args[0]); err != nil {
+				return plugin.Error("reload", err)
+			}
+			addr = args[0]
+		default:
+			return plugin.Error("reload", c.Errf("unknown property '%s'", c.Val()))
+		}
+	}
+
 	jitter := time.Duration(rand.Int63n(j.Nanoseconds()) - (j.Nanoseconds() / 2))
 	i = i + jitter
 
@@ -74,6 +93,7 @@ func setup(c *caddy.Controller) error {
 
 	once.Do(func() {
 		caddy.RegisterEventHook("reload", hook)
+		go sighup()
 	})
 
 	// re-register on finalShutDown as the instance most-likely will be changed
@@ -83,6 +103,18 @@ func setup(c *caddy.Controller) error {
 			return nil
 		})
 	})
+	if addr != "" {
+		s := &server{addr: addr}
+		c.OnStartup(s.OnStartup)
+		c.OnRestart(s.OnShutdown)
+		c.OnFinalShutdown(s.OnShutdown)
+	}
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, ReloadCount, RollbackCount, ConfigInfo)
+		return nil
+	})
+
 	return nil
 }
 
@@ -0,0 +1,321 @@
+package rpz
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/file"
+
+	"github.com/miekg/dns"
+)
+
+// policy is a response policy zone. The zone is loaded from a file or transferred from a primary, the
+// triggers are rebuilt from it whenever its serial changes.
+type policy struct {
+	name string
+	zone *file.Zone
+
+	mu     sync.RWMutex
+	serial int64 // serial of the zone the triggers were built from, -1 if they haven't been built.
+	t      *triggers
+}
+
+func newPolicy(name string, z *file.Zone) *policy {
+	return &policy{name: name, zone: z, serial: -1}
+}
+
+// triggers returns the triggers of the policy zone.
+func (p *policy) triggers() *triggers {
+	serial := p.zone.SOASerialIfDefined()
+	if serial < 0 {
+		// Not loaded (or transferred) yet.
+		return emptyTriggers
+	}
+
+	p.mu.RLock()
+	t := p.t
+	current := p.serial == serial
+	p.mu.RUnlock()
+	if current {
+		return t
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.serial == serial {
+		return p.t
+	}
+	rrs := p.zone.All()
+	soa := rrs[0].(*dns.SOA)
+	p.t = build(p.name, soa, rrs[1:])
+	p.serial = int64(soa.Serial)
+	log.Infof("Loaded policy zone %s with serial %d", p.name, soa.Serial)
+	return p.t
+}
+
+// triggers holds the rules of a policy zone by trigger type.
+type triggers struct {
+	soa      *dns.SOA // SOA record of the policy zone, for negative responses.
+	qname    names
+	nsdname  names
+	clientIP *ipTrie
+	ip       *ipTrie
+}
+
+var emptyTriggers = newTriggers(nil)
+
+func newTriggers(soa *dns.SOA) *triggers {
+	return &triggers{
+		soa:      soa,
+		qname:    newNames(),
+		nsdname:  newNames(),
+		clientIP: newIPTrie(),
+		ip:       newIPTrie(),
+	}
+}
+
+// response returns true if t has triggers that match on the response.
+func (t *triggers) response() bool {
+	return t.qname.len() > 0 || t.nsdname.len() > 0 || t.ip.len > 0
+}
+
+// negative returns the authority section of negative responses: the SOA record of the policy zone.
+func (t *triggers) negative() []dns.RR {
+	if t.soa == nil {
+		return nil
+	}
+	soa := dns.Copy(t.soa).(*dns.SOA)
+	if soa.Minttl < soa.Hdr.Ttl {
+		soa.Hdr.Ttl = soa.Minttl
+	}
+	return []dns.RR{soa}
+}
+
+// Trigger types, suffixes of the owner names of the rules in a policy zone.
+const (
+	clientIPTrigger = "rpz-client-ip."
+	ipTrigger       = "rpz-ip."
+	nsdnameTrigger  = "rpz-nsdname."
+	nsipTrigger     = "rpz-nsip."
+)
+
+// build builds the triggers of the policy zone origin from its records.
+func build(origin string, soa *dns.SOA, rrs []dns.RR) *triggers {
+	owners := make(map[string][]dns.RR)
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		if hdr.Name == origin {
+			continue
+		}
+		switch hdr.Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeDNSKEY, dns.TypeDS:
+			continue
+		}
+		owners[hdr.Name] = append(owners[hdr.Name], rr)
+	}
+
+	t := newTriggers(soa)
+	for owner, rrs := range owners {
+		trigger := owner
+		if origin != "." {
+			trigger = strings.TrimSuffix(owner, origin)
+		}
+		if err := t.add(trigger, newRule(owner, rrs)); err != nil {
+			log.Warningf("Ignoring %s in policy zone %s: %s", owner, origin, err)
+		}
+	}
+	return t
+}
+
+// add adds the rule r for trigger, the owner name of the rule relative to the policy zone.
+func (t *triggers) add(trigger string, r *rule) error {
+	switch {
+	case strings.HasSuffix(trigger, "."+clientIPTrigger):
+		n, err := parseIP(strings.TrimSuffix(trigger, clientIPTrigger))
+		if err != nil {
+			return err
+		}
+		t.clientIP.insert(n, r)
+	case strings.HasSuffix(trigger, "."+ipTrigger):
+		n, err := parseIP(strings.TrimSuffix(trigger, ipTrigger))
+		if err != nil {
+			return err
+		}
+		t.ip.insert(n, r)
+	case strings.HasSuffix(trigger, "."+nsdnameTrigger):
+		t.nsdname.add(strings.TrimSuffix(trigger, nsdnameTrigger), r)
+	case strings.HasSuffix(trigger, "."+nsipTrigger):
+		return fmt.Errorf("NSIP triggers are not supported")
+	default:
+		t.qname.add(trigger, r)
+	}
+	return nil
+}
+
+// parseIP parses the IP address and prefix length from the owner name of an IP trigger, with the
+// trigger type removed, i.e. 24.0.2.0.192 for 192.0.2.0/24 and 64.zz.1.db8.2001 for 2001:db8:1::/64.
+func parseIP(s string) (*net.IPNet, error) {
+	labels := dns.SplitDomainName(s)
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("invalid IP trigger: %s", s)
+	}
+	prefix, err := strconv.Atoi(labels[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix length in IP trigger: %s", s)
+	}
+	addr := make([]string, 0, len(labels)-1)
+	for i := len(labels) - 1; i > 0; i-- {
+		addr = append(addr, labels[i])
+	}
+
+	var ip net.IP
+	bits := 32
+	if len(addr) == 4 {
+		ip = net.ParseIP(strings.Join(addr, ".")).To4()
+	}
+	if ip == nil {
+		bits = 128
+		var groups []string
+		for _, a := range addr {
+			if a == "zz" {
+				for j := 0; j < 8-len(addr)+1; j++ {
+					groups = append(groups, "0")
+				}
+				continue
+			}
+			groups = append(groups, a)
+		}
+		if len(groups) == 8 {
+			ip = net.ParseIP(strings.Join(groups, ":"))
+		}
+	}
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address in IP trigger: %s", s)
+	}
+	if prefix < 1 || prefix > bits {
+		return nil, fmt.Errorf("invalid prefix length in IP trigger: %s", s)
+	}
+	mask := net.CIDRMask(prefix, bits)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
+
+// names holds the rules of name triggers, QNAME and NSDNAME.
+type names struct {
+	exact    map[string]*rule
+	wildcard map[string]*rule // wildcards by the name below the wildcard label.
+}
+
+func newNames() names {
+	return names{exact: make(map[string]*rule), wildcard: make(map[string]*rule)}
+}
+
+func (n names) add(name string, r *rule) {
+	if strings.HasPrefix(name, "*.") {
+		name = name[2:]
+		if name == "" {
+			name = "."
+		}
+		n.wildcard[name] = r
+		return
+	}
+	n.exact[name] = r
+}
+
+// match returns the rule for name. An exact match wins over a wildcard, the closest wildcard wins over
+// wildcards higher up in the tree.
+func (n names) match(name string) *rule {
+	name = strings.ToLower(name)
+	if r, ok := n.exact[name]; ok {
+		return r
+	}
+	if name == "." {
+		return nil
+	}
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		if r, ok := n.wildcard[name[off:]]; ok {
+			return r
+		}
+	}
+	return n.wildcard["."]
+}
+
+func (n names) len() int { return len(n.exact) + len(n.wildcard) }
+
+// rule is the action the records at a trigger in a policy zone specify.
+type rule struct {
+	owner  string
+	action action
+	target string   // target of a CNAME rewrite.
+	ttl    uint32   // TTL of the CNAME rewrite.
+	data   []dns.RR // local data.
+}
+
+type action int
+
+const (
+	// actionNXDOMAIN answers with NXDOMAIN.
+	actionNXDOMAIN action = iota
+	// actionNODATA answers with an empty response.
+	actionNODATA
+	// actionPassthru exempts the query from the policies, the response is returned unmodified.
+	actionPassthru
+	// actionDrop silently drops the query; no reply is sent.
+	actionDrop
+	// actionTCPOnly answers UDP queries with a truncated response, to force the client to TCP.
+	actionTCPOnly
+	// actionCNAME rewrites the query to another name.
+	actionCNAME
+	// actionData answers with the local data in the policy zone.
+	actionData
+)
+
+func (a action) String() string {
+	switch a {
+	case actionNXDOMAIN:
+		return "nxdomain"
+	case actionNODATA:
+		return "nodata"
+	case actionPassthru:
+		return "passthru"
+	case actionDrop:
+		return "drop"
+	case actionTCPOnly:
+		return "tcp-only"
+	case actionCNAME:
+		return "cname"
+	}
+	return "data"
+}
+
+// newRule returns the rule for the records rrs at owner.
+func newRule(owner string, rrs []dns.RR) *rule {
+	r := &rule{owner: owner, action: actionData, data: rrs}
+	for _, rr := range rrs {
+		c, ok := rr.(*dns.CNAME)
+		if !ok {
+			continue
+		}
+		switch c.Target {
+		case ".":
+			r.action = actionNXDOMAIN
+		case "*.":
+			r.action = actionNODATA
+		case "rpz-passthru.":
+			r.action = actionPassthru
+		case "rpz-drop.":
+			r.action = actionDrop
+		case "rpz-tcp-only.":
+			r.action = actionTCPOnly
+		default:
+			r.action = actionCNAME
+			r.target = c.Target
+			r.ttl = c.Hdr.Ttl
+		}
+		r.data = nil
+		break
+	}
+	return r
+}
@@ -0,0 +1,153 @@
+package rpz
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+)
+
+func TestParseIP(t *testing.T) {
+	tests := []struct {
+		in        string
+		expected  string
+		shouldErr bool
+	}{
+		{"32.1.2.0.192.", "192.0.2.1/32", false},
+		{"24.0.2.0.192.", "192.0.2.0/24", false},
+		{"8.1.2.0.192.", "192.0.0.0/8", false},
+		{"128.1.zz.db8.2001.", "2001:db8::1/128", false},
+		{"64.zz.1.db8.2001.", "2001:db8:1::/64", false},
+		{"48.zz.1.db8.2001.", "2001:db8:1::/48", false},
+		{"128.1.0.0.0.0.0.0.0.", "::1/128", false},
+		{"128.1.zz.", "::1/128", false},
+		// fails
+		{"33.1.2.0.192.", "", true},
+		{"0.1.2.0.192.", "", true},
+		{"x.1.2.0.192.", "", true},
+		{"32.1.2.0.", "", true},
+		{"32.", "", true},
+		{"128.1.zz.zz.", "", true},
+	}
+
+	for i, tc := range tests {
+		n, err := parseIP(tc.in)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %s, got %s", i, tc.in, n)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %s, got %s", i, tc.in, err)
+			continue
+		}
+		if n.String() != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, n)
+		}
+	}
+}
+
+func TestNamesMatch(t *testing.T) {
+	n := newNames()
+	exact, wild, deep, root := &rule{owner: "exact"}, &rule{owner: "wild"}, &rule{owner: "deep"}, &rule{owner: "root"}
+	n.add("example.com.", exact)
+	n.add("*.example.com.", wild)
+	n.add("*.a.example.com.", deep)
+
+	tests := []struct {
+		name     string
+		expected *rule
+	}{
+		{"example.com.", exact},
+		{"EXAMPLE.com.", exact},
+		{"www.example.com.", wild},
+		{"a.example.com.", wild},
+		{"b.a.example.com.", deep},
+		{"example.org.", nil},
+		{"com.", nil},
+	}
+	for i, tc := range tests {
+		if r := n.match(tc.name); r != tc.expected {
+			t.Errorf("Test %d: expected %v for %s, got %v", i, tc.expected, tc.name, r)
+		}
+	}
+
+	n.add("*.", root)
+	if r := n.match("example.org."); r != root {
+		t.Errorf("Expected the root wildcard to match, got %v", r)
+	}
+	if r := n.match("."); r != nil {
+		t.Errorf("Expected the root wildcard not to match the root, got %v", r)
+	}
+}
+
+func TestIPTrie(t *testing.T) {
+	tr := newIPTrie()
+	wide, narrow, v6 := &rule{owner: "wide"}, &rule{owner: "narrow"}, &rule{owner: "v6"}
+	for _, x := range []struct {
+		cidr string
+		r    *rule
+	}{{"10.0.0.0/8", wide}, {"10.1.2.0/24", narrow}, {"2001:db8::/32", v6}} {
+		_, n, _ := net.ParseCIDR(x.cidr)
+		tr.insert(n, x.r)
+	}
+
+	tests := []struct {
+		ip       string
+		expected *rule
+	}{
+		{"10.1.1.1", wide},
+		{"10.1.2.3", narrow},
+		{"11.1.2.3", nil},
+		{"2001:db8::1", v6},
+		{"2001:db9::1", nil},
+		{"::ffff:10.1.2.3", narrow},
+	}
+	for i, tc := range tests {
+		if r := tr.match(net.ParseIP(tc.ip)); r != tc.expected {
+			t.Errorf("Test %d: expected %v for %s, got %v", i, tc.expected, tc.ip, r)
+		}
+	}
+}
+
+func TestPolicyReload(t *testing.T) {
+	z, err := file.Parse(strings.NewReader(`$ORIGIN rpz.
+@	3600	IN	SOA	ns.rpz. hostmaster.rpz. 1 3600 600 86400 60
+a.example.com	300	IN	CNAME	.
+`), "rpz.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newPolicy("rpz.", z)
+	if r := p.triggers().qname.match("a.example.com."); r == nil || r.action != actionNXDOMAIN {
+		t.Errorf("Expected NXDOMAIN rule for a.example.com., got %v", r)
+	}
+
+	// A new version of the zone, as a reload or transfer would set it.
+	z1, err := file.Parse(strings.NewReader(`$ORIGIN rpz.
+@	3600	IN	SOA	ns.rpz. hostmaster.rpz. 2 3600 600 86400 60
+b.example.com	300	IN	CNAME	*.
+`), "rpz.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.Apex = z1.Apex
+	z.Tree = z1.Tree
+
+	tr := p.triggers()
+	if r := tr.qname.match("a.example.com."); r != nil {
+		t.Errorf("Expected no rule for a.example.com., got %v", r)
+	}
+	if r := tr.qname.match("b.example.com."); r == nil || r.action != actionNODATA {
+		t.Errorf("Expected NODATA rule for b.example.com., got %v", r)
+	}
+}
+
+func TestPolicyNotLoaded(t *testing.T) {
+	p := newPolicy("rpz.", file.NewZone("rpz.", "stdin"))
+	if tr := p.triggers(); tr.response() {
+		t.Errorf("Expected no triggers for a policy zone that isn't loaded")
+	}
+}
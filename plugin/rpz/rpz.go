@@ -0,0 +1,298 @@
+// Package rpz implements a plugin that rewrites responses according to response policy zones.
+package rpz
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// RPZ applies the policies of the response policy zones to the queries and responses of the next
+// plugin.
+type RPZ struct {
+	Next  plugin.Handler
+	Zones []string
+
+	policies []*policy // in order of precedence.
+}
+
+// hit is a rule that matched a query or response.
+type hit struct {
+	policy   *policy
+	triggers *triggers
+	trigger  string // type of the trigger that matched.
+	rule     *rule
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (p *RPZ) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones(p.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(p.Name(), p.Next, ctx, w, r)
+	}
+
+	triggers := make([]*triggers, len(p.policies))
+	for i, pol := range p.policies {
+		triggers[i] = pol.triggers()
+	}
+
+	// The client IP and QNAME triggers are checked before the query is resolved. The first policy zone
+	// that matches wins, but policy zones before it may still match on the response.
+	var h *hit
+	n := len(p.policies)
+	ip := net.ParseIP(state.IP())
+	for i, t := range triggers {
+		if rl := t.clientIP.match(ip); rl != nil {
+			h, n = &hit{p.policies[i], t, "client-ip", rl}, i
+			break
+		}
+		if rl := t.qname.match(state.Name()); rl != nil {
+			h, n = &hit{p.policies[i], t, "qname", rl}, i
+			break
+		}
+	}
+
+	response := false
+	for _, t := range triggers[:n] {
+		if t.response() {
+			response = true
+			break
+		}
+	}
+	if !response {
+		if h == nil {
+			return plugin.NextOrFailure(p.Name(), p.Next, ctx, w, r)
+		}
+		return p.apply(ctx, w, state, h, nil, dns.RcodeSuccess)
+	}
+
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(p.Name(), p.Next, ctx, nw, r)
+	if err != nil || nw.Msg == nil {
+		return rcode, err
+	}
+	if rh := p.matchResponse(ctx, w, state, nw.Msg, triggers[:n]); rh != nil {
+		h = rh
+	}
+	if h == nil {
+		w.WriteMsg(nw.Msg)
+		return rcode, nil
+	}
+	return p.apply(ctx, w, state, h, nw.Msg, rcode)
+}
+
+// matchResponse checks the triggers that match on the response m: the names in the CNAME chain, the
+// addresses in the answer and the name servers of the zone of the answer.
+func (p *RPZ) matchResponse(ctx context.Context, w dns.ResponseWriter, state request.Request, m *dns.Msg, triggers []*triggers) *hit {
+	var ns []string
+	nsLookup := false
+	for i, t := range triggers {
+		for _, rr := range m.Answer {
+			if c, ok := rr.(*dns.CNAME); ok {
+				if rl := t.qname.match(c.Target); rl != nil {
+					return &hit{p.policies[i], t, "qname", rl}
+				}
+			}
+		}
+		if t.ip.len > 0 {
+			for _, rr := range m.Answer {
+				var rl *rule
+				switch x := rr.(type) {
+				case *dns.A:
+					rl = t.ip.match(x.A)
+				case *dns.AAAA:
+					rl = t.ip.match(x.AAAA)
+				}
+				if rl != nil {
+					return &hit{p.policies[i], t, "ip", rl}
+				}
+			}
+		}
+		if t.nsdname.len() > 0 {
+			if !nsLookup {
+				ns = p.nameservers(ctx, w, final(state.Name(), m.Answer), m)
+				nsLookup = true
+			}
+			for _, n := range ns {
+				if rl := t.nsdname.match(n); rl != nil {
+					return &hit{p.policies[i], t, "nsdname", rl}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// nameservers returns the names of the name servers of the zone that holds name, m is the response
+// for name.
+func (p *RPZ) nameservers(ctx context.Context, w dns.ResponseWriter, name string, m *dns.Msg) []string {
+	zone := ""
+	var ns []string
+	for _, rr := range m.Ns {
+		switch x := rr.(type) {
+		case *dns.NS:
+			ns = append(ns, x.Ns)
+		case *dns.SOA:
+			zone = x.Hdr.Name
+		}
+	}
+	if len(ns) > 0 {
+		return ns
+	}
+
+	if zone == "" {
+		// The authority section of the response to a SOA query holds the zone's SOA, unless name is the apex.
+		m1, err := p.lookup(ctx, w, name, dns.TypeSOA)
+		if err != nil {
+			log.Debugf("Failed to find the zone of %s: %s", name, err)
+			return nil
+		}
+		for _, rr := range append(m1.Answer, m1.Ns...) {
+			if x, ok := rr.(*dns.SOA); ok {
+				zone = x.Hdr.Name
+			}
+		}
+		if zone == "" {
+			return nil
+		}
+	}
+
+	m1, err := p.lookup(ctx, w, zone, dns.TypeNS)
+	if err != nil {
+		log.Debugf("Failed to find the name servers of %s: %s", zone, err)
+		return nil
+	}
+	for _, rr := range m1.Answer {
+		if x, ok := rr.(*dns.NS); ok {
+			ns = append(ns, x.Ns)
+		}
+	}
+	return ns
+}
+
+// apply applies the action of the rule in h. The response m of the next plugin is nil if the query
+// hasn't been resolved yet.
+func (p *RPZ) apply(ctx context.Context, w dns.ResponseWriter, state request.Request, h *hit, m *dns.Msg, rcode int) (int, error) {
+	act := h.rule.action
+	HitCount.WithLabelValues(metrics.WithServer(ctx), h.policy.name, h.trigger, act.String()).Inc()
+	log.Infof("%s %s/%s: %s trigger %s in policy zone %s, %s", state.IP(), state.Name(), state.Type(), h.trigger, h.rule.owner, h.policy.name, act)
+
+	if act == actionTCPOnly && state.Proto() == "tcp" {
+		act = actionPassthru
+	}
+
+	switch act {
+	case actionPassthru:
+		if m == nil {
+			return plugin.NextOrFailure(p.Name(), p.Next, ctx, w, state.Req)
+		}
+		w.WriteMsg(m)
+		return rcode, nil
+	case actionDrop:
+		return dns.RcodeSuccess, nil
+	}
+
+	res := new(dns.Msg)
+	res.SetReply(state.Req)
+	res.RecursionAvailable = true
+
+	switch act {
+	case actionNXDOMAIN:
+		res.Rcode = dns.RcodeNameError
+		res.Ns = h.triggers.negative()
+	case actionNODATA:
+		res.Ns = h.triggers.negative()
+	case actionTCPOnly:
+		res.Truncated = true
+	case actionCNAME:
+		target := h.rule.target
+		if strings.HasPrefix(target, "*.") {
+			// The query name with the policy zone's suffix, i.e. example.com. becomes example.com.garden.net.
+			target = state.Name() + target[2:]
+		}
+		res.Answer = []dns.RR{&dns.CNAME{
+			Hdr:    dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: h.rule.ttl},
+			Target: target,
+		}}
+		if state.QType() == dns.TypeCNAME {
+			break
+		}
+		m1, err := p.lookup(ctx, w, target, state.QType())
+		if err != nil {
+			log.Debugf("Failed to look up %s/%s: %s", target, state.Type(), err)
+			break
+		}
+		res.Rcode = m1.Rcode
+		res.Answer = append(res.Answer, m1.Answer...)
+		res.Ns = m1.Ns
+	case actionData:
+		for _, rr := range h.rule.data {
+			if state.QType() != dns.TypeANY && rr.Header().Rrtype != state.QType() {
+				continue
+			}
+			rr = dns.Copy(rr)
+			rr.Header().Name = state.QName()
+			res.Answer = append(res.Answer, rr)
+		}
+		if len(res.Answer) == 0 {
+			res.Ns = h.triggers.negative()
+		}
+	}
+
+	state.SizeAndDo(res)
+	w.WriteMsg(res)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (p *RPZ) Name() string { return "rpz" }
+
+// lookup sends a query for name and qtype to the next plugin and returns the response.
+func (p *RPZ) lookup(ctx context.Context, w dns.ResponseWriter, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(p.Name(), p.Next, ctx, nw, m)
+	if err != nil {
+		return nil, err
+	}
+	if nw.Msg == nil {
+		return nil, errNoResponse{name, qtype, rcode}
+	}
+	return nw.Msg, nil
+}
+
+type errNoResponse struct {
+	name  string
+	qtype uint16
+	rcode int
+}
+
+func (e errNoResponse) Error() string {
+	return "no response for " + e.name + "/" + dns.TypeToString[e.qtype] + ": " + dns.RcodeToString[e.rcode]
+}
+
+// final returns the name at the end of the CNAME chain that starts at name in answer.
+func final(name string, answer []dns.RR) string {
+	for i := 0; i < len(answer); i++ {
+		found := false
+		for _, rr := range answer {
+			if c, ok := rr.(*dns.CNAME); ok && strings.EqualFold(c.Hdr.Name, name) {
+				name, found = c.Target, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return name
+}
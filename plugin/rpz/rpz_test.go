@@ -0,0 +1,263 @@
+package rpz
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const policyZone = `$ORIGIN rpz.
+@	3600	IN	SOA	ns.rpz. hostmaster.rpz. 1 3600 600 86400 60
+@	3600	IN	NS	ns.rpz.
+nxdomain.example.com	300	IN	CNAME	.
+nodata.example.com	300	IN	CNAME	*.
+*.wild.example.com	300	IN	CNAME	.
+pass.wild.example.com	300	IN	CNAME	rpz-passthru.
+drop.example.com	300	IN	CNAME	rpz-drop.
+tcp.example.com	300	IN	CNAME	rpz-tcp-only.
+rewrite.example.com	300	IN	CNAME	www.example.com.
+garden.example.com	300	IN	CNAME	*.walled.example.com.
+local.example.com	300	IN	A	192.0.2.53
+local.example.com	300	IN	TXT	"blocked"
+blocked.example.com	300	IN	CNAME	.
+24.0.113.0.203.rpz-ip	300	IN	CNAME	.
+ns.evil.net.rpz-nsdname	300	IN	CNAME	.
+`
+
+const exampleCom = `$ORIGIN example.com.
+@	3600	IN	SOA	ns.example.com. hostmaster.example.com. 1 3600 600 86400 60
+@	3600	IN	NS	ns.example.com.
+ns	3600	IN	A	192.0.2.100
+www	3600	IN	A	192.0.2.1
+bad	3600	IN	A	203.0.113.5
+cname	3600	IN	CNAME	blocked.example.com.
+blocked	3600	IN	A	192.0.2.2
+pass.wild	3600	IN	A	192.0.2.4
+tcp	3600	IN	A	192.0.2.5
+`
+
+const exampleOrg = `$ORIGIN example.org.
+@	3600	IN	SOA	ns.evil.net. hostmaster.example.org. 1 3600 600 86400 60
+@	3600	IN	NS	ns.evil.net.
+www	3600	IN	A	192.0.2.10
+`
+
+func parseZone(t *testing.T, origin, zone string) *file.Zone {
+	z, err := file.Parse(strings.NewReader(zone), origin, "stdin", 0)
+	if err != nil {
+		t.Fatalf("Failed to parse zone %s: %s", origin, err)
+	}
+	return z
+}
+
+// newTestRPZ returns an RPZ with the policy zones, in order, in front of a file plugin serving
+// example.com and example.org.
+func newTestRPZ(t *testing.T, policies ...string) *RPZ {
+	next := file.File{Zones: file.Zones{
+		Z: map[string]*file.Zone{
+			"example.com.": parseZone(t, "example.com.", exampleCom),
+			"example.org.": parseZone(t, "example.org.", exampleOrg),
+		},
+		Names: []string{"example.com.", "example.org."},
+	}}
+	p := &RPZ{Next: next, Zones: []string{"."}}
+	for i := 0; i < len(policies); i += 2 {
+		p.policies = append(p.policies, newPolicy(policies[i], parseZone(t, policies[i], policies[i+1])))
+	}
+	return p
+}
+
+var rpzSOA = test.SOA("rpz. 60 IN SOA ns.rpz. hostmaster.rpz. 1 3600 600 86400 60")
+
+func TestRPZ(t *testing.T) {
+	p := newTestRPZ(t, "rpz.", policyZone)
+
+	tests := []test.Case{
+		{
+			Qname: "www.example.com.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example.com. 3600 IN A 192.0.2.1")},
+			Ns:     []dns.RR{test.NS("example.com. 3600 IN NS ns.example.com.")},
+		},
+		{
+			Qname: "nxdomain.example.com.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{rpzSOA},
+		},
+		{
+			Qname: "nodata.example.com.", Qtype: dns.TypeA,
+			Ns: []dns.RR{rpzSOA},
+		},
+		{
+			Qname: "a.b.wild.example.com.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{rpzSOA},
+		},
+		{
+			// The exact match wins over the wildcard.
+			Qname: "pass.wild.example.com.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("pass.wild.example.com. 3600 IN A 192.0.2.4")},
+			Ns:     []dns.RR{test.NS("example.com. 3600 IN NS ns.example.com.")},
+		},
+		{
+			Qname: "rewrite.example.com.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("rewrite.example.com. 300 IN CNAME www.example.com."),
+				test.A("www.example.com. 3600 IN A 192.0.2.1"),
+			},
+			Ns: []dns.RR{test.NS("example.com. 3600 IN NS ns.example.com.")},
+		},
+		{
+			Qname: "garden.example.com.", Qtype: dns.TypeCNAME,
+			Answer: []dns.RR{test.CNAME("garden.example.com. 300 IN CNAME garden.example.com.walled.example.com.")},
+		},
+		{
+			Qname: "local.example.com.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("local.example.com. 300 IN A 192.0.2.53")},
+		},
+		{
+			Qname: "local.example.com.", Qtype: dns.TypeTXT,
+			Answer: []dns.RR{test.TXT(`local.example.com. 300 IN TXT "blocked"`)},
+		},
+		{
+			Qname: "local.example.com.", Qtype: dns.TypeMX,
+			Ns: []dns.RR{rpzSOA},
+		},
+		{
+			// QNAME trigger on the target of a CNAME in the response.
+			Qname: "cname.example.com.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{rpzSOA},
+		},
+		{
+			// IP trigger.
+			Qname: "bad.example.com.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{rpzSOA},
+		},
+		{
+			// NSDNAME trigger.
+			Qname: "www.example.org.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{rpzSOA},
+		},
+	}
+
+	for _, tc := range tests {
+		m := tc.Msg()
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := p.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Errorf("Expected no error for %s/%s, got %s", tc.Qname, dns.TypeToString[tc.Qtype], err)
+			continue
+		}
+		if rec.Msg == nil {
+			t.Errorf("Expected a response for %s/%s", tc.Qname, dns.TypeToString[tc.Qtype])
+			continue
+		}
+		test.SortAndCheck(t, rec.Msg, tc)
+	}
+}
+
+func TestRPZDrop(t *testing.T) {
+	p := newTestRPZ(t, "rpz.", policyZone)
+
+	m := new(dns.Msg)
+	m.SetQuestion("drop.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := p.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if rec.Msg != nil {
+		t.Errorf("Expected no response, got %s", rec.Msg)
+	}
+}
+
+func TestRPZTCPOnly(t *testing.T) {
+	p := newTestRPZ(t, "rpz.", policyZone)
+
+	m := new(dns.Msg)
+	m.SetQuestion("tcp.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	p.ServeDNS(context.TODO(), rec, m)
+	if !rec.Msg.Truncated || len(rec.Msg.Answer) != 0 {
+		t.Errorf("Expected an empty truncated response over UDP, got %s", rec.Msg)
+	}
+
+	rec = dnstest.NewRecorder(&test.ResponseWriter{TCP: true})
+	p.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Truncated || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected an answer over TCP, got %s", rec.Msg)
+	}
+}
+
+func TestRPZClientIP(t *testing.T) {
+	p := newTestRPZ(t, "rpz.", `$ORIGIN rpz.
+@	3600	IN	SOA	ns.rpz. hostmaster.rpz. 1 3600 600 86400 60
+16.0.0.240.10.rpz-client-ip	300	IN	CNAME	.
+`)
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	p.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeNameError {
+		t.Errorf("Expected NXDOMAIN for client 10.240.0.1, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+
+	rec = dnstest.NewRecorder(&test.ResponseWriter6{})
+	p.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected NOERROR for an IPv6 client, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+}
+
+func TestRPZPrecedence(t *testing.T) {
+	allow := `$ORIGIN allow.
+@	3600	IN	SOA	ns.allow. hostmaster.allow. 1 3600 600 86400 60
+24.0.113.0.203.rpz-ip	300	IN	CNAME	rpz-passthru.
+`
+	block := `$ORIGIN block.
+@	3600	IN	SOA	ns.block. hostmaster.block. 1 3600 600 86400 60
+bad.example.com	300	IN	CNAME	.
+www.example.com	300	IN	CNAME	.
+`
+	tests := []struct {
+		policies []string
+		qname    string
+		rcode    int
+	}{
+		// The IP trigger in the first policy zone wins over the QNAME trigger in the second.
+		{[]string{"allow.", allow, "block.", block}, "bad.example.com.", dns.RcodeSuccess},
+		{[]string{"allow.", allow, "block.", block}, "www.example.com.", dns.RcodeNameError},
+		{[]string{"block.", block, "allow.", allow}, "bad.example.com.", dns.RcodeNameError},
+	}
+
+	for i, tc := range tests {
+		p := newTestRPZ(t, tc.policies...)
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		p.ServeDNS(context.TODO(), rec, m)
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rec.Msg.Rcode])
+		}
+	}
+}
+
+func TestRPZZones(t *testing.T) {
+	p := newTestRPZ(t, "rpz.", policyZone)
+	p.Zones = []string{"example.org."}
+
+	m := new(dns.Msg)
+	m.SetQuestion("nxdomain.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	p.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode == dns.RcodeNameError && len(rec.Msg.Ns) > 0 && rec.Msg.Ns[0].Header().Name == "rpz." {
+		t.Errorf("Expected policies not to apply outside of the zones")
+	}
+}
@@ -0,0 +1,175 @@
+package rpz
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("rpz")
+
+func init() {
+	caddy.RegisterPlugin("rpz", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	p, err := rpzParse(c)
+	if err != nil {
+		return plugin.Error("rpz", err)
+	}
+
+	// Policy zones from files are reloaded when they change, transferred policy zones are kept up to
+	// date like secondary zones.
+	for _, pol := range p.policies {
+		z := pol.zone
+		if len(z.TransferFrom) > 0 {
+			c.OnStartup(func() error {
+				z.StartupOnce.Do(func() {
+					z.TransferIn()
+					go func() {
+						z.Update()
+					}()
+				})
+				return nil
+			})
+			continue
+		}
+		c.OnStartup(func() error {
+			z.StartupOnce.Do(func() {
+				z.Reload()
+			})
+			return nil
+		})
+		c.OnShutdown(z.OnShutdown)
+	}
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, HitCount)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		p.Next = next
+		return p
+	})
+
+	return nil
+}
+
+func rpzParse(c *caddy.Controller) (*RPZ, error) {
+	p := &RPZ{}
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		p.Zones = make([]string, len(c.ServerBlockKeys))
+		copy(p.Zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			p.Zones = args
+		}
+		for i := range p.Zones {
+			p.Zones[i] = plugin.Host(p.Zones[i]).Normalize()
+		}
+
+		seen := make(map[string]bool)
+		for c.NextBlock() {
+			switch c.Val() {
+			case "policy":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				name := plugin.Host(args[0]).Normalize()
+				if seen[name] {
+					return nil, c.Errf("duplicate policy zone '%s'", name)
+				}
+				seen[name] = true
+
+				var (
+					z   *file.Zone
+					err error
+				)
+				if args[1] == "from" {
+					z, err = transferZone(c, name, args[2:])
+				} else if len(args) == 2 {
+					z, err = fileZone(name, args[1], config.Root)
+				} else {
+					return nil, c.ArgErr()
+				}
+				if err != nil {
+					return nil, err
+				}
+				p.policies = append(p.policies, newPolicy(name, z))
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if len(p.policies) == 0 {
+		return nil, fmt.Errorf("no policy zones")
+	}
+	return p, nil
+}
+
+// fileZone loads the policy zone name from fileName.
+func fileZone(name, fileName, root string) (*file.Zone, error) {
+	if !path.IsAbs(fileName) && root != "" {
+		fileName = path.Join(root, fileName)
+	}
+	reader, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return file.Parse(reader, name, fileName, 0)
+}
+
+// transferZone returns the policy zone name that is transferred from the addresses in args, which
+// may end with a TSIG key: 'ADDRESS... [key KEY]'.
+func transferZone(c *caddy.Controller, name string, args []string) (*file.Zone, error) {
+	key := ""
+	if l := len(args); l >= 2 && args[l-2] == "key" {
+		key = strings.ToLower(dns.Fqdn(args[l-1]))
+		args = args[:l-2]
+	}
+	if len(args) == 0 {
+		return nil, c.ArgErr()
+	}
+	from := make([]string, len(args))
+	for i := range args {
+		addr, err := parse.HostPort(args[i], transport.Port)
+		if err != nil {
+			return nil, err
+		}
+		from[i] = addr
+	}
+	keys, err := file.TransferKeys(c, nil, key, from)
+	if err != nil {
+		return nil, err
+	}
+
+	z := file.NewZone(name, "stdin")
+	z.TransferFrom = from
+	z.TransferKeys = keys
+	return z, nil
+}
@@ -0,0 +1,100 @@
+package rpz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	zoneFile := filepath.Join(dir, "db.rpz")
+	if err := ioutil.WriteFile(zoneFile, []byte(policyZone), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     []string
+		policies  []string
+		from      []string
+	}{
+		{`rpz {
+			policy rpz. ` + zoneFile + `
+		}`, false, []string{"."}, []string{"rpz."}, nil},
+		{`rpz example.org {
+			policy rpz. ` + zoneFile + `
+			policy rpz.example.net from 10.0.0.1 10.0.0.2:5353
+		}`, false, []string{"example.org."}, []string{"rpz.", "rpz.example.net."}, []string{"10.0.0.1:53", "10.0.0.2:5353"}},
+		// fails
+		{`rpz`, true, nil, nil, nil},
+		{`rpz {
+			policy rpz.
+		}`, true, nil, nil, nil},
+		{`rpz {
+			policy rpz. /does/not/exist
+		}`, true, nil, nil, nil},
+		{`rpz {
+			policy rpz. ` + zoneFile + ` extra
+		}`, true, nil, nil, nil},
+		{`rpz {
+			policy rpz. from
+		}`, true, nil, nil, nil},
+		{`rpz {
+			policy rpz. from example.net
+		}`, true, nil, nil, nil},
+		{`rpz {
+			policy rpz. from 10.0.0.1 key unknown.key.
+		}`, true, nil, nil, nil},
+		{`rpz {
+			policy rpz. ` + zoneFile + `
+			policy rpz. from 10.0.0.1
+		}`, true, nil, nil, nil},
+		{`rpz {
+			blah
+		}`, true, nil, nil, nil},
+		{"rpz {\npolicy rpz. " + zoneFile + "\n}\nrpz {\npolicy rpz. " + zoneFile + "\n}", true, nil, nil, nil},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		c.ServerBlockKeys = []string{"."}
+		p, err := rpzParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(p.Zones) != len(tc.zones) || p.Zones[0] != tc.zones[0] {
+			t.Errorf("Test %d: expected zones %v, got %v", i, tc.zones, p.Zones)
+		}
+		if len(p.policies) != len(tc.policies) {
+			t.Errorf("Test %d: expected %d policy zones, got %d", i, len(tc.policies), len(p.policies))
+			continue
+		}
+		for j, pol := range p.policies {
+			if pol.name != tc.policies[j] {
+				t.Errorf("Test %d: expected policy zone %s, got %s", i, tc.policies[j], pol.name)
+			}
+		}
+		if tc.from != nil {
+			from := p.policies[len(p.policies)-1].zone.TransferFrom
+			if len(from) != len(tc.from) || from[0] != tc.from[0] || from[1] != tc.from[1] {
+				t.Errorf("Test %d: expected transfers from %v, got %v", i, tc.from, from)
+			}
+		}
+	}
+}
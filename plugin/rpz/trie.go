@@ -0,0 +1,62 @@
+package rpz
+
+import "net"
+
+// ipTrie holds the rules of IP triggers in two binary tries, one for IPv4 and one for IPv6. Lookups
+// return the rule of the longest matching prefix.
+type ipTrie struct {
+	v4  *ipNode
+	v6  *ipNode
+	len int
+}
+
+type ipNode struct {
+	child [2]*ipNode
+	rule  *rule // rule of the prefix that ends in this node, if any.
+}
+
+func newIPTrie() *ipTrie { return &ipTrie{v4: new(ipNode), v6: new(ipNode)} }
+
+// insert adds the rule r for the network n.
+func (t *ipTrie) insert(n *net.IPNet, r *rule) {
+	ones, _ := n.Mask.Size()
+	ip, cur := t.root(n.IP)
+	for i := 0; i < ones; i++ {
+		b := bit(ip, i)
+		if cur.child[b] == nil {
+			cur.child[b] = new(ipNode)
+		}
+		cur = cur.child[b]
+	}
+	cur.rule = r
+	t.len++
+}
+
+// match returns the rule of the longest prefix that contains ip, or nil if there is none.
+func (t *ipTrie) match(ip net.IP) *rule {
+	if t.len == 0 || ip == nil {
+		return nil
+	}
+	ip, cur := t.root(ip)
+	var r *rule
+	for i := 0; cur != nil; i++ {
+		if cur.rule != nil {
+			r = cur.rule
+		}
+		if i == len(ip)*8 {
+			break
+		}
+		cur = cur.child[bit(ip, i)]
+	}
+	return r
+}
+
+func (t *ipTrie) root(ip net.IP) (net.IP, *ipNode) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, t.v4
+	}
+	return ip.To16(), t.v6
+}
+
+// bit returns bit i of ip, counting from the most significant bit.
+func bit(ip net.IP, i int) int { return int(ip[i/8]>>(7-uint(i%8))) & 1 }
@@ -0,0 +1,29 @@
+package rrl
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	ResponsesExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "responses_exceeded_total",
+		Help:      "Counter of responses that exceeded the rate limit, by category.",
+	}, []string{"server", "category"})
+	ResponsesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "responses_dropped_total",
+		Help:      "Counter of responses that were dropped because they exceeded the rate limit.",
+	}, []string{"server"})
+	ResponsesSlipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "responses_slipped_total",
+		Help:      "Counter of truncated responses that were sent instead of dropping the response.",
+	}, []string{"server"})
+)
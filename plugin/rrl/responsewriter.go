@@ -0,0 +1,51 @@
+package rrl
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// ResponseWriter is a response writer that only writes the reply when it is within the rate limits.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	*RRL
+	state  request.Request
+	server string // Server handling the request.
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	t := newToken(w.prefix(net.ParseIP(w.state.IP())), res, w.now().UTC())
+
+	ok, slip := w.allowed(t)
+	if ok {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	ResponsesExceeded.WithLabelValues(w.server, t.category.String()).Inc()
+	if w.logOnly {
+		log.Infof("Response to %s for %q (%s) over the %s limit", w.state.IP(), t.name, dns.TypeToString[t.qtype], t.category)
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	if slip {
+		ResponsesSlipped.WithLabelValues(w.server).Inc()
+		m := new(dns.Msg)
+		m.SetReply(w.state.Req)
+		m.Truncated = true
+		w.state.SizeAndDo(m)
+		return w.ResponseWriter.WriteMsg(m)
+	}
+
+	ResponsesDropped.WithLabelValues(w.server).Inc()
+	return nil
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Write(buf []byte) (int, error) {
+	log.Warning("Rate limiting called with Write: not limiting reply")
+	return w.ResponseWriter.Write(buf)
+}
@@ -0,0 +1,174 @@
+// Package rrl implements Response Rate Limiting, to reduce the effectiveness of CoreDNS as an
+// amplifier in reflection attacks.
+package rrl
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// RRL is a plugin that limits the rate of identical responses sent to a client network over UDP.
+type RRL struct {
+	Next  plugin.Handler
+	Zones []string
+
+	window time.Duration
+
+	ipv4PrefixLength int
+	ipv6PrefixLength int
+
+	// allowances per second for each category of response, 0 means unlimited.
+	rates [categories]float64
+
+	slipRatio uint
+	logOnly   bool
+
+	table     *cache.Cache
+	tableSize int
+	tableMu   sync.Mutex // Makes looking up and creating a bucket atomic.
+
+	// Testing.
+	now func() time.Time
+}
+
+// New returns an initialized RRL with default settings. It's up to the caller to set the Next
+// handler.
+func New() *RRL {
+	return &RRL{
+		Zones:            []string{"."},
+		window:           defaultWindow,
+		ipv4PrefixLength: defaultIPv4PrefixLength,
+		ipv6PrefixLength: defaultIPv6PrefixLength,
+		slipRatio:        defaultSlipRatio,
+		table:            cache.New(defaultTableSize),
+		tableSize:        defaultTableSize,
+		now:              time.Now,
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (rl *RRL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	// TCP clients have proven their source address, they can't be used in reflection attacks.
+	if state.Proto() == "tcp" {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	zone := plugin.Zones(rl.Zones).Matches(state.Name())
+	if zone == "" {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	rw := &ResponseWriter{ResponseWriter: w, RRL: rl, state: state, server: metrics.WithServer(ctx)}
+	rcode, err := plugin.NextOrFailure(rl.Name(), rl.Next, ctx, rw, r)
+	if plugin.ClientWrite(rcode) {
+		return rcode, err
+	}
+
+	// The error response would be written by the server, bypassing us. Write it ourselves so that
+	// errors are rate limited as well.
+	m := state.ErrorMessage(rcode)
+	state.SizeAndDo(m)
+	rw.WriteMsg(m)
+	return dns.RcodeSuccess, err
+}
+
+// Name implements the Handler interface.
+func (rl *RRL) Name() string { return "rrl" }
+
+// allowed debits the account for token t with one response. It returns true when the response is
+// within the allowance for the token's category. When the response is over the limit, slip tells
+// if a truncated response should be sent instead of nothing at all.
+func (rl *RRL) allowed(t token) (ok, slip bool) {
+	rate := rl.rates[t.category]
+	if rate == 0 {
+		return true, false
+	}
+
+	k := cache.Hash([]byte(t.String()))
+	var b *bucket
+	rl.tableMu.Lock()
+	if el, found := rl.table.Get(k); found {
+		b = el.(*bucket)
+	} else {
+		b = &bucket{balance: rate, last: rl.now()}
+		rl.table.Add(k, b)
+	}
+	rl.tableMu.Unlock()
+
+	if b.debit(rl.now(), rate, rl.window) {
+		return true, false
+	}
+	return false, b.slip(rl.slipRatio)
+}
+
+// prefix returns the client's network, derived from its address and the configured prefix lengths.
+func (rl *RRL) prefix(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(rl.ipv4PrefixLength, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(rl.ipv6PrefixLength, 128)).String()
+}
+
+// bucket is a leaky bucket holding the balance of a single account.
+type bucket struct {
+	sync.Mutex
+	balance float64
+	last    time.Time
+	dropped uint
+}
+
+// debit credits the bucket with rate responses per second elapsed since the last debit and then
+// debits one response. The balance is never more than one second's worth of responses and
+// never less than window's worth of responses below zero. It returns true if the balance
+// is not negative.
+func (b *bucket) debit(now time.Time, rate float64, window time.Duration) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	b.balance += now.Sub(b.last).Seconds() * rate
+	if b.balance > rate {
+		b.balance = rate
+	}
+	b.last = now
+
+	b.balance--
+	if min := -window.Seconds() * rate; b.balance < min {
+		b.balance = min
+	}
+	if b.balance >= 0 {
+		b.dropped = 0
+		return true
+	}
+	return false
+}
+
+// slip returns true if the n-th response that is over the limit should be sent truncated instead
+// of being dropped.
+func (b *bucket) slip(ratio uint) bool {
+	if ratio == 0 {
+		return false
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.dropped++
+	return b.dropped%ratio == 0
+}
+
+const (
+	defaultWindow           = 15 * time.Second
+	defaultIPv4PrefixLength = 24
+	defaultIPv6PrefixLength = 56
+	defaultSlipRatio        = 2
+	defaultTableSize        = 100000
+)
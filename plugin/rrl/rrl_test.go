@@ -0,0 +1,210 @@
+package rrl
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// answer is a handler that replies to every query with a single A record, or NXDOMAIN for
+// names under nx.example.org.
+func answer() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if dns.IsSubDomain("nx.example.org.", r.Question[0].Name) {
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{test.SOA("nx.example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300")}
+		} else {
+			m.Answer = []dns.RR{test.A(r.Question[0].Name + " 300 IN A 127.0.0.1")}
+		}
+		w.WriteMsg(m)
+		return m.Rcode, nil
+	})
+}
+
+func TestRRL(t *testing.T) {
+	now := time.Now()
+	rl := New()
+	rl.Next = answer()
+	rl.rates = [categories]float64{2, 2, 1, 2, 2}
+	rl.now = func() time.Time { return now }
+
+	tests := []struct {
+		qname     string
+		tcp       bool
+		advance   time.Duration
+		written   bool
+		truncated bool
+	}{
+		{qname: "a.example.org.", written: true},
+		{qname: "a.example.org.", written: true},
+		{qname: "a.example.org.", written: false},                          // over the limit, dropped
+		{qname: "a.example.org.", written: true, truncated: true},          // slip
+		{qname: "b.example.org.", written: true},                           // different token
+		{qname: "a.example.org.", tcp: true, written: true},                // TCP is never limited
+		{qname: "a.example.org.", advance: 3 * time.Second, written: true}, // balance restored
+		{qname: "x.nx.example.org.", written: true},
+		{qname: "y.nx.example.org.", written: false}, // NXDOMAIN is keyed on the zone
+	}
+
+	for i, tc := range tests {
+		now = now.Add(tc.advance)
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tc.tcp})
+
+		if _, err := rl.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		if written := rec.Msg != nil; written != tc.written {
+			t.Errorf("Test %d: expected written to be %t, got %t", i, tc.written, written)
+			continue
+		}
+		if rec.Msg == nil {
+			continue
+		}
+		if rec.Msg.Truncated != tc.truncated {
+			t.Errorf("Test %d: expected truncated to be %t, got %t", i, tc.truncated, rec.Msg.Truncated)
+		}
+		if tc.truncated && len(rec.Msg.Answer) != 0 {
+			t.Errorf("Test %d: expected empty answer in truncated response, got %d records", i, len(rec.Msg.Answer))
+		}
+	}
+}
+
+func TestRRLConcurrent(t *testing.T) {
+	now := time.Now()
+	rl := New()
+	rl.rates[responses] = 1
+	rl.now = func() time.Time { return now }
+
+	// Concurrent first responses must share a single bucket, only one of them is allowed.
+	tok := token{prefix: "127.0.0.0", category: responses, name: "example.org.", qtype: dns.TypeA}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := rl.allowed(tok); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 1 {
+		t.Errorf("Expected 1 response to be allowed, got %d", allowed)
+	}
+}
+
+func TestRRLLogOnly(t *testing.T) {
+	rl := New()
+	rl.Next = answer()
+	rl.rates[responses] = 1
+	rl.logOnly = true
+
+	for i := 0; i < 5; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rl.ServeDNS(context.TODO(), rec, m)
+		if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+			t.Errorf("Test %d: expected response to be written in log_only mode", i)
+		}
+	}
+}
+
+func TestRRLErrors(t *testing.T) {
+	rl := New()
+	rl.Next = test.ErrorHandler()
+	rl.rates[errors] = 1
+	rl.slipRatio = 0
+
+	written := 0
+	for i := 0; i < 3; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, _ := rl.ServeDNS(context.TODO(), rec, m)
+		if rcode != dns.RcodeSuccess {
+			t.Errorf("Test %d: expected the error to be written by rrl, got rcode %d", i, rcode)
+		}
+		if rec.Msg != nil {
+			written++
+		}
+	}
+	if written != 1 {
+		t.Errorf("Expected 1 error response to be written, got %d", written)
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	sig := test.RRSIG("a.b.example.org. 300 IN RRSIG A 8 3 300 20190101000000 20180101000000 12345 example.org. c2lnbmF0dXJl")
+
+	tests := []struct {
+		msg              *dns.Msg
+		expectedCategory category
+		expectedName     string
+	}{
+		{
+			&dns.Msg{MsgHdr: dns.MsgHdr{Response: true}, Question: []dns.Question{{Name: "WWW.example.org.", Qtype: dns.TypeA}},
+				Answer: []dns.RR{test.A("www.example.org. 300 IN A 127.0.0.1")}},
+			responses, "www.example.org.",
+		},
+		{
+			&dns.Msg{MsgHdr: dns.MsgHdr{Response: true}, Question: []dns.Question{{Name: "a.b.example.org.", Qtype: dns.TypeA}},
+				Answer: []dns.RR{test.A("a.b.example.org. 300 IN A 127.0.0.1"), sig}},
+			responses, "*.b.example.org.",
+		},
+		{
+			&dns.Msg{MsgHdr: dns.MsgHdr{Response: true, Rcode: dns.RcodeNameError}, Question: []dns.Question{{Name: "nx.example.org.", Qtype: dns.TypeA}},
+				Ns: []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300")}},
+			nxdomains, "example.org.",
+		},
+		{
+			&dns.Msg{MsgHdr: dns.MsgHdr{Response: true}, Question: []dns.Question{{Name: "www.sub.example.org.", Qtype: dns.TypeA}},
+				Ns: []dns.RR{test.NS("sub.example.org. 300 IN NS ns.sub.example.org.")}},
+			referrals, "sub.example.org.",
+		},
+		{
+			&dns.Msg{MsgHdr: dns.MsgHdr{Response: true, Rcode: dns.RcodeServerFailure}, Question: []dns.Question{{Name: "example.org.", Qtype: dns.TypeA}}},
+			errors, "",
+		},
+	}
+
+	for i, tc := range tests {
+		tok := newToken("10.0.0.0", tc.msg, time.Now())
+		if tok.category != tc.expectedCategory {
+			t.Errorf("Test %d: expected category %s, got %s", i, tc.expectedCategory, tok.category)
+		}
+		if tok.name != tc.expectedName {
+			t.Errorf("Test %d: expected name %q, got %q", i, tc.expectedName, tok.name)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	rl := New()
+	if p := rl.prefix(net.ParseIP("10.240.0.1")); p != "10.240.0.0" {
+		t.Errorf("Expected 10.240.0.0, got %s", p)
+	}
+	if p := rl.prefix(net.ParseIP("2001:db8:1:2:3::1")); p != "2001:db8:1::" {
+		t.Errorf("Expected 2001:db8:1::, got %s", p)
+	}
+}
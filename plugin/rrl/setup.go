@@ -0,0 +1,176 @@
+package rrl
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/mholt/caddy"
+)
+
+var log = clog.NewWithPlugin("rrl")
+
+func init() {
+	caddy.RegisterPlugin("rrl", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	rl, err := rrlParse(c)
+	if err != nil {
+		return plugin.Error("rrl", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rl.Next = next
+		return rl
+	})
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, ResponsesExceeded, ResponsesDropped, ResponsesSlipped)
+		return nil
+	})
+
+	return nil
+}
+
+func rrlParse(c *caddy.Controller) (*RRL, error) {
+	rl := New()
+
+	j := 0
+	for c.Next() {
+		if j > 0 {
+			return nil, plugin.ErrOnce
+		}
+		j++
+
+		// rrl [zones..]
+		origins := make([]string, len(c.ServerBlockKeys))
+		copy(origins, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			origins = args
+		}
+
+		// Unless set explicitly, the other categories use the allowance for responses.
+		set := [categories]bool{}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "window":
+				n, err := intArg(c, 3600)
+				if err != nil {
+					return nil, err
+				}
+				if n == 0 {
+					return nil, fmt.Errorf("window can not be zero")
+				}
+				rl.window = time.Duration(n) * time.Second
+
+			case "ipv4_prefix_length":
+				n, err := intArg(c, 32)
+				if err != nil {
+					return nil, err
+				}
+				rl.ipv4PrefixLength = n
+
+			case "ipv6_prefix_length":
+				n, err := intArg(c, 128)
+				if err != nil {
+					return nil, err
+				}
+				rl.ipv6PrefixLength = n
+
+			case "responses_per_second", "nodata_per_second", "nxdomains_per_second", "referrals_per_second", "errors_per_second":
+				cat := perSecond[c.Val()]
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				rate, err := strconv.ParseFloat(args[0], 64)
+				if err != nil {
+					return nil, err
+				}
+				if rate < 0 {
+					return nil, fmt.Errorf("%s can not be negative: %s", c.Val(), args[0])
+				}
+				rl.rates[cat] = rate
+				set[cat] = true
+
+			case "slip_ratio":
+				n, err := intArg(c, 10)
+				if err != nil {
+					return nil, err
+				}
+				rl.slipRatio = uint(n)
+
+			case "log_only":
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+				rl.logOnly = true
+
+			case "max_table_size":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				size, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if size <= 0 {
+					return nil, fmt.Errorf("max_table_size must be positive: %d", size)
+				}
+				rl.tableSize = size
+				rl.table = cache.New(size)
+
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
+		for cat := nodata; cat < categories; cat++ {
+			if !set[cat] {
+				rl.rates[cat] = rl.rates[responses]
+			}
+		}
+
+		for i := range origins {
+			origins[i] = plugin.Host(origins[i]).Normalize()
+		}
+		rl.Zones = origins
+	}
+
+	return rl, nil
+}
+
+var perSecond = map[string]category{
+	"responses_per_second": responses,
+	"nodata_per_second":    nodata,
+	"nxdomains_per_second": nxdomains,
+	"referrals_per_second": referrals,
+	"errors_per_second":    errors,
+}
+
+func intArg(c *caddy.Controller, max int) (int, error) {
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > max {
+		return 0, fmt.Errorf("%s must be between 0 and %d: %d", c.Val(), max, n)
+	}
+	return n, nil
+}
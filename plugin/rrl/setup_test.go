@@ -0,0 +1,95 @@
+package rrl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input             string
+		shouldErr         bool
+		expectedZones     []string
+		expectedWindow    time.Duration
+		expectedRates     [categories]float64
+		expectedSlipRatio uint
+		expectedLogOnly   bool
+	}{
+		{`rrl`, false, []string{}, defaultWindow, [categories]float64{}, defaultSlipRatio, false},
+		{`rrl example.org`, false, []string{"example.org."}, defaultWindow, [categories]float64{}, defaultSlipRatio, false},
+		{`rrl {
+			responses_per_second 10
+		}`, false, []string{}, defaultWindow, [categories]float64{10, 10, 10, 10, 10}, defaultSlipRatio, false},
+		{`rrl {
+			responses_per_second 10
+			nxdomains_per_second 2
+			errors_per_second 0
+			window 5
+			slip_ratio 0
+			log_only
+			ipv4_prefix_length 32
+			ipv6_prefix_length 64
+			max_table_size 1000
+		}`, false, []string{}, 5 * time.Second, [categories]float64{10, 10, 2, 10, 0}, 0, true},
+		// fails
+		{`rrl {
+			responses_per_second -1
+		}`, true, nil, 0, [categories]float64{}, 0, false},
+		{`rrl {
+			responses_per_second
+		}`, true, nil, 0, [categories]float64{}, 0, false},
+		{`rrl {
+			window 0
+		}`, true, nil, 0, [categories]float64{}, 0, false},
+		{`rrl {
+			ipv4_prefix_length 33
+		}`, true, nil, 0, [categories]float64{}, 0, false},
+		{`rrl {
+			slip_ratio 11
+		}`, true, nil, 0, [categories]float64{}, 0, false},
+		{`rrl {
+			max_table_size 0
+		}`, true, nil, 0, [categories]float64{}, 0, false},
+		{`rrl {
+			log_only yes
+		}`, true, nil, 0, [categories]float64{}, 0, false},
+		{`rrl {
+			unknown
+		}`, true, nil, 0, [categories]float64{}, 0, false},
+		{`rrl
+		rrl`, true, nil, 0, [categories]float64{}, 0, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		rl, err := rrlParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+
+		if len(rl.Zones) != len(test.expectedZones) {
+			t.Errorf("Test %d: expected zones %v, got %v", i, test.expectedZones, rl.Zones)
+		}
+		if rl.window != test.expectedWindow {
+			t.Errorf("Test %d: expected window %s, got %s", i, test.expectedWindow, rl.window)
+		}
+		if rl.rates != test.expectedRates {
+			t.Errorf("Test %d: expected rates %v, got %v", i, test.expectedRates, rl.rates)
+		}
+		if rl.slipRatio != test.expectedSlipRatio {
+			t.Errorf("Test %d: expected slip ratio %d, got %d", i, test.expectedSlipRatio, rl.slipRatio)
+		}
+		if rl.logOnly != test.expectedLogOnly {
+			t.Errorf("Test %d: expected log_only %t, got %t", i, test.expectedLogOnly, rl.logOnly)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package rrl
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/response"
+
+	"github.com/miekg/dns"
+)
+
+// category is the class of a response, each category has its own allowance.
+type category int
+
+const (
+	responses category = iota
+	nodata
+	nxdomains
+	referrals
+	errors
+	categories // the number of categories
+)
+
+var categoryToString = [categories]string{
+	responses: "responses",
+	nodata:    "nodata",
+	nxdomains: "nxdomains",
+	referrals: "referrals",
+	errors:    "errors",
+}
+
+func (c category) String() string { return categoryToString[c] }
+
+// token identifies the account a response is charged to. Responses that share the client's
+// network, category and name (and type) are considered identical.
+type token struct {
+	prefix   string
+	category category
+	name     string
+	qtype    uint16
+}
+
+func (t token) String() string {
+	return t.prefix + "/" + t.category.String() + "/" + t.name + "/" + strconv.Itoa(int(t.qtype))
+}
+
+// newToken returns the token for response m sent to a client in network prefix.
+//
+// Positive answers and NODATA responses are keyed on the query name and type, unless the answer
+// was synthesized from a wildcard, then the wildcard is used as the name. NXDOMAIN responses
+// are keyed on the zone (the owner name of the SOA record in the authority section), so that
+// random query names can't be used to evade the limits. Referrals are keyed on the name of the
+// delegation and all other errors are put in a single account for each client network.
+func newToken(prefix string, m *dns.Msg, now time.Time) token {
+	t := token{prefix: prefix}
+	if len(m.Question) > 0 {
+		t.name = strings.ToLower(m.Question[0].Name)
+		t.qtype = m.Question[0].Qtype
+	}
+
+	mt, _ := response.Typify(m, now)
+	switch mt {
+	case response.NoError:
+		t.category = responses
+		if w := wildcard(m.Answer); w != "" {
+			t.name = w
+		}
+	case response.NoData:
+		t.category = nodata
+		if w := wildcard(m.Ns); w != "" {
+			t.name = w
+		}
+	case response.NameError:
+		t.category = nxdomains
+		t.name = authority(m, dns.TypeSOA, t.name)
+		t.qtype = 0
+	case response.Delegation:
+		t.category = referrals
+		t.name = authority(m, dns.TypeNS, t.name)
+		t.qtype = 0
+	default:
+		t.category = errors
+		t.name = ""
+		t.qtype = 0
+	}
+	return t
+}
+
+// authority returns the lowercased owner name of the first record of type qtype in the authority
+// section of m, or name when there is no such record.
+func authority(m *dns.Msg, qtype uint16, name string) string {
+	for _, r := range m.Ns {
+		if r.Header().Rrtype == qtype {
+			return strings.ToLower(r.Header().Name)
+		}
+	}
+	return name
+}
+
+// wildcard returns the wildcard name an answer was synthesized from, or the empty string if it
+// wasn't synthesized. This can only be detected when the answer is signed: the labels field of
+// the RRSIG is smaller than the number of labels in the owner name.
+func wildcard(rrs []dns.RR) string {
+	for _, r := range rrs {
+		sig, ok := r.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+		labels := dns.SplitDomainName(sig.Hdr.Name)
+		if int(sig.Labels) >= len(labels) {
+			continue
+		}
+		return strings.ToLower(dns.Fqdn("*." + strings.Join(labels[len(labels)-int(sig.Labels):], ".")))
+	}
+	return ""
+}
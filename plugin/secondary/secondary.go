@@ -8,3 +8,14 @@ import "github.com/coredns/coredns/plugin/file"
 type Secondary struct {
 	file.File
 }
+
+// Ready implements the health.Readiness interface. Secondary is ready when all its zones have been
+// transferred (or loaded from their cache).
+func (s Secondary) Ready() bool {
+	for _, z := range s.Zones.Z {
+		if z.SOASerialIfDefined() == -1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,22 @@
+package secondary
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestReady(t *testing.T) {
+	z := file.NewZone("example.org.", "stdin")
+	s := Secondary{file.File{Zones: file.Zones{Z: map[string]*file.Zone{"example.org.": z}, Names: []string{"example.org."}}}}
+
+	if s.Ready() {
+		t.Errorf("Expected secondary not to be ready before the zone is transferred")
+	}
+
+	z.Insert(test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300"))
+	if !s.Ready() {
+		t.Errorf("Expected secondary to be ready after the zone is transferred")
+	}
+}
@@ -1,10 +1,13 @@
 package secondary
 
 import (
+	"path"
+
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 
 	"github.com/mholt/caddy"
@@ -50,6 +53,7 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 	z := make(map[string]*file.Zone)
 	names := []string{}
 	upstr := upstream.Upstream{}
+	config := dnsserver.GetConfig(c)
 	for c.Next() {
 
 		if c.Val() == "secondary" {
@@ -66,6 +70,8 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 				names = append(names, origins[i])
 			}
 
+			var keys map[string]tsig.Key
+
 			for c.NextBlock() {
 
 				t, f := []string{}, []string{}
@@ -73,10 +79,16 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 
 				switch c.Val() {
 				case "transfer":
-					t, f, e = parse.Transfer(c, true)
+					var key string
+					t, f, key, e = parse.Transfer(c, true)
 					if e != nil {
 						return file.Zones{}, e
 					}
+					for _, addrs := range [][]string{t, f} {
+						if keys, e = file.TransferKeys(c, keys, key, addrs); e != nil {
+							return file.Zones{}, e
+						}
+					}
 				case "upstream":
 					args := c.RemainingArgs()
 					var err error
@@ -84,6 +96,21 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 					if err != nil {
 						return file.Zones{}, err
 					}
+				case "cache":
+					if !c.NextArg() {
+						return file.Zones{}, c.ArgErr()
+					}
+					cache := c.Val()
+					if c.NextArg() {
+						return file.Zones{}, c.ArgErr()
+					}
+					if len(origins) > 1 {
+						return file.Zones{}, c.Err("cache can only be used with a single zone")
+					}
+					if !path.IsAbs(cache) && config.Root != "" {
+						cache = path.Join(config.Root, cache)
+					}
+					z[origins[0]].Cache = cache
 				default:
 					return file.Zones{}, c.Errf("unknown property '%s'", c.Val())
 				}
@@ -95,9 +122,14 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 					if f != nil {
 						z[origin].TransferFrom = append(z[origin].TransferFrom, f...)
 					}
+					z[origin].TransferKeys = keys
 					z[origin].Upstream = upstr
 				}
 			}
+
+			for _, origin := range origins {
+				z[origin].LoadCache()
+			}
 		}
 	}
 	return file.Zones{Z: z, Names: names}, nil
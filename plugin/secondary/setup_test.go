@@ -3,7 +3,11 @@ package secondary
 import (
 	"testing"
 
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
+
 	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
 )
 
 func TestSecondaryParse(t *testing.T) {
@@ -63,3 +67,80 @@ func TestSecondaryParse(t *testing.T) {
 		}
 	}
 }
+
+func TestSecondaryParseTsig(t *testing.T) {
+	tests := []struct {
+		inputFileRules string
+		shouldErr      bool
+	}{
+		{`secondary example.org {
+				transfer from 127.0.0.1 key xfr.example.org.
+				transfer to * key xfr.example.org.
+			}`, false},
+		{`secondary example.org {
+				transfer from 127.0.0.1 key unknown.example.org.
+			}`, true},
+	}
+
+	k := tsig.Key{Name: "xfr.example.org.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.inputFileRules)
+		dnsserver.GetConfig(c).TsigKeys = map[string]tsig.Key{k.Name: k}
+		s, err := secondaryParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		z := s.Z["example.org."]
+		for _, addr := range []string{"127.0.0.1:53", "*"} {
+			if x := z.TransferKeys[addr]; x != k {
+				t.Fatalf("Test %d expected key %v for %s, but got %v", i, k, addr, x)
+			}
+		}
+	}
+}
+
+func TestSecondaryParseCache(t *testing.T) {
+	tests := []struct {
+		inputFileRules string
+		shouldErr      bool
+		cache          string
+	}{
+		{`secondary example.org {
+				transfer from 127.0.0.1
+				cache /tmp/does-not-exist/db.example.org
+			}`, false, "/tmp/does-not-exist/db.example.org"},
+		{`secondary example.org {
+				cache
+			}`, true, ""},
+		{`secondary example.org {
+				cache a b
+			}`, true, ""},
+		{`secondary example.org example.net {
+				cache /tmp/does-not-exist/db.example.org
+			}`, true, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.inputFileRules)
+		s, err := secondaryParse(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if x := s.Z["example.org."].Cache; x != test.cache {
+			t.Fatalf("Test %d expected cache %q, but got %q", i, test.cache, x)
+		}
+	}
+}
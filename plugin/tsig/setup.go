@@ -0,0 +1,59 @@
+package tsig
+
+import (
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
+
+	"github.com/mholt/caddy"
+)
+
+func setup(c *caddy.Controller) error {
+	keys, err := tsigParse(c)
+	if err != nil {
+		return plugin.Error("tsig", err)
+	}
+
+	config := dnsserver.GetConfig(c)
+	if config.TsigKeys == nil {
+		config.TsigKeys = make(map[string]tsig.Key)
+	}
+	for _, k := range keys {
+		config.TsigKeys[k.Name] = k
+	}
+	return nil
+}
+
+func tsigParse(c *caddy.Controller) ([]tsig.Key, error) {
+	keys := []tsig.Key{}
+	for c.Next() {
+		if len(c.RemainingArgs()) > 0 {
+			return nil, c.ArgErr()
+		}
+		for c.NextBlock() {
+			switch c.Val() {
+			case "key":
+				args := c.RemainingArgs()
+				if len(args) != 3 {
+					return nil, c.ArgErr()
+				}
+				k, err := tsig.New(args[0], args[1], args[2])
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				for _, k1 := range keys {
+					if k1.Name == k.Name {
+						return nil, c.Errf("duplicate key '%s'", k.Name)
+					}
+				}
+				keys = append(keys, k)
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, c.Err("no keys defined")
+	}
+	return keys, nil
+}
@@ -0,0 +1,76 @@
+package tsig
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		keys      map[string]string // key name -> algorithm
+	}{
+		{`tsig {
+			key update.example.org. hmac-sha256 c2VjcmV0
+		}`, false, map[string]string{"update.example.org.": dns.HmacSHA256}},
+		{`tsig {
+			key Update.Example.org hmac-md5 c2VjcmV0
+			key xfr.example.org. hmac-sha512. c2VjcmV0
+		}`, false, map[string]string{"update.example.org.": dns.HmacMD5, "xfr.example.org.": dns.HmacSHA512}},
+		// fails
+		{`tsig`, true, nil},
+		{`tsig example.org {
+			key update.example.org. hmac-sha256 c2VjcmV0
+		}`, true, nil},
+		{`tsig {
+			key update.example.org. hmac-sha256
+		}`, true, nil},
+		{`tsig {
+			key update.example.org. hmac-foo c2VjcmV0
+		}`, true, nil},
+		{`tsig {
+			key update.example.org. hmac-sha256 not-base64!
+		}`, true, nil},
+		{`tsig {
+			key update.example.org. hmac-sha256 c2VjcmV0
+			key update.example.org. hmac-sha1 c2VjcmV0
+		}`, true, nil},
+		{`tsig {
+			secret update.example.org. hmac-sha256 c2VjcmV0
+		}`, true, nil},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		err := setup(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		keys := dnsserver.GetConfig(c).TsigKeys
+		if len(keys) != len(test.keys) {
+			t.Errorf("Test %d: expected %d keys, got %d", i, len(test.keys), len(keys))
+		}
+		for name, alg := range test.keys {
+			k, ok := keys[name]
+			if !ok {
+				t.Errorf("Test %d: expected key %s to be defined", i, name)
+				continue
+			}
+			if k.Algorithm != alg {
+				t.Errorf("Test %d: expected algorithm %s for key %s, got %s", i, alg, name, k.Algorithm)
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package tsig defines the TSIG keys a server uses to authenticate messages.
+package tsig
+
+import "github.com/mholt/caddy"
+
+func init() {
+	caddy.RegisterPlugin("tsig", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
@@ -0,0 +1,312 @@
+package validate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// rootAnchor is the DS record of the root zone's KSK-2017, it is used when no trust anchors are
+// configured.
+const rootAnchor = ". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"
+
+// holdDown is the time a new key must be seen before it is trusted, see RFC 5011, section 2.4.1.
+const holdDown = 30 * 24 * time.Hour
+
+// state is the state of a trust anchor as defined in RFC 5011, section 4.
+type state int
+
+const (
+	stateValid state = iota
+	stateAddPend
+	stateMissing
+	stateRevoked
+)
+
+func (s state) String() string {
+	switch s {
+	case stateAddPend:
+		return "addpend"
+	case stateMissing:
+		return "missing"
+	case stateRevoked:
+		return "revoked"
+	}
+	return "valid"
+}
+
+// anchor is a single trust anchor. It is either a DS record or a DNSKEY. DS records are replaced
+// by the DNSKEY they match, once that key is seen.
+type anchor struct {
+	ds    *dns.DS
+	key   *dns.DNSKEY
+	state state
+	added time.Time // when the key was first seen, for keys in addpend state.
+}
+
+// trusted returns true if a can be used to validate the DNSKEYs of its zone. Missing keys are still
+// trusted.
+func (a *anchor) trusted() bool { return a.state == stateValid || a.state == stateMissing }
+
+// matches returns true if a is (a DS record of) k.
+func (a *anchor) matches(k *dns.DNSKEY) bool {
+	if a.ds != nil {
+		if k.KeyTag() != a.ds.KeyTag || k.Algorithm != a.ds.Algorithm {
+			return false
+		}
+		ds := k.ToDS(a.ds.DigestType)
+		return ds != nil && strings.EqualFold(ds.Digest, a.ds.Digest)
+	}
+	return k.Algorithm == a.key.Algorithm && k.Protocol == a.key.Protocol && k.PublicKey == a.key.PublicKey
+}
+
+// anchors holds the trust anchors of a single zone. The DNSKEYs of the zone are tracked as
+// described in RFC 5011; when the anchors are read from a file, changes are written back to it.
+type anchors struct {
+	sync.Mutex
+	zone     string
+	file     string
+	holdDown time.Duration
+	list     []*anchor
+}
+
+// newAnchors returns the trust anchors in rrs, which must all belong to the same zone.
+func newAnchors(rrs []dns.RR) (*anchors, error) {
+	a := &anchors{holdDown: holdDown}
+	for _, rr := range rrs {
+		if err := a.add(rr, ""); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// readAnchors reads the trust anchors from file. The RFC 5011 state of each key is kept in a comment
+// after the record.
+func readAnchors(file string) (*anchors, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	a, err := parseAnchors(f, file)
+	if err != nil {
+		return nil, err
+	}
+	a.file = file
+	return a, nil
+}
+
+func parseAnchors(r io.Reader, file string) (*anchors, error) {
+	a := &anchors{holdDown: holdDown}
+	for x := range dns.ParseZone(r, ".", file) {
+		if x.Error != nil {
+			return nil, x.Error
+		}
+		if err := a.add(x.RR, x.Comment); err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+	}
+	if len(a.list) == 0 {
+		return nil, fmt.Errorf("%s: no trust anchors", file)
+	}
+	return a, nil
+}
+
+// add adds the trust anchor rr, comment holds its state.
+func (a *anchors) add(rr dns.RR, comment string) error {
+	name := strings.ToLower(rr.Header().Name)
+	if a.zone == "" {
+		a.zone = name
+	}
+	if name != a.zone {
+		return fmt.Errorf("trust anchors for more than one zone: %s and %s", a.zone, name)
+	}
+
+	t := &anchor{}
+	switch x := rr.(type) {
+	case *dns.DS:
+		t.ds = x
+	case *dns.DNSKEY:
+		t.key = x
+	default:
+		return fmt.Errorf("trust anchor must be a DS or DNSKEY record, got %s", dns.TypeToString[rr.Header().Rrtype])
+	}
+
+	for _, f := range strings.Fields(strings.TrimLeft(comment, "; ")) {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "state":
+			switch kv[1] {
+			case "valid":
+				t.state = stateValid
+			case "addpend":
+				t.state = stateAddPend
+			case "missing":
+				t.state = stateMissing
+			case "revoked":
+				t.state = stateRevoked
+			default:
+				return fmt.Errorf("unknown trust anchor state %q", kv[1])
+			}
+		case "added":
+			added, err := time.Parse(time.RFC3339, kv[1])
+			if err != nil {
+				return err
+			}
+			t.added = added
+		}
+	}
+	a.list = append(a.list, t)
+	return nil
+}
+
+// validate returns true if the DNSKEY RRset keys is signed by a trusted anchor. The anchors are
+// then updated with the keys in the RRset.
+func (a *anchors) validate(keys []*dns.DNSKEY, sigs []*dns.RRSIG, now time.Time) bool {
+	a.Lock()
+	defer a.Unlock()
+
+	set := make([]dns.RR, len(keys))
+	for i := range keys {
+		set[i] = keys[i]
+	}
+
+	ok := false
+	for _, t := range a.list {
+		if !t.trusted() {
+			continue
+		}
+		for _, k := range keys {
+			if k.Flags&dns.REVOKE == 0 && t.matches(k) && verifyRRset(set, sigs, []*dns.DNSKEY{k}, now) != nil {
+				ok = true
+			}
+		}
+	}
+	if !ok {
+		return false
+	}
+
+	if a.track(keys, sigs, set, now) && a.file != "" {
+		if err := a.write(); err != nil {
+			log.Errorf("Failed to write trust anchors to %s: %s", a.file, err)
+		}
+	}
+	return true
+}
+
+// track updates the anchors with the SEP keys in the validated DNSKEY RRset, see RFC 5011,
+// section 4. It returns true if anything changed.
+func (a *anchors) track(keys []*dns.DNSKEY, sigs []*dns.RRSIG, set []dns.RR, now time.Time) bool {
+	changed := false
+	seen := make(map[*anchor]bool)
+	for _, k := range keys {
+		if k.Flags&dns.SEP == 0 || k.Flags&dns.ZONE == 0 {
+			continue
+		}
+
+		var t *anchor
+		for _, t1 := range a.list {
+			if (k.Flags&dns.REVOKE == 0 || t1.key != nil) && t1.matches(k) {
+				t = t1
+				break
+			}
+		}
+
+		if k.Flags&dns.REVOKE != 0 {
+			// A revoked key must have signed the RRset itself.
+			if t != nil && t.state != stateRevoked && verifyRRset(set, sigs, []*dns.DNSKEY{k}, now) != nil {
+				log.Infof("Trust anchor %d for %s is revoked", k.KeyTag(), a.zone)
+				t.state, t.key = stateRevoked, k
+				changed = true
+			}
+			if t != nil {
+				seen[t] = true
+			}
+			continue
+		}
+
+		switch {
+		case t == nil:
+			log.Infof("New key %d for %s, trusted after %s", k.KeyTag(), a.zone, a.holdDown)
+			t = &anchor{key: k, state: stateAddPend, added: now}
+			a.list = append(a.list, t)
+			changed = true
+		case t.ds != nil:
+			t.ds, t.key = nil, k
+			changed = true
+		case t.state == stateAddPend && now.Sub(t.added) >= a.holdDown:
+			log.Infof("Key %d for %s is now a trust anchor", k.KeyTag(), a.zone)
+			t.state = stateValid
+			changed = true
+		case t.state == stateMissing:
+			t.state = stateValid
+			changed = true
+		}
+		seen[t] = true
+	}
+
+	list := a.list[:0]
+	for _, t := range a.list {
+		if !seen[t] {
+			switch t.state {
+			case stateAddPend:
+				// RFC 5011, section 4: a pending key that disappears is forgotten.
+				changed = true
+				continue
+			case stateValid:
+				if t.key != nil {
+					t.state = stateMissing
+					changed = true
+				}
+			}
+		}
+		list = append(list, t)
+	}
+	a.list = list
+	return changed
+}
+
+// write writes the anchors to their file. The file is replaced atomically.
+func (a *anchors) write() error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "; Trust anchors for %s, the state of each key is tracked as described in RFC 5011.\n", a.zone)
+	for _, t := range a.list {
+		if t.ds != nil {
+			fmt.Fprintf(buf, "%s\n", t.ds)
+			continue
+		}
+		fmt.Fprintf(buf, "%s ; state=%s", t.key, t.state)
+		if t.state == stateAddPend {
+			fmt.Fprintf(buf, " added=%s", t.added.UTC().Format(time.RFC3339))
+		}
+		fmt.Fprintln(buf)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(a.file), "."+filepath.Base(a.file))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), a.file)
+}
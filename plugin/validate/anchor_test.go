@@ -0,0 +1,132 @@
+package validate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// keySet returns the DNSKEY RRset of keys, signed by signers.
+func keySet(t *testing.T, keys []*dns.DNSKEY, signers ...*signer) ([]*dns.DNSKEY, []*dns.RRSIG) {
+	set := make([]dns.RR, len(keys))
+	for i := range keys {
+		set[i] = keys[i]
+	}
+	var sigs []*dns.RRSIG
+	for _, s := range signers {
+		rrs := s.sign(t, set...)
+		sigs = append(sigs, rrs[len(rrs)-1].(*dns.RRSIG))
+	}
+	return keys, sigs
+}
+
+func TestAnchorsTrack(t *testing.T) {
+	ksk1 := newSigner(t, ".", 257)
+	ksk2 := newSigner(t, ".", 257)
+
+	a, err := newAnchors([]dns.RR{ksk1.key.ToDS(dns.SHA256)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	// The DS anchor becomes a tracked key, the new key is pending.
+	if !validateAt(t, a, now, []*dns.DNSKEY{ksk1.key, ksk2.key}, ksk1) {
+		t.Fatalf("Expected DNSKEY RRset to validate")
+	}
+	if len(a.list) != 2 || a.list[0].key == nil || a.list[1].state != stateAddPend {
+		t.Fatalf("Expected tracked key and pending key, got %v", a.list)
+	}
+
+	// Not trusted before the hold-down time passed.
+	if ok := validateAt(t, a, now, []*dns.DNSKEY{ksk2.key}, ksk2); ok {
+		t.Errorf("Expected pending key not to be trusted")
+	}
+	if ok := validateAt(t, a, now.Add(holdDown+time.Hour), []*dns.DNSKEY{ksk1.key, ksk2.key}, ksk1); !ok {
+		t.Fatalf("Expected DNSKEY RRset to validate")
+	}
+	if a.list[1].state != stateValid {
+		t.Fatalf("Expected new key to be valid after the hold-down time, got %s", a.list[1].state)
+	}
+
+	// Revoke the old key.
+	revoked := *ksk1.key
+	revoked.Flags |= dns.REVOKE
+	ksk1.key = &revoked
+	if ok := validateAt(t, a, now.Add(holdDown+2*time.Hour), []*dns.DNSKEY{ksk1.key, ksk2.key}, ksk1, ksk2); !ok {
+		t.Fatalf("Expected DNSKEY RRset to validate")
+	}
+	if a.list[0].state != stateRevoked {
+		t.Errorf("Expected old key to be revoked, got %s", a.list[0].state)
+	}
+	if ok := validateAt(t, a, now.Add(holdDown+3*time.Hour), []*dns.DNSKEY{ksk1.key}, ksk1); ok {
+		t.Errorf("Expected revoked key not to be trusted")
+	}
+}
+
+func validateAt(t *testing.T, a *anchors, now time.Time, keys []*dns.DNSKEY, signers ...*signer) bool {
+	keys, sigs := keySet(t, keys, signers...)
+	return a.validate(keys, sigs, now)
+}
+
+func TestAnchorsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ksk1 := newSigner(t, ".", 257)
+	ksk2 := newSigner(t, ".", 257)
+
+	file := filepath.Join(dir, "root.key")
+	if err := ioutil.WriteFile(file, []byte(ksk1.key.String()+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := readAnchors(file)
+	if err != nil {
+		t.Fatalf("Failed to read trust anchors: %s", err)
+	}
+	if ok := validateAt(t, a, time.Now(), []*dns.DNSKEY{ksk1.key, ksk2.key}, ksk1); !ok {
+		t.Fatalf("Expected DNSKEY RRset to validate")
+	}
+
+	buf, _ := ioutil.ReadFile(file)
+	if !strings.Contains(string(buf), "state=addpend added=") {
+		t.Errorf("Expected pending key to be written, got %s", buf)
+	}
+	a, err = readAnchors(file)
+	if err != nil {
+		t.Fatalf("Failed to read trust anchors: %s", err)
+	}
+	if len(a.list) != 2 || a.list[1].state != stateAddPend || a.list[1].added.IsZero() {
+		t.Errorf("Expected pending key to be read back, got %v", a.list)
+	}
+}
+
+func TestParseAnchors(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+	}{
+		{rootAnchor, false},
+		{rootAnchor + "\norg. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D", true},
+		{". IN A 127.0.0.1", true},
+		{". IN DNSKEY 257 3 8 AwEAAa== ; state=bogus", true},
+		{"", true},
+	}
+	for i, tc := range tests {
+		_, err := parseAnchors(strings.NewReader(tc.input), "test")
+		if tc.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error, got none", i)
+		}
+		if !tc.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+		}
+	}
+}
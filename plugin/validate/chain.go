@@ -0,0 +1,230 @@
+package validate
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// zoneKeys is the outcome of validating the chain of trust to a name. For a secure name it holds the
+// zone the name is in and the validated keys of that zone.
+type zoneKeys struct {
+	zone    string
+	result  result
+	keys    []*dns.DNSKEY
+	expires time.Time
+}
+
+const (
+	maxTTL   = 1 * time.Hour   // maximum time a chain of trust is cached.
+	bogusTTL = 1 * time.Minute // time a bogus chain of trust is cached.
+)
+
+// zone returns the chain of trust to name. The result is cached for the lowest TTL of the records
+// it is built from.
+func (v *Validate) zone(ctx context.Context, w dns.ResponseWriter, name string) *zoneKeys {
+	name = strings.ToLower(name)
+	now := v.now()
+	if i, ok := v.keys.Get(hash(name)); ok {
+		if zk := i.(*zoneKeys); now.Before(zk.expires) {
+			return zk
+		}
+	}
+
+	zk, ttl, err := v.chain(ctx, w, name)
+	if err != nil {
+		log.Warningf("Failed to validate chain of trust to %s: %s", name, err)
+		return &zoneKeys{zone: name, result: bogus}
+	}
+	if zk.result == bogus {
+		ttl = bogusTTL
+	}
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	zk1 := *zk
+	zk1.expires = now.Add(ttl)
+	v.keys.Add(hash(name), &zk1)
+	return &zk1
+}
+
+// chain builds the chain of trust to name: from the DS records of name it walks up to the
+// trust anchor, the zone that signed them, and then back down to the DNSKEYs of name. It
+// also returns the time for which the result may be cached.
+func (v *Validate) chain(ctx context.Context, w dns.ResponseWriter, name string) (*zoneKeys, time.Duration, error) {
+	if a, ok := v.anchors[name]; ok {
+		return v.anchorKeys(ctx, w, a)
+	}
+	if !v.trusted(name) {
+		return &zoneKeys{zone: name, result: insecure}, maxTTL, nil
+	}
+
+	m, err := v.lookup(ctx, w, name, dns.TypeDS)
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl := minTTL(m)
+
+	signer := ""
+	for _, rrs := range [][]dns.RR{m.Answer, m.Ns} {
+		for _, rr := range rrs {
+			if sig, ok := rr.(*dns.RRSIG); ok && signer == "" {
+				signer = strings.ToLower(sig.SignerName)
+			}
+		}
+	}
+	if signer == "" {
+		// An unsigned response; fine in an insecure zone, but not in a secure one.
+		p := v.zone(ctx, w, parent(name))
+		if p.result == secure {
+			return &zoneKeys{zone: name, result: bogus}, ttl, nil
+		}
+		return &zoneKeys{zone: p.zone, result: p.result}, ttl, nil
+	}
+	if signer == name || !dns.IsSubDomain(signer, name) {
+		return &zoneKeys{zone: name, result: bogus}, ttl, nil
+	}
+
+	p := v.zone(ctx, w, signer)
+	if p.result != secure {
+		return &zoneKeys{zone: p.zone, result: p.result}, ttl, nil
+	}
+	for _, rrs := range [][]dns.RR{m.Answer, m.Ns} {
+		for _, set := range rrsets(rrs) {
+			hdr := set[0].Header()
+			sigs := signatures(rrs, hdr.Name, hdr.Rrtype)
+			if hdr.Rrtype == dns.TypeNS && len(sigs) == 0 {
+				continue
+			}
+			if verifyRRset(set, sigs, p.keys, v.now()) == nil {
+				return &zoneKeys{zone: name, result: bogus}, ttl, nil
+			}
+		}
+	}
+
+	var ds []*dns.DS
+	for _, rr := range m.Answer {
+		if d, ok := rr.(*dns.DS); ok && strings.EqualFold(d.Hdr.Name, name) {
+			ds = append(ds, d)
+		}
+	}
+	if len(ds) == 0 {
+		switch denyDS(name, m.Answer, m.Ns) {
+		case noCut:
+			return &zoneKeys{zone: p.zone, result: secure, keys: p.keys}, ttl, nil
+		case insecureCut:
+			return &zoneKeys{zone: name, result: insecure}, ttl, nil
+		}
+		return &zoneKeys{zone: name, result: bogus}, ttl, nil
+	}
+
+	ds = supportedDS(ds)
+	if len(ds) == 0 {
+		// RFC 4035, section 5.2: no DS with a supported algorithm makes the zone insecure.
+		return &zoneKeys{zone: name, result: insecure}, ttl, nil
+	}
+
+	m, err = v.lookup(ctx, w, name, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, 0, err
+	}
+	if t := minTTL(m); t < ttl {
+		ttl = t
+	}
+	keys, sigs := dnskeys(m, name)
+	set := make([]dns.RR, len(keys))
+	for i := range keys {
+		set[i] = keys[i]
+	}
+	for _, d := range ds {
+		for _, k := range keys {
+			if k.KeyTag() != d.KeyTag || k.Algorithm != d.Algorithm {
+				continue
+			}
+			kd := k.ToDS(d.DigestType)
+			if kd == nil || !strings.EqualFold(kd.Digest, d.Digest) {
+				continue
+			}
+			if verifyRRset(set, sigs, []*dns.DNSKEY{k}, v.now()) != nil {
+				return &zoneKeys{zone: name, result: secure, keys: zoneSigning(keys)}, ttl, nil
+			}
+		}
+	}
+	return &zoneKeys{zone: name, result: bogus}, ttl, nil
+}
+
+// anchorKeys validates the DNSKEYs of the zone of the trust anchors a.
+func (v *Validate) anchorKeys(ctx context.Context, w dns.ResponseWriter, a *anchors) (*zoneKeys, time.Duration, error) {
+	m, err := v.lookup(ctx, w, a.zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, 0, err
+	}
+	keys, sigs := dnskeys(m, a.zone)
+	if !a.validate(keys, sigs, v.now()) {
+		return &zoneKeys{zone: a.zone, result: bogus}, minTTL(m), nil
+	}
+	return &zoneKeys{zone: a.zone, result: secure, keys: zoneSigning(keys)}, minTTL(m), nil
+}
+
+// trusted returns true if name is below one of the trust anchors.
+func (v *Validate) trusted(name string) bool {
+	for zone := range v.anchors {
+		if dns.IsSubDomain(zone, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dnskeys returns the DNSKEY records for name in the answer section of m and their signatures.
+func dnskeys(m *dns.Msg, name string) ([]*dns.DNSKEY, []*dns.RRSIG) {
+	var keys []*dns.DNSKEY
+	for _, rr := range m.Answer {
+		if k, ok := rr.(*dns.DNSKEY); ok && strings.EqualFold(k.Hdr.Name, name) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, signatures(m.Answer, name, dns.TypeDNSKEY)
+}
+
+// zoneSigning returns the keys that may sign the data in a zone: the ones with the ZONE flag that
+// have not been revoked.
+func zoneSigning(keys []*dns.DNSKEY) []*dns.DNSKEY {
+	var zs []*dns.DNSKEY
+	for _, k := range keys {
+		if k.Flags&dns.ZONE != 0 && k.Flags&dns.REVOKE == 0 {
+			zs = append(zs, k)
+		}
+	}
+	return zs
+}
+
+// supportedDS returns the DS records in ds that use a digest and algorithm we can validate.
+func supportedDS(ds []*dns.DS) []*dns.DS {
+	var sup []*dns.DS
+	for _, d := range ds {
+		if _, ok := dns.HashToString[d.DigestType]; !ok || d.DigestType == dns.GOST94 {
+			continue
+		}
+		if _, ok := dns.AlgorithmToHash[d.Algorithm]; !ok {
+			continue
+		}
+		sup = append(sup, d)
+	}
+	return sup
+}
+
+// minTTL returns the lowest TTL of the records in the answer and authority section of m.
+func minTTL(m *dns.Msg) time.Duration {
+	ttl := uint32(maxTTL.Seconds())
+	for _, rrs := range [][]dns.RR{m.Answer, m.Ns} {
+		for _, rr := range rrs {
+			if t := rr.Header().Ttl; t < ttl {
+				ttl = t
+			}
+		}
+	}
+	return time.Duration(ttl) * time.Second
+}
@@ -0,0 +1,284 @@
+package validate
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// cut is what the denial of a DS RRset tells us about a name.
+type cut int
+
+const (
+	// unproven means the denial is missing or doesn't prove the absence of the DS RRset.
+	unproven cut = iota
+	// noCut means the name is not a delegation, it is in the same zone as its parent.
+	noCut
+	// insecureCut means the name is a delegation to an unsigned zone.
+	insecureCut
+)
+
+// denyDS checks the proof that there is no DS RRset at name in the (validated) answer and authority
+// section of a response.
+func denyDS(name string, answer, ns []dns.RR) cut {
+	for _, rr := range answer {
+		if c, ok := rr.(*dns.CNAME); ok && strings.EqualFold(c.Hdr.Name, name) {
+			// A CNAME can't coexist with a delegation.
+			return noCut
+		}
+	}
+
+	nsec, nsec3 := denials(ns)
+	for _, n := range nsec {
+		if strings.EqualFold(n.Hdr.Name, name) {
+			return delegation(n.TypeBitMap)
+		}
+		if covers(n, name) {
+			return noCut
+		}
+	}
+	if len(nsec3) == 0 {
+		return unproven
+	}
+	for _, n := range nsec3 {
+		if n.Match(name) {
+			return delegation(n.TypeBitMap)
+		}
+	}
+	// RFC 5155, section 8.6: the next closer name must be covered by an opt-out NSEC3.
+	_, nc, ok := closestEncloser(name, nsec3)
+	if !ok {
+		return unproven
+	}
+	if nc.Flags&optOut != 0 {
+		return insecureCut
+	}
+	return noCut
+}
+
+// delegation returns the cut for a name that has the types in bitmap.
+func delegation(bitmap []uint16) cut {
+	switch {
+	case hasType(bitmap, dns.TypeDS):
+		return unproven
+	case hasType(bitmap, dns.TypeNS) && !hasType(bitmap, dns.TypeSOA):
+		return insecureCut
+	}
+	return noCut
+}
+
+// nameError checks the proof that name doesn't exist, see RFC 4035, section 5.4 and RFC 5155,
+// section 8.4.
+func nameError(name string, ns []dns.RR) bool {
+	nsec, nsec3 := denials(ns)
+	for _, n := range nsec {
+		if !covers(n, name) {
+			continue
+		}
+		return coveredBy(nsec, wildcard(encloser(name, n)))
+	}
+
+	ce, _, ok := closestEncloser(name, nsec3)
+	if !ok {
+		return false
+	}
+	for _, n := range nsec3 {
+		if n.Cover(wildcard(ce)) {
+			return true
+		}
+	}
+	return false
+}
+
+// noData checks the proof that name exists but has no data of type qtype, see RFC 4035, section 5.4
+// and RFC 5155, section 8.5 to 8.7.
+func noData(name string, qtype uint16, ns []dns.RR) bool {
+	nsec, nsec3 := denials(ns)
+	for _, n := range nsec {
+		if strings.EqualFold(n.Hdr.Name, name) {
+			return !hasType(n.TypeBitMap, qtype) && !hasType(n.TypeBitMap, dns.TypeCNAME)
+		}
+	}
+	for _, n := range nsec {
+		if !covers(n, name) {
+			continue
+		}
+		if dns.IsSubDomain(name, n.NextDomain) {
+			// An empty non-terminal.
+			return true
+		}
+		// Wildcard no data.
+		wc := wildcard(encloser(name, n))
+		for _, w := range nsec {
+			if strings.EqualFold(w.Hdr.Name, wc) {
+				return !hasType(w.TypeBitMap, qtype) && !hasType(w.TypeBitMap, dns.TypeCNAME)
+			}
+		}
+		return false
+	}
+
+	for _, n := range nsec3 {
+		if n.Match(name) {
+			return !hasType(n.TypeBitMap, qtype) && !hasType(n.TypeBitMap, dns.TypeCNAME)
+		}
+	}
+	ce, nc, ok := closestEncloser(name, nsec3)
+	if !ok {
+		return false
+	}
+	if qtype == dns.TypeDS && nc.Flags&optOut != 0 {
+		return true
+	}
+	for _, n := range nsec3 {
+		if n.Match(wildcard(ce)) {
+			return !hasType(n.TypeBitMap, qtype) && !hasType(n.TypeBitMap, dns.TypeCNAME)
+		}
+	}
+	return false
+}
+
+// noCloserMatch checks the proof that a wildcard with closest encloser ce was rightfully expanded
+// for name: there must not be a closer match for name.
+func noCloserMatch(name, ce string, ns []dns.RR) bool {
+	nsec, nsec3 := denials(ns)
+	if coveredBy(nsec, name) {
+		return true
+	}
+	nc := nextCloser(name, ce)
+	for _, n := range nsec3 {
+		if n.Cover(nc) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestEncloser finds the closest encloser of name in nsec3, it also returns the NSEC3 that covers
+// the next closer name. See RFC 5155, section 8.3.
+func closestEncloser(name string, nsec3 []*dns.NSEC3) (string, *dns.NSEC3, bool) {
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		ce := name[off:]
+		if off == 0 {
+			continue
+		}
+		matched := false
+		for _, n := range nsec3 {
+			if n.Match(ce) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		nc := nextCloser(name, ce)
+		for _, n := range nsec3 {
+			if n.Cover(nc) {
+				return strings.ToLower(ce), n, true
+			}
+		}
+		return "", nil, false
+	}
+	return "", nil, false
+}
+
+// nextCloser returns the name one label longer than ce, on the way to name.
+func nextCloser(name, ce string) string {
+	labels := dns.CountLabel(name) - dns.CountLabel(ce)
+	if labels < 1 {
+		return name
+	}
+	idx := dns.Split(name)
+	return name[idx[labels-1]:]
+}
+
+// denials returns the NSEC and NSEC3 records in rrs. NSEC3 records with an unknown hash algorithm
+// are skipped.
+func denials(rrs []dns.RR) ([]*dns.NSEC, []*dns.NSEC3) {
+	var (
+		nsec  []*dns.NSEC
+		nsec3 []*dns.NSEC3
+	)
+	for _, rr := range rrs {
+		switch x := rr.(type) {
+		case *dns.NSEC:
+			nsec = append(nsec, x)
+		case *dns.NSEC3:
+			if x.Hash == dns.SHA1 {
+				nsec3 = append(nsec3, x)
+			}
+		}
+	}
+	return nsec, nsec3
+}
+
+// covers returns true if name falls between the owner name and the next name of n.
+func covers(n *dns.NSEC, name string) bool {
+	owner, next := n.Hdr.Name, n.NextDomain
+	if compare(owner, next) < 0 {
+		return compare(owner, name) < 0 && compare(name, next) < 0
+	}
+	// The last NSEC in the zone, next is the apex.
+	return compare(owner, name) < 0 && dns.IsSubDomain(next, name)
+}
+
+// coveredBy returns true if name is covered by one of the records in nsec.
+func coveredBy(nsec []*dns.NSEC, name string) bool {
+	for _, n := range nsec {
+		if covers(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// encloser returns the closest encloser of name proven by the covering NSEC n: the longest ancestor
+// name shares with the owner or the next name of n.
+func encloser(name string, n *dns.NSEC) string {
+	l1 := dns.CompareDomainName(name, n.Hdr.Name)
+	l2 := dns.CompareDomainName(name, n.NextDomain)
+	if l2 > l1 {
+		l1 = l2
+	}
+	if l1 == 0 {
+		return "."
+	}
+	idx := dns.Split(name)
+	if l1 >= len(idx) {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(name[idx[len(idx)-l1]:])
+}
+
+// wildcard returns the wildcard name below ce.
+func wildcard(ce string) string {
+	if ce == "." {
+		return "*."
+	}
+	return "*." + ce
+}
+
+func hasType(bitmap []uint16, qtype uint16) bool {
+	for _, t := range bitmap {
+		if t == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+// compare orders a and b in the canonical DNS name order of RFC 4034, section 6.1.
+func compare(a, b string) int {
+	la := dns.SplitDomainName(strings.ToLower(a))
+	lb := dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := bytes.Compare([]byte(la[i]), []byte(lb[j])); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// optOut is the opt-out flag of an NSEC3 record.
+const optOut = 1
@@ -0,0 +1,112 @@
+package validate
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// nsec3Chain returns the NSEC3 chain for names in zone, the types of each name are in types.
+func nsec3Chain(zone string, types map[string][]uint16, flags uint8) []dns.RR {
+	hashes := []string{}
+	byHash := map[string]string{}
+	for name := range types {
+		h := dns.HashName(name, dns.SHA1, 0, "")
+		hashes = append(hashes, h)
+		byHash[h] = name
+	}
+	sort.Strings(hashes)
+
+	rrs := []dns.RR{}
+	for i, h := range hashes {
+		rrs = append(rrs, &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(h) + "." + zone, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 3600},
+			Hash:       dns.SHA1,
+			Flags:      flags,
+			NextDomain: hashes[(i+1)%len(hashes)],
+			HashLength: 20,
+			TypeBitMap: types[byHash[h]],
+		})
+	}
+	return rrs
+}
+
+func TestNSEC3(t *testing.T) {
+	chain := nsec3Chain("example.org.", map[string][]uint16{
+		"example.org.":     {dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeDNSKEY, dns.TypeNSEC3PARAM},
+		"www.example.org.": {dns.TypeA, dns.TypeRRSIG},
+		"sub.example.org.": {dns.TypeNS},
+	}, 0)
+
+	if !noData("www.example.org.", dns.TypeAAAA, chain) {
+		t.Errorf("Expected no data for www.example.org./AAAA to be proven")
+	}
+	if noData("www.example.org.", dns.TypeA, chain) {
+		t.Errorf("Expected no data for www.example.org./A not to be proven")
+	}
+	if !nameError("nope.example.org.", chain) {
+		t.Errorf("Expected name error for nope.example.org. to be proven")
+	}
+	if nameError("www.example.org.", chain) {
+		t.Errorf("Expected name error for www.example.org. not to be proven")
+	}
+	if c := denyDS("sub.example.org.", nil, chain); c != insecureCut {
+		t.Errorf("Expected sub.example.org. to be an insecure delegation, got %d", c)
+	}
+	if c := denyDS("www.example.org.", nil, chain); c != noCut {
+		t.Errorf("Expected www.example.org. not to be a delegation, got %d", c)
+	}
+
+	optout := nsec3Chain("example.org.", map[string][]uint16{
+		"example.org.":     {dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeDNSKEY, dns.TypeNSEC3PARAM},
+		"www.example.org.": {dns.TypeA, dns.TypeRRSIG},
+	}, optOut)
+	if c := denyDS("unsigned.example.org.", nil, optout); c != insecureCut {
+		t.Errorf("Expected unsigned.example.org. to be an insecure delegation, got %d", c)
+	}
+}
+
+func TestNSEC(t *testing.T) {
+	nsec := []dns.RR{
+		rr(t, "example.org. 3600 IN NSEC b.example.org. NS SOA RRSIG NSEC DNSKEY"),
+		rr(t, "b.example.org. 3600 IN NSEC d.c.example.org. A RRSIG NSEC"),
+		rr(t, "d.c.example.org. 3600 IN NSEC example.org. A RRSIG NSEC"),
+	}
+
+	tests := []struct {
+		name  string
+		qtype uint16
+		rcode int
+		ok    bool
+	}{
+		{"a.example.org.", dns.TypeA, dns.RcodeNameError, true},
+		{"z.example.org.", dns.TypeA, dns.RcodeNameError, true},
+		{"b.example.org.", dns.TypeA, dns.RcodeNameError, false},
+		{"b.example.org.", dns.TypeAAAA, dns.RcodeSuccess, true},
+		{"b.example.org.", dns.TypeA, dns.RcodeSuccess, false},
+		{"c.example.org.", dns.TypeA, dns.RcodeSuccess, true}, // empty non-terminal
+	}
+	for i, tc := range tests {
+		ok := false
+		if tc.rcode == dns.RcodeNameError {
+			ok = nameError(tc.name, nsec)
+		} else {
+			ok = noData(tc.name, tc.qtype, nsec)
+		}
+		if ok != tc.ok {
+			t.Errorf("Test %d: expected proof for %s to be %t", i, tc.name, tc.ok)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// The canonical order example from RFC 4034, section 6.1.
+	names := []string{"example.", "a.example.", "yljkjljk.a.example.", "Z.a.example.", "zABC.a.EXAMPLE.", "z.example.", "*.z.example."}
+	for i := 0; i < len(names)-1; i++ {
+		if compare(names[i], names[i+1]) >= 0 {
+			t.Errorf("Expected %s to sort before %s", names[i], names[i+1])
+		}
+	}
+}
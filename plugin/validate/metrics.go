@@ -0,0 +1,18 @@
+package validate
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Variables declared for monitoring.
+var (
+	// ResultCount is the number of validated responses, by result.
+	ResultCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "validate",
+		Name:      "results_total",
+		Help:      "Counter of validated responses, by result.",
+	}, []string{"server", "result"})
+)
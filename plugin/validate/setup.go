@@ -0,0 +1,102 @@
+package validate
+
+import (
+	"path/filepath"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/mholt/caddy"
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("validate")
+
+func init() {
+	caddy.RegisterPlugin("validate", caddy.Plugin{
+		ServerType: "dns",
+		Action:     setup,
+	})
+}
+
+func setup(c *caddy.Controller) error {
+	v, err := validateParse(c)
+	if err != nil {
+		return plugin.Error("validate", err)
+	}
+
+	c.OnStartup(func() error {
+		metrics.MustRegister(c, ResultCount)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		v.Next = next
+		return v
+	})
+
+	return nil
+}
+
+func validateParse(c *caddy.Controller) (*Validate, error) {
+	var zones []string
+	tas := []*anchors{}
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		zones = make([]string, len(c.ServerBlockKeys))
+		copy(zones, c.ServerBlockKeys)
+		if args := c.RemainingArgs(); len(args) > 0 {
+			zones = args
+		}
+		for i := range zones {
+			zones[i] = plugin.Host(zones[i]).Normalize()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "trust_anchor":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				file := args[0]
+				if !filepath.IsAbs(file) && config.Root != "" {
+					file = filepath.Join(config.Root, file)
+				}
+				a, err := readAnchors(file)
+				if err != nil {
+					return nil, err
+				}
+				for _, a1 := range tas {
+					if a1.zone == a.zone {
+						return nil, c.Errf("duplicate trust anchors for zone '%s'", a.zone)
+					}
+				}
+				tas = append(tas, a)
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(tas) == 0 {
+		rr, _ := dns.NewRR(rootAnchor)
+		a, _ := newAnchors([]dns.RR{rr})
+		tas = append(tas, a)
+	}
+
+	v := New(zones)
+	for _, a := range tas {
+		v.anchors[a.zone] = a
+	}
+	return v, nil
+}
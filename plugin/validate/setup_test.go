@@ -0,0 +1,72 @@
+package validate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mholt/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "root.key")
+	if err := ioutil.WriteFile(file, []byte(rootAnchor+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     []string
+		anchors   int
+	}{
+		{`validate`, false, []string{"."}, 1},
+		{`validate example.org`, false, []string{"example.org."}, 1},
+		{`validate {
+			trust_anchor ` + file + `
+		}`, false, []string{"."}, 1},
+		// fails
+		{`validate {
+			trust_anchor ` + file + `
+			trust_anchor ` + file + `
+		}`, true, nil, 0},
+		{`validate {
+			trust_anchor
+		}`, true, nil, 0},
+		{`validate {
+			trust_anchor /does/not/exist
+		}`, true, nil, 0},
+		{`validate {
+			blah
+		}`, true, nil, 0},
+		{"validate\nvalidate", true, nil, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		c.ServerBlockKeys = []string{"."}
+		v, err := validateParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(v.Zones) != len(tc.zones) || v.Zones[0] != tc.zones[0] {
+			t.Errorf("Test %d: expected zones %v, got %v", i, tc.zones, v.Zones)
+		}
+		if len(v.anchors) != tc.anchors {
+			t.Errorf("Test %d: expected %d trust anchors, got %d", i, tc.anchors, len(v.anchors))
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package validate implements a plugin that validates DNSSEC signed responses.
+package validate
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Validate validates the responses of the next plugin against the configured trust anchors.
+type Validate struct {
+	Next  plugin.Handler
+	Zones []string
+
+	anchors map[string]*anchors // trust anchors by zone
+	keys    *cache.Cache        // validated chains of trust, see zone.
+
+	now func() time.Time
+}
+
+// New returns a new Validate without trust anchors.
+func New(zones []string) *Validate {
+	return &Validate{
+		Zones:   zones,
+		anchors: make(map[string]*anchors),
+		keys:    cache.New(defaultCap),
+		now:     time.Now,
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (v *Validate) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(v.Zones).Matches(state.Name())
+	if zone == "" || r.CheckingDisabled {
+		return plugin.NextOrFailure(v.Name(), v.Next, ctx, w, r)
+	}
+
+	r1 := r.Copy()
+	if o := r1.IsEdns0(); o != nil {
+		o.SetDo()
+	} else {
+		r1.SetEdns0(4096, true)
+	}
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(v.Name(), v.Next, ctx, nw, r1)
+	if nw.Msg == nil {
+		return rcode, err
+	}
+	m := nw.Msg
+
+	server := metrics.WithServer(ctx)
+	res := v.validate(ctx, w, state.Name(), state.QType(), m)
+	ResultCount.WithLabelValues(server, res.String()).Inc()
+
+	switch res {
+	case bogus:
+		log.Warningf("Bogus response for %s/%s", state.Name(), state.Type())
+		return dns.RcodeServerFailure, nil
+	case secure:
+		// RFC 6840, section 5.7: only set AD for clients that signal they understand it.
+		m.AuthenticatedData = state.Do() || r.AuthenticatedData
+	default:
+		m.AuthenticatedData = false
+	}
+
+	if !state.Do() {
+		m.Answer = filter(m.Answer, state.QType())
+		m.Ns = filter(m.Ns, state.QType())
+		m.Extra = filter(m.Extra, state.QType())
+	}
+	if !state.SizeAndDo(m) {
+		m.Extra = withoutOPT(m.Extra)
+	}
+
+	w.WriteMsg(m)
+	return rcode, err
+}
+
+// Name implements the Handler interface.
+func (v *Validate) Name() string { return "validate" }
+
+// lookup sends a query for name and qtype with the DO bit set to the next plugin and returns the response.
+func (v *Validate) lookup(ctx context.Context, w dns.ResponseWriter, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(4096, true)
+
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(v.Name(), v.Next, ctx, nw, m)
+	if err != nil {
+		return nil, err
+	}
+	if nw.Msg == nil {
+		return nil, errNoResponse{name, qtype, rcode}
+	}
+	return nw.Msg, nil
+}
+
+type errNoResponse struct {
+	name  string
+	qtype uint16
+	rcode int
+}
+
+func (e errNoResponse) Error() string {
+	return "no response for " + e.name + "/" + dns.TypeToString[e.qtype] + ": " + dns.RcodeToString[e.rcode]
+}
+
+// filter removes the DNSSEC records from rrs, unless they have type qtype.
+func filter(rrs []dns.RR, qtype uint16) []dns.RR {
+	out := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		switch t := rr.Header().Rrtype; t {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			if t != qtype {
+				continue
+			}
+		}
+		out = append(out, rr)
+	}
+	return out
+}
+
+func withoutOPT(rrs []dns.RR) []dns.RR {
+	out := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+// parent returns the parent of name, the parent of the root zone is the root zone.
+func parent(name string) string {
+	off, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[off:]
+}
+
+// hash returns the key under which the chain of trust of name is cached.
+func hash(name string) uint64 { return cache.Hash([]byte(strings.ToLower(name))) }
+
+const defaultCap = 10000 // default capacity of the cache of validated keys.
@@ -0,0 +1,272 @@
+package validate
+
+import (
+	"context"
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// signer holds the key of a test zone.
+type signer struct {
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newSigner(t *testing.T, zone string, flags uint16) *signer {
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	return &signer{key: k, priv: priv.(crypto.Signer)}
+}
+
+// sign returns rrs followed by their signature.
+func (s *signer) sign(t *testing.T, rrs ...dns.RR) []dns.RR {
+	hdr := rrs[0].Header()
+	sig := &dns.RRSIG{
+		Hdr:         dns.RR_Header{Name: hdr.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: hdr.Ttl},
+		TypeCovered: hdr.Rrtype,
+		Algorithm:   s.key.Algorithm,
+		OrigTtl:     hdr.Ttl,
+		Expiration:  uint32(time.Now().Add(365 * 24 * time.Hour).Unix()),
+		Inception:   uint32(time.Now().Add(-24 * time.Hour).Unix()),
+		KeyTag:      s.key.KeyTag(),
+		SignerName:  s.key.Hdr.Name,
+	}
+	if err := sig.Sign(s.priv, rrs); err != nil {
+		t.Fatalf("Failed to sign %s: %s", rrs[0], err)
+	}
+	return append(rrs, sig)
+}
+
+func rr(t *testing.T, s string) dns.RR {
+	r, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %s", s, err)
+	}
+	return r
+}
+
+// testZones are the signed root zone, the signed zone example.org. and the unsigned zone unsigned.org.
+type testZones struct {
+	root, example *signer
+	answers       map[string]*dns.Msg
+}
+
+func newTestZones(t *testing.T) *testZones {
+	z := &testZones{
+		root:    newSigner(t, ".", 257),
+		example: newSigner(t, "example.org.", 257),
+		answers: make(map[string]*dns.Msg),
+	}
+	answer := func(name string, qtype uint16, rcode int, answer, ns []dns.RR) {
+		m := new(dns.Msg)
+		m.Rcode = rcode
+		m.Answer = answer
+		m.Ns = ns
+		z.answers[name+"/"+dns.TypeToString[qtype]] = m
+	}
+
+	ds := z.example.key.ToDS(dns.SHA256)
+	ds.Hdr.Ttl = 3600
+
+	answer(".", dns.TypeDNSKEY, dns.RcodeSuccess, z.root.sign(t, z.root.key), nil)
+	answer("example.org.", dns.TypeDS, dns.RcodeSuccess, z.root.sign(t, ds), nil)
+	answer("example.org.", dns.TypeDNSKEY, dns.RcodeSuccess, z.example.sign(t, z.example.key), nil)
+	answer("unsigned.org.", dns.TypeDS, dns.RcodeSuccess, nil,
+		z.root.sign(t, rr(t, "unsigned.org. 3600 IN NSEC zzz. NS RRSIG NSEC")))
+	answer("www.unsigned.org.", dns.TypeDS, dns.RcodeSuccess, nil,
+		[]dns.RR{rr(t, "unsigned.org. 3600 IN SOA ns.unsigned.org. root.unsigned.org. 1 3600 3600 3600 3600")})
+
+	answer("www.example.org.", dns.TypeA, dns.RcodeSuccess, z.example.sign(t, rr(t, "www.example.org. 3600 IN A 127.0.0.1")), nil)
+	answer("www.unsigned.org.", dns.TypeA, dns.RcodeSuccess, []dns.RR{rr(t, "www.unsigned.org. 3600 IN A 127.0.0.1")}, nil)
+	answer("unsigned.example.org.", dns.TypeA, dns.RcodeSuccess, []dns.RR{rr(t, "unsigned.example.org. 3600 IN A 127.0.0.1")}, nil)
+	other := newSigner(t, "example.org.", 257)
+	answer("bad.example.org.", dns.TypeA, dns.RcodeSuccess, other.sign(t, rr(t, "bad.example.org. 3600 IN A 127.0.0.1")), nil)
+	answer("nsec.example.org.", dns.TypeA, dns.RcodeNameError, nil,
+		z.example.sign(t, rr(t, "example.org. 3600 IN SOA ns.example.org. root.example.org. 1 3600 3600 3600 3600")))
+	answer("a.wild.example.org.", dns.TypeTXT, dns.RcodeSuccess,
+		expand(z.example.sign(t, rr(t, `*.wild.example.org. 3600 IN TXT "wild"`)), "a.wild.example.org."), nil)
+
+	// A DNAME with a synthesized CNAME, and the same DNAME with a CNAME pointing elsewhere.
+	dname := z.example.sign(t, rr(t, "dname.example.org. 3600 IN DNAME example.org."))
+	answer("www.dname.example.org.", dns.TypeA, dns.RcodeSuccess, append(append(append([]dns.RR{}, dname...),
+		rr(t, "www.dname.example.org. 3600 IN CNAME www.example.org.")),
+		z.example.sign(t, rr(t, "www.example.org. 3600 IN A 127.0.0.1"))...), nil)
+	answer("evil.dname.example.org.", dns.TypeA, dns.RcodeSuccess, append(append([]dns.RR{}, dname...),
+		rr(t, "evil.dname.example.org. 3600 IN CNAME www.unsigned.org."),
+		rr(t, "www.unsigned.org. 3600 IN A 127.0.0.1")), nil)
+	return z
+}
+
+// expand returns rrs as expanded from a wildcard for name.
+func expand(rrs []dns.RR, name string) []dns.RR {
+	for _, rr := range rrs {
+		rr.Header().Name = name
+	}
+	return rrs
+}
+
+// nxdomain returns the signed proof that names between example.org. and www.example.org. don't exist.
+func (z *testZones) nxdomain(t *testing.T) []dns.RR {
+	ns := z.example.sign(t, rr(t, "example.org. 3600 IN SOA ns.example.org. root.example.org. 1 3600 3600 3600 3600"))
+	return append(ns, z.example.sign(t, rr(t, "example.org. 3600 IN NSEC www.example.org. NS SOA RRSIG NSEC DNSKEY"))...)
+}
+
+// nodata returns the signed proof that www.example.org. only has an A record.
+func (z *testZones) nodata(t *testing.T) []dns.RR {
+	ns := z.example.sign(t, rr(t, "example.org. 3600 IN SOA ns.example.org. root.example.org. 1 3600 3600 3600 3600"))
+	return append(ns, z.example.sign(t, rr(t, "www.example.org. 3600 IN NSEC example.org. A RRSIG NSEC"))...)
+}
+
+// handler answers from the test zones, www.example.org. only has an A record and other unknown names
+// in example.org. don't exist.
+func (z *testZones) handler(t *testing.T) plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		q := r.Question[0]
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if a, ok := z.answers[q.Name+"/"+dns.TypeToString[q.Qtype]]; ok {
+			m.Rcode, m.Answer, m.Ns = a.Rcode, a.Answer, a.Ns
+		} else if q.Name == "www.example.org." {
+			m.Ns = z.nodata(t)
+		} else if dns.IsSubDomain("example.org.", q.Name) {
+			m.Rcode, m.Ns = dns.RcodeNameError, z.nxdomain(t)
+		} else {
+			return dns.RcodeServerFailure, nil
+		}
+		if q.Name == "a.wild.example.org." {
+			m.Ns = z.nxdomain(t)
+		}
+		m.SetEdns0(4096, true)
+		w.WriteMsg(m)
+		return m.Rcode, nil
+	})
+}
+
+func newTestValidate(t *testing.T, z *testZones) *Validate {
+	a, err := newAnchors([]dns.RR{z.root.key.ToDS(dns.SHA256)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := New([]string{"."})
+	v.anchors[a.zone] = a
+	v.Next = z.handler(t)
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	z := newTestZones(t)
+	v := newTestValidate(t, z)
+
+	tests := []struct {
+		qname string
+		qtype uint16
+		do    bool
+		cd    bool
+		rcode int
+		ad    bool
+		sigs  bool // expect signatures in the response
+	}{
+		{qname: "www.example.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeSuccess, ad: true, sigs: true},
+		{qname: "www.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess},
+		{qname: "www.unsigned.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeSuccess},
+		{qname: "unsigned.example.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeServerFailure},
+		{qname: "unsigned.example.org.", qtype: dns.TypeA, do: true, cd: true, rcode: dns.RcodeSuccess},
+		{qname: "bad.example.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeServerFailure},
+		{qname: "nope.example.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeNameError, ad: true, sigs: true},
+		{qname: "nsec.example.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeServerFailure}, // NXDOMAIN without NSEC
+		{qname: "www.example.org.", qtype: dns.TypeAAAA, do: true, rcode: dns.RcodeSuccess, ad: true, sigs: true},
+		{qname: "a.wild.example.org.", qtype: dns.TypeTXT, do: true, rcode: dns.RcodeSuccess, ad: true, sigs: true},
+		{qname: "www.dname.example.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeSuccess, ad: true, sigs: true},
+		{qname: "evil.dname.example.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeServerFailure}, // tampered CNAME
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		m.CheckingDisabled = tc.cd
+		if tc.do {
+			m.SetEdns0(4096, true)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, _ := v.ServeDNS(context.TODO(), rec, m)
+		if rec.Msg != nil {
+			rcode = rec.Msg.Rcode
+		}
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+			continue
+		}
+		if rec.Msg == nil {
+			continue
+		}
+		if rec.Msg.AuthenticatedData != tc.ad {
+			t.Errorf("Test %d: expected AD bit to be %t", i, tc.ad)
+		}
+		sigs := 0
+		for _, rr := range append(rec.Msg.Answer, rec.Msg.Ns...) {
+			if rr.Header().Rrtype == dns.TypeRRSIG {
+				sigs++
+			}
+		}
+		if (sigs > 0) != tc.sigs {
+			t.Errorf("Test %d: expected signatures to be %t, got %d", i, tc.sigs, sigs)
+		}
+		if !tc.do && rec.Msg.IsEdns0() != nil {
+			t.Errorf("Test %d: expected no OPT record in the response", i)
+		}
+	}
+}
+
+func TestValidateBadAnchor(t *testing.T) {
+	z := newTestZones(t)
+	v := newTestValidate(t, z)
+
+	other := newSigner(t, ".", 257)
+	a, _ := newAnchors([]dns.RR{other.key})
+	v.anchors["."] = a
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	rcode, _ := v.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+	if rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected SERVFAIL with a trust anchor that doesn't match, got %s", dns.RcodeToString[rcode])
+	}
+}
+
+func TestValidateCache(t *testing.T) {
+	z := newTestZones(t)
+	v := newTestValidate(t, z)
+	next := v.Next
+
+	queries := 0
+	v.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		queries++
+		return next.ServeDNS(ctx, w, r)
+	})
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	v.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+	if queries != 4 { // A, DS example.org., DNSKEY ., DNSKEY example.org.
+		t.Fatalf("Expected 4 queries, got %d", queries)
+	}
+	v.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+	if queries != 5 {
+		t.Errorf("Expected the chain of trust to be cached, got %d queries", queries-4)
+	}
+}
@@ -0,0 +1,270 @@
+package validate
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// result is the outcome of validating a response, see RFC 4033, section 5.
+type result int
+
+const (
+	// insecure means there is no chain of trust to the data, it is passed on without AD bit.
+	insecure result = iota
+	// secure means the data is validated by a chain of trust from a trust anchor.
+	secure
+	// bogus means there should be a chain of trust, but validation failed.
+	bogus
+)
+
+func (r result) String() string {
+	switch r {
+	case secure:
+		return "secure"
+	case bogus:
+		return "bogus"
+	}
+	return "insecure"
+}
+
+// validate validates the response m to the query for qname and qtype. The response is secure when all
+// its RRsets are, bogus when one of them is. Negative responses must prove the non-existence of the
+// data in a secure zone.
+func (v *Validate) validate(ctx context.Context, w dns.ResponseWriter, qname string, qtype uint16, m *dns.Msg) result {
+	if m.Rcode != dns.RcodeSuccess && m.Rcode != dns.RcodeNameError {
+		return insecure
+	}
+
+	res := secure
+	combine := func(r result) {
+		if r == bogus || res == secure {
+			res = r
+		}
+	}
+
+	verify := func(set []dns.RR) result {
+		r, ce := v.verify(ctx, w, set, m.Answer)
+		if r == secure && ce != "" && !noCloserMatch(set[0].Header().Name, ce, m.Ns) {
+			r = bogus
+		}
+		return r
+	}
+
+	// DNAMEs go first, CNAMEs synthesized from a secure DNAME are validated by it.
+	sets := rrsets(m.Answer)
+	var dnames []*dns.DNAME
+	for _, set := range sets {
+		if set[0].Header().Rrtype != dns.TypeDNAME {
+			continue
+		}
+		r := verify(set)
+		combine(r)
+		if r != secure {
+			continue
+		}
+		for _, rr := range set {
+			dnames = append(dnames, rr.(*dns.DNAME))
+		}
+	}
+	for _, set := range sets {
+		switch set[0].Header().Rrtype {
+		case dns.TypeDNAME:
+			continue
+		case dns.TypeCNAME:
+			if synthesized(set, dnames) {
+				continue
+			}
+		}
+		combine(verify(set))
+	}
+
+	var denial []dns.RR
+	for _, set := range rrsets(m.Ns) {
+		hdr := set[0].Header()
+		if hdr.Rrtype == dns.TypeNS && len(signatures(m.Ns, hdr.Name, hdr.Rrtype)) == 0 {
+			// Referrals and the NS RRset of the child zone are not signed.
+			continue
+		}
+		r, _ := v.verify(ctx, w, set, m.Ns)
+		combine(r)
+		if r == secure {
+			denial = append(denial, set...)
+		}
+	}
+	if res == bogus {
+		return bogus
+	}
+
+	target, answered := final(qname, qtype, m.Answer)
+	if answered {
+		return res
+	}
+
+	zk := v.zone(ctx, w, target)
+	if zk.result != secure {
+		combine(zk.result)
+		return res
+	}
+	ok := false
+	if m.Rcode == dns.RcodeNameError {
+		ok = nameError(target, denial)
+	} else {
+		ok = noData(target, qtype, denial)
+	}
+	if !ok {
+		return bogus
+	}
+	return res
+}
+
+// verify validates the RRset set with the signatures in sigs. If the signatures are for a wildcard
+// expansion the closest encloser of the owner name is returned as well.
+func (v *Validate) verify(ctx context.Context, w dns.ResponseWriter, set []dns.RR, sigs []dns.RR) (result, string) {
+	hdr := set[0].Header()
+	name := strings.ToLower(hdr.Name)
+	if hdr.Rrtype == dns.TypeDS {
+		// The DS RRset lives in the parent zone.
+		name = parent(name)
+	}
+
+	rrsigs := signatures(sigs, hdr.Name, hdr.Rrtype)
+	if len(rrsigs) == 0 {
+		zk := v.zone(ctx, w, name)
+		if zk.result == secure {
+			return bogus, ""
+		}
+		return zk.result, ""
+	}
+
+	signer := strings.ToLower(rrsigs[0].SignerName)
+	if !dns.IsSubDomain(signer, name) {
+		return bogus, ""
+	}
+	zk := v.zone(ctx, w, signer)
+	if zk.result != secure {
+		return zk.result, ""
+	}
+	sig := verifyRRset(set, rrsigs, zk.keys, v.now())
+	if sig == nil {
+		return bogus, ""
+	}
+	if labels := dns.CountLabel(hdr.Name); int(sig.Labels) < labels {
+		if strings.HasPrefix(hdr.Name, "*.") && int(sig.Labels) == labels-1 {
+			// The owner name is the wildcard itself.
+			return secure, ""
+		}
+		idx := dns.Split(hdr.Name)
+		return secure, strings.ToLower(hdr.Name[idx[labels-int(sig.Labels)]:])
+	}
+	return secure, ""
+}
+
+// verifyRRset returns the first signature in sigs that validates set with one of keys at time now.
+func verifyRRset(set []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY, now time.Time) *dns.RRSIG {
+	for _, sig := range sigs {
+		if !sig.ValidityPeriod(now) {
+			continue
+		}
+		for _, k := range keys {
+			if k.KeyTag() != sig.KeyTag || k.Algorithm != sig.Algorithm {
+				continue
+			}
+			if sig.Verify(k, set) == nil {
+				return sig
+			}
+		}
+	}
+	return nil
+}
+
+// rrsets groups the records in rrs, except the signatures and OPT records, into RRsets.
+func rrsets(rrs []dns.RR) [][]dns.RR {
+	type key struct {
+		name  string
+		qtype uint16
+	}
+	idx := make(map[key]int)
+	sets := [][]dns.RR{}
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		if hdr.Rrtype == dns.TypeRRSIG || hdr.Rrtype == dns.TypeOPT {
+			continue
+		}
+		k := key{strings.ToLower(hdr.Name), hdr.Rrtype}
+		i, ok := idx[k]
+		if !ok {
+			i = len(sets)
+			idx[k] = i
+			sets = append(sets, nil)
+		}
+		sets[i] = append(sets[i], rr)
+	}
+	return sets
+}
+
+// signatures returns the signatures in rrs for the RRset with name and type qtype.
+func signatures(rrs []dns.RR, name string, qtype uint16) []*dns.RRSIG {
+	var sigs []*dns.RRSIG
+	for _, rr := range rrs {
+		sig, ok := rr.(*dns.RRSIG)
+		if ok && sig.TypeCovered == qtype && strings.EqualFold(sig.Hdr.Name, name) {
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+// final follows the CNAMEs in answer from qname and returns the name it ends at. It also reports
+// whether answer holds the data for qtype at that name.
+func final(qname string, qtype uint16, answer []dns.RR) (string, bool) {
+	target := strings.ToLower(qname)
+	for i := 0; i < 8; i++ {
+		next := ""
+		for _, rr := range answer {
+			hdr := rr.Header()
+			if !strings.EqualFold(hdr.Name, target) {
+				continue
+			}
+			if hdr.Rrtype == qtype || qtype == dns.TypeANY {
+				return target, true
+			}
+			if c, ok := rr.(*dns.CNAME); ok {
+				next = strings.ToLower(c.Target)
+			}
+		}
+		if next == "" {
+			return target, false
+		}
+		target = next
+	}
+	return target, true
+}
+
+// synthesized reports whether the CNAMEs in set are synthesized from one of dnames, i.e. their target is
+// the owner name with the DNAME's owner replaced by its target, see RFC 6672, section 2.2.
+func synthesized(set []dns.RR, dnames []*dns.DNAME) bool {
+	for _, rr := range set {
+		c, ok := rr.(*dns.CNAME)
+		if !ok {
+			return false
+		}
+		found := false
+		for _, d := range dnames {
+			if !dns.IsSubDomain(d.Hdr.Name, c.Hdr.Name) || strings.EqualFold(d.Hdr.Name, c.Hdr.Name) {
+				continue
+			}
+			prefix := c.Hdr.Name[:len(c.Hdr.Name)-len(d.Hdr.Name)]
+			if strings.EqualFold(c.Target, prefix+d.Target) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
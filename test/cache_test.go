@@ -57,3 +57,51 @@ func TestLookupCache(t *testing.T) {
 		t.Errorf("Expected TTL to be %d, got %d", 10, ttl)
 	}
 }
+
+func TestCacheControl(t *testing.T) {
+	name, rm, err := test.TempFile(".", exampleOrg)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	// Nothing in this server block opens the CH class, except cache with control.
+	corefile := `.:0 {
+	file ` + name + ` example.org
+	cache {
+		control
+	}
+}
+`
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if _, err := dns.Exchange(m, udp); err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+
+	m.SetQuestion("dump.cache.coredns.", dns.TypeTXT)
+	m.Question[0].Qclass = dns.ClassCHAOS
+	resp, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 1 {
+		t.Fatalf("Expected 1 cache item in the dump, got %s", resp)
+	}
+
+	m.SetQuestion("example.org.purge.cache.coredns.", dns.TypeTXT)
+	m.Question[0].Qclass = dns.ClassCHAOS
+	resp, err = dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if len(resp.Answer) != 1 || resp.Answer[0].(*dns.TXT).Txt[0] != "purged 1" {
+		t.Fatalf("Expected 1 purged item, got %s", resp)
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestZoneIxfr(t *testing.T) {
+	name, rm, err := TempFile(".", `$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. 2017042745 7200 3600 1209600 3600
+	3600 IN NS a.iana-servers.net.
+	3600 IN NS b.iana-servers.net.
+
+www     IN A 127.0.0.1
+`)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	corefile := `example.org:0 {
+	file ` + name + ` {
+		update net 127.0.0.0/8 ::1/128
+		transfer to *
+	}
+}
+`
+	i, udp, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	// The servers listen on all addresses, use IPv4 so the notify's source matches the primary.
+	_, port, _ := net.SplitHostPort(tcp)
+	corefile = `example.org:0 {
+	secondary {
+		transfer from 127.0.0.1:` + port + `
+	}
+}
+`
+	i1, udp1, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i1.Stop()
+
+	rr, _ := dns.NewRR("new.example.org. 3600 IN A 127.0.0.2")
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{rr})
+	if r, err := dns.Exchange(m, udp); err != nil || r.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected update to succeed: %v %v", err, r)
+	}
+
+	// soa(2017042746), soa(2017042745), soa(2017042746), A, soa(2017042746)
+	m = new(dns.Msg)
+	m.SetIxfr("example.org.", 2017042745, "sns.dns.icann.org.", "noc.dns.icann.org.")
+	tr := new(dns.Transfer)
+	c, err := tr.In(m, tcp)
+	if err != nil {
+		t.Fatalf("Failed to setup transfer: %s", err)
+	}
+	records := []dns.RR{}
+	for env := range c {
+		if env.Error != nil {
+			t.Fatalf("Failed to transfer: %s", env.Error)
+		}
+		records = append(records, env.RR...)
+	}
+	if len(records) != 5 {
+		t.Fatalf("Expected 5 records in incremental transfer, got %d: %v", len(records), records)
+	}
+	if soa, ok := records[1].(*dns.SOA); !ok || soa.Serial != 2017042745 {
+		t.Errorf("Expected old SOA as second record, got %s", records[1])
+	}
+
+	// Notify the secondary, it should pick up the change.
+	m = new(dns.Msg)
+	m.SetNotify("example.org.")
+	_, port, _ = net.SplitHostPort(udp1)
+	if _, err := dns.Exchange(m, "127.0.0.1:"+port); err != nil {
+		t.Fatalf("Could not send notify: %s", err)
+	}
+
+	m = new(dns.Msg)
+	m.SetQuestion("new.example.org.", dns.TypeA)
+	for j := 0; j < 20; j++ {
+		r, err := dns.Exchange(m, udp1)
+		if err == nil && len(r.Answer) == 1 {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Expected secondary to have new.example.org. after notify")
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestZoneUpdate(t *testing.T) {
+	t.Parallel()
+
+	name, rm, err := TempFile(".", `$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. (
+		2017042745 ; serial
+		7200       ; refresh (2 hours)
+		3600       ; retry (1 hour)
+		1209600    ; expire (2 weeks)
+		3600       ; minimum (1 hour)
+	)
+
+	3600 IN NS a.iana-servers.net.
+	3600 IN NS b.iana-servers.net.
+
+www     IN A 127.0.0.1
+`)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	const secret = "c2VjcmV0c2VjcmV0c2VjcmV0"
+	corefile := `example.org:0 {
+		tsig {
+			key update.example.org. hmac-sha256 ` + secret + `
+		}
+		file ` + name + ` {
+			update key update.example.org.
+		}
+}
+`
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	rr, _ := dns.NewRR("new.example.org. 3600 IN A 127.0.0.2")
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{rr})
+
+	// Not signed.
+	r, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Could not exchange msg: %s", err)
+	}
+	if r.Rcode != dns.RcodeRefused {
+		t.Fatalf("Expected unsigned update to be refused, got %s", dns.RcodeToString[r.Rcode])
+	}
+
+	// Signed with the wrong secret.
+	c := &dns.Client{TsigSecret: map[string]string{"update.example.org.": "d3JvbmdzZWNyZXQ="}}
+	m.SetTsig("update.example.org.", dns.HmacSHA256, 300, 0)
+	r, _, err = c.Exchange(m, udp)
+	if err == nil && r.Rcode != dns.RcodeNotAuth {
+		t.Fatalf("Expected update with bad signature to fail, got %s", dns.RcodeToString[r.Rcode])
+	}
+
+	// Signed.
+	c = &dns.Client{TsigSecret: map[string]string{"update.example.org.": secret}}
+	m.Extra = nil
+	m.SetTsig("update.example.org.", dns.HmacSHA256, 300, 0)
+	r, _, err = c.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Could not exchange msg: %s", err)
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected signed update to succeed, got %s", dns.RcodeToString[r.Rcode])
+	}
+	if r.IsTsig() == nil {
+		t.Errorf("Expected reply to be signed")
+	}
+
+	q := new(dns.Msg)
+	q.SetQuestion("new.example.org.", dns.TypeA)
+	r, err = dns.Exchange(q, udp)
+	if err != nil {
+		t.Fatalf("Could not exchange msg: %s", err)
+	}
+	if len(r.Answer) != 1 {
+		t.Fatalf("Expected 1 RR in the answer section, got %d", len(r.Answer))
+	}
+
+	q.SetQuestion("example.org.", dns.TypeSOA)
+	r, err = dns.Exchange(q, udp)
+	if err != nil {
+		t.Fatalf("Could not exchange msg: %s", err)
+	}
+	if len(r.Answer) != 1 || r.Answer[0].(*dns.SOA).Serial != 2017042746 {
+		t.Fatalf("Expected serial to be increased to 2017042746, got %v", r.Answer)
+	}
+}
+
+func TestZoneUpdateUnknownKey(t *testing.T) {
+	t.Parallel()
+
+	name, rm, err := TempFile(".", `$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. 2017042745 7200 3600 1209600 3600
+	3600 IN NS a.iana-servers.net.
+`)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	// Without a tsig block the server doesn't verify signed updates, the key must be rejected.
+	corefile := `example.org:0 {
+		file ` + name + ` {
+			update key update.example.org.
+		}
+}
+`
+	if i, _, _, err := CoreDNSServerAndPorts(corefile); err == nil {
+		i.Stop()
+		t.Fatalf("Expected update with an unknown TSIG key to fail to start")
+	}
+
+	// An update signed with a key the server doesn't know, and thus a made up secret, is refused.
+	corefile = `example.org:0 {
+		file ` + name + ` {
+			update net 127.0.0.0/8
+		}
+}
+`
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	rr, _ := dns.NewRR("evil.example.org. 3600 IN A 6.6.6.6")
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{rr})
+	m.SetTsig("update.example.org.", dns.HmacSHA256, 300, 0)
+
+	c := &dns.Client{TsigSecret: map[string]string{"update.example.org.": "Zm9yZ2Vk"}}
+	r, _, err := c.Exchange(m, udp)
+	if err == nil && r.Rcode != dns.RcodeNotAuth {
+		t.Fatalf("Expected forged update to fail, got %s", dns.RcodeToString[r.Rcode])
+	}
+
+	q := new(dns.Msg)
+	q.SetQuestion("evil.example.org.", dns.TypeA)
+	r, err = dns.Exchange(q, udp)
+	if err != nil {
+		t.Fatalf("Could not exchange msg: %s", err)
+	}
+	if len(r.Answer) != 0 {
+		t.Fatalf("Expected no RRs for evil.example.org., got %v", r.Answer)
+	}
+}
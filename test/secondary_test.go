@@ -1,6 +1,9 @@
 package test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/proxy"
@@ -81,3 +84,128 @@ func TestSecondaryZoneTransfer(t *testing.T) {
 		t.Fatalf("Expected answer section")
 	}
 }
+
+func TestSecondaryZoneTransferTsig(t *testing.T) {
+	name, rm, err := test.TempFile(".", exampleOrg)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	const secret = "c2VjcmV0c2VjcmV0c2VjcmV0"
+	corefile := `example.org:0 {
+	tsig {
+		key xfr.example.org. hmac-sha256 ` + secret + `
+	}
+	file ` + name + ` {
+		transfer to * key xfr.example.org.
+	}
+}
+`
+	i, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	// An unsigned transfer must fail.
+	m := new(dns.Msg)
+	m.SetAxfr("example.org.")
+	tr := new(dns.Transfer)
+	c, err := tr.In(m, tcp)
+	if err != nil {
+		t.Fatalf("Failed to setup transfer: %s", err)
+	}
+	for env := range c {
+		if env.Error == nil {
+			t.Fatalf("Expected unsigned transfer to fail")
+		}
+	}
+
+	corefile = `example.org:0 {
+	tsig {
+		key xfr.example.org. hmac-sha256 ` + secret + `
+	}
+	secondary {
+		transfer from ` + tcp + ` key xfr.example.org.
+	}
+}
+`
+	i1, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i1.Stop()
+
+	m = new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeSOA)
+
+	r, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if len(r.Answer) == 0 {
+		t.Fatalf("Expected answer section")
+	}
+}
+
+func TestSecondaryZoneCache(t *testing.T) {
+	name, rm, err := test.TempFile(".", exampleOrg)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	dir, err := ioutil.TempDir("", "coredns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, "db.example.org")
+
+	corefile := `example.org:0 {
+       file ` + name + ` {
+	       transfer to *
+       }
+}
+`
+	i, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+
+	corefile = `example.org:0 {
+		secondary {
+			transfer from ` + tcp + `
+			cache ` + cache + `
+		}
+}
+`
+	i1, _, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	i1.Stop()
+	i.Stop()
+
+	if _, err := os.Stat(cache); err != nil {
+		t.Fatalf("Expected zone to be written to the cache: %s", err)
+	}
+
+	// The primary is gone, the zone is loaded from the cache.
+	i1, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i1.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeSOA)
+	r, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if len(r.Answer) == 0 {
+		t.Fatalf("Expected answer section")
+	}
+}